@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"google.golang.org/grpc"
+)
+
+var (
+	grpcConnStr = flag.String("grpc", ":8081", "The gRPC connection string")
+	command     = flag.String("command", "", "Command to send to the workers: drain, pause, resume or shutdown. Lists the workers if empty")
+	workerID    = flag.String("worker", "", "The worker to send the command to. All workers if empty")
+)
+
+func main() {
+	flag.Parse()
+
+	//TODO: Fix the insecure thingie
+	conn, err := grpc.Dial(*grpcConnStr, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	cl := pb.NewNeuralStyleAdminClient(conn)
+	ctx := context.Background()
+
+	if *command == "" {
+		resp, err := cl.ListWorkers(ctx, &pb.ListWorkersRequest{})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, w := range resp.Workers {
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\tlast seen %s\tcompleted %d\tfailed %d\n",
+				w.Id, w.Hostname, w.Version, w.State, w.JobId,
+				time.Unix(w.LastSeen, 0).Format(time.RFC3339),
+				w.JobsCompleted, w.JobsFailed)
+			if w.Problem != "" {
+				fmt.Printf("\tproblem: %s\n", w.Problem)
+			}
+			for _, s := range w.Slots {
+				fmt.Printf("\tslot %d\t%s\t%s\t%s\n", s.Index, s.Device, s.State, s.JobId)
+			}
+		}
+		return
+	}
+
+	c, ok := pb.WorkerCommand_value["COMMAND_"+strings.ToUpper(*command)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", *command)
+		os.Exit(2)
+	}
+
+	resp, err := cl.SendCommand(ctx, &pb.WorkerCommandRequest{
+		WorkerId: *workerID,
+		Command:  pb.WorkerCommand(c),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Sent %s to %d workers", pb.WorkerCommand(c), len(resp.WorkerIds))
+}
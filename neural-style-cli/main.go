@@ -1,35 +1,119 @@
 package main
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 
 	"github.com/mgilbir/neural-style-art-project/pb"
+	"github.com/mgilbir/neural-style-art-project/upload"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
 	"google.golang.org/grpc"
 )
 
 var (
 	styleFile   = flag.String("style_image", "", "style image")
 	contentFile = flag.String("content_image", "", "content image")
+	maskFile    = flag.String("mask", "", "Greyscale mask image. The result shows where it is white and the content where it is black")
 	name        = flag.String("name", "", "name")
 	grpcConnStr = flag.String("grpc", ":8081", "The gRPC connection string")
+
+	convergeThreshold = flag.Float64("converge_threshold", 0, "Stop once the total loss improves less than this fraction over converge_window iterations. 0 to disable")
+	convergeWindow    = flag.Int("converge_window", 50, "Iterations over which the loss improvement is measured")
+
+	timeout      = flag.Duration("timeout", 0, "How long the render may take. 0 uses the worker default")
+	stallTimeout = flag.Duration("stall_timeout", 0, "How long the engine may go without progress. 0 uses the worker default")
+
+	imageSize     = flag.Int("image_size", 0, "Largest side of the output image in pixels. 0 uses the server default")
+	numIterations = flag.Int("num_iterations", 0, "Iterations to run. 0 uses the worker default")
+	stages        = flag.String("stages", "", "Render in stages of increasing size, each starting from the previous result, e.g. 256:500,512:300,1024:200. Each stage is size[:iterations]")
+	outputFormat  = flag.String("output_format", "png", "Format of the result: png, jpg, gif or tiff")
+
+	tileSize    = flag.Int("tile_size", 0, "Render in tiles of at most this many pixels a side and stitch them together. 0 renders in one pass")
+	tileOverlap = flag.Int("tile_overlap", 0, "Pixels shared by neighbouring tiles. 0 uses an eighth of the tile size")
+
+	contentWeight = flag.Float64("content_weight", 0, "Engine content weight. 0 uses the engine default")
+	styleWeight   = flag.Float64("style_weight", 0, "Engine style weight. 0 uses the engine default")
+	styleScale    = flag.Float64("style_scale", 0, "Engine style scale. 0 uses the engine default")
+	tvWeight      = flag.Float64("tv_weight", 0, "Engine total variation weight. 0 uses the engine default")
+
+	sweep = flag.String("sweep", "", "Render a grid trying values of one or two parameters and a contact sheet of the results, e.g. style_weight=10..1000x5log,style_scale=0.5..2x4. Each axis is parameter=from..to x steps, with log for a log scale")
+
+	chainFrames = flag.Bool("chain_frames", false, "Start each frame of an animated GIF from the previous rendered frame. Frames are then rendered one after another")
+
+	colorTransfer = flag.String("color_transfer", "keep_colors", "Adjust the colours of the result: keep_colors, luminance to keep the content colours, match_content or match_style to match their histograms")
+	strength      = flag.Float64("strength", 0, "Fraction of the stylised result kept, the rest is the content. 0 keeps all of it")
+	maskFeather   = flag.Int("mask_feather", 0, "Pixels either side of the mask edges over which the result fades into the content. 0 uses a hundredth of the largest side")
+	invertMask    = flag.Bool("invert_mask", false, "Show the result where the mask is black instead")
+
+	logsID        = flag.String("logs", "", "Print the engine logs of the job with this ID and -name instead of submitting a job")
+	postProcessID = flag.String("postprocess", "", "Post-process the result of the completed job with this ID and -name again with -color_transfer and -strength instead of submitting a job")
+
+	maxMsgSize    = flag.Int("max_msg_size", 4*1024*1024, "The largest gRPC message accepted from the server, in bytes")
+	serverMsgSize = flag.Int("server_max_msg_size", upload.DefaultMaxMsgSize, "The largest gRPC message the server accepts, in bytes. Larger images are streamed")
 )
 
 func main() {
 	flag.Parse()
 
 	//TODO: Fix the insecure thingie
-	conn, err := grpc.Dial(*grpcConnStr, grpc.WithInsecure())
+	conn, err := grpc.Dial(*grpcConnStr, grpc.WithInsecure(), grpc.WithMaxMsgSize(*maxMsgSize))
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer conn.Close()
 
+	cl := pb.NewNeuralStyleImagerClient(conn)
+
+	format, ok := pb.ImageFormat_value[strings.ToUpper(*outputFormat)]
+	if !ok {
+		log.Fatalf("Unknown output format %q", *outputFormat)
+	}
+
+	transfer, ok := pb.ColorTransfer_value[strings.ToUpper(*colorTransfer)]
+	if !ok {
+		log.Fatalf("Unknown colour transfer %q", *colorTransfer)
+	}
+	postProcess := &pb.PostProcess{
+		ColorTransfer: pb.ColorTransfer(transfer),
+		Strength:      *strength,
+		MaskFeather:   int32(*maskFeather),
+		InvertMask:    *invertMask,
+	}
+
+	if *logsID != "" {
+		printLogs(cl, *logsID, *name)
+		return
+	}
+
+	if *postProcessID != "" {
+		_, err := cl.PostProcessJob(context.Background(), &pb.PostProcessRequest{
+			Id:          *postProcessID,
+			Name:        *name,
+			PostProcess: postProcess,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Result post-processed")
+		return
+	}
+
 	style, err := os.Open(*styleFile)
 	if err != nil {
 		log.Fatal(err)
@@ -53,27 +137,257 @@ func main() {
 		log.Fatal(err)
 	}
 
-	cl := pb.NewNeuralStyleImagerClient(conn)
-
 	job := pb.CreateFullJobRequest{
 		Name: *name,
 		Style: &pb.InputImage{
 			Title:  styleName,
-			Format: pb.ImageFormat_JPG,
+			Format: imageFormat(styleImg),
 			Image:  styleImg,
 		},
 		Content: &pb.InputImage{
 			Title:  *name,
-			Format: pb.ImageFormat_JPG,
+			Format: imageFormat(contentImg),
 			Image:  contentImg,
 		},
+		Params: &pb.JobParameters{
+			ImageSize:     int32(*imageSize),
+			OutputFormat:  pb.ImageFormat(format),
+			NumIterations: int32(*numIterations),
+			ContentWeight: *contentWeight,
+			StyleWeight:   *styleWeight,
+			StyleScale:    *styleScale,
+			TvWeight:      *tvWeight,
+			PostProcess:   postProcess,
+		},
+	}
+
+	var maskImg []byte
+	if *maskFile != "" {
+		maskImg, err = ioutil.ReadFile(*maskFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		job.Mask = &pb.InputImage{
+			Title:  "mask",
+			Format: imageFormat(maskImg),
+			Image:  maskImg,
+		}
+	}
+
+	job.Params.Sweep, err = parseSweep(*sweep)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	job.Params.Stages, err = parseStages(*stages)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *convergeThreshold > 0 {
+		job.Params.Convergence = &pb.Convergence{
+			Threshold: *convergeThreshold,
+			Window:    int32(*convergeWindow),
+		}
+	}
+
+	if *tileSize > 0 {
+		job.Params.Tiling = &pb.Tiling{
+			TileSize: int32(*tileSize),
+			Overlap:  int32(*tileOverlap),
+		}
+	}
+
+	if *chainFrames {
+		job.Params.Animation = &pb.Animation{ChainFrames: true}
+	}
+
+	if *timeout > 0 || *stallTimeout > 0 {
+		job.Params.Timeouts = &pb.Timeouts{
+			MaxDuration: int32(timeout.Seconds()),
+			Stall:       int32(stallTimeout.Seconds()),
+		}
 	}
 
 	ctx := context.Background()
-	_, err = cl.CreateFullJob(ctx, &job)
+	if upload.Fits(len(styleImg)+len(contentImg)+len(maskImg), *serverMsgSize) {
+		_, err = cl.CreateFullJob(ctx, &job)
+	} else {
+		err = uploadJob(ctx, cl, &job)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	log.Println("Job submitted")
 }
+
+func printLogs(cl pb.NeuralStyleImagerClient, id string, name string) {
+	resp, err := cl.GetJobLog(context.Background(), &pb.JobLogRequest{Id: id, Name: name})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, l := range resp.Logs {
+		fmt.Printf("=== Attempt %s on %s at %s\n", l.AttemptId, l.WorkerId, time.Unix(l.CreatedAt, 0))
+		if l.Truncated {
+			fmt.Println("...")
+		}
+		fmt.Print(l.Log)
+	}
+}
+
+// parseStages reads a comma separated list of size[:iterations] stages
+func parseStages(s string) ([]*pb.Stage, error) {
+	var stages []*pb.Stage
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		parts := strings.SplitN(field, ":", 2)
+		size, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid stage %q: %v", field, err)
+		}
+		stage := &pb.Stage{ImageSize: int32(size)}
+		if len(parts) == 2 {
+			iterations, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid stage %q: %v", field, err)
+			}
+			stage.NumIterations = int32(iterations)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// parseSweep reads a comma separated list of parameter=from..to x steps axes,
+// each optionally followed by log
+func parseSweep(s string) (*pb.Sweep, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	sweep := &pb.Sweep{}
+	for _, field := range strings.Split(s, ",") {
+		axis := &pb.SweepAxis{}
+
+		parts := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid sweep axis %q", field)
+		}
+		parameter, ok := pb.SweepParameter_value[strings.ToUpper(parts[0])]
+		if !ok {
+			return nil, fmt.Errorf("Unknown sweep parameter %q", parts[0])
+		}
+		axis.Parameter = pb.SweepParameter(parameter)
+
+		spec := parts[1]
+		if strings.HasSuffix(spec, "log") {
+			axis.Logarithmic = true
+			spec = strings.TrimSuffix(spec, "log")
+		}
+		x := strings.LastIndex(spec, "x")
+		if x < 0 {
+			return nil, fmt.Errorf("Invalid sweep axis %q", field)
+		}
+		bounds := strings.SplitN(spec[:x], "..", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("Invalid sweep axis %q", field)
+		}
+		var err error
+		axis.From, err = strconv.ParseFloat(bounds[0], 64)
+		if err == nil {
+			axis.To, err = strconv.ParseFloat(bounds[1], 64)
+		}
+		if err == nil {
+			var steps int
+			steps, err = strconv.Atoi(spec[x+1:])
+			axis.Steps = int32(steps)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sweep axis %q: %v", field, err)
+		}
+		sweep.Axes = append(sweep.Axes, axis)
+	}
+	return sweep, nil
+}
+
+// imageFormat tells the server what an image looks like. The server checks
+// it anyway.
+func imageFormat(b []byte) pb.ImageFormat {
+	_, name, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return pb.ImageFormat_UNKNOWN
+	}
+
+	switch name {
+	case "jpeg":
+		return pb.ImageFormat_JPG
+	case "png":
+		return pb.ImageFormat_PNG
+	case "webp":
+		return pb.ImageFormat_WEBP
+	case "gif":
+		return pb.ImageFormat_GIF
+	case "tiff":
+		return pb.ImageFormat_TIFF
+	}
+	return pb.ImageFormat_UNKNOWN
+}
+
+// uploadJob streams a job with images too large for a single message
+func uploadJob(ctx context.Context, cl pb.NeuralStyleImagerClient, job *pb.CreateFullJobRequest) error {
+	stream, err := cl.UploadJob(ctx)
+	if err != nil {
+		return err
+	}
+
+	styleImg, contentImg := job.Style.Image, job.Content.Image
+
+	header := *job
+	header.Style = &pb.InputImage{
+		Title:  job.Style.Title,
+		Format: job.Style.Format,
+		Digest: upload.Digest(styleImg),
+	}
+	header.Content = &pb.InputImage{
+		Title:  job.Content.Title,
+		Format: job.Content.Format,
+		Digest: upload.Digest(contentImg),
+	}
+	var maskImg []byte
+	if job.Mask != nil {
+		maskImg = job.Mask.Image
+		header.Mask = &pb.InputImage{
+			Title:  job.Mask.Title,
+			Format: job.Mask.Format,
+			Digest: upload.Digest(maskImg),
+		}
+	}
+
+	send := func(c *pb.ImageChunk) error {
+		return stream.Send(&pb.JobUpload{Chunk: c})
+	}
+
+	err = stream.Send(&pb.JobUpload{Job: &header})
+	if err == nil {
+		err = upload.Send(pb.ImagePart_PART_STYLE, styleImg, send)
+	}
+	if err == nil {
+		err = upload.Send(pb.ImagePart_PART_CONTENT, contentImg, send)
+	}
+	if err == nil && maskImg != nil {
+		err = upload.Send(pb.ImagePart_PART_MASK, maskImg, send)
+	}
+	//The server ended the stream early, the reason comes with the response
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
@@ -9,20 +9,52 @@ It is generated from these files:
 	image.proto
 	imager.proto
 	jobs.proto
+	params.proto
+	workers.proto
 
 It has these top-level messages:
 	InputImage
+	ImageChunk
 	CreateJobRequest
 	CreateJobResponse
 	CreateFullJobRequest
 	CreateFullJobResponse
+	JobUpload
+	JobLogRequest
+	JobLog
+	JobLogResponse
+	PostProcessRequest
+	PostProcessResponse
 	JobRequest
 	JobAck
 	Job
 	JobResult
+	ResultUpload
+	LossSample
 	JobResultResponse
 	JobFail
 	JobProgressResponse
+	WorkerRegistration
+	WorkerRegistrationResponse
+	WorkerHeartbeat
+	HeartbeatResponse
+	JobLogUpload
+	JobLogUploadResponse
+	JobParameters
+	Convergence
+	Tiling
+	Stage
+	Animation
+	Sweep
+	SweepAxis
+	PostProcess
+	Timeouts
+	WorkerInfo
+	SlotInfo
+	ListWorkersRequest
+	ListWorkersResponse
+	WorkerCommandRequest
+	WorkerCommandResponse
 */
 package pb
 
@@ -41,17 +73,26 @@ const (
 	ImageFormat_UNKNOWN ImageFormat = 0
 	ImageFormat_JPG     ImageFormat = 1
 	ImageFormat_PNG     ImageFormat = 2
+	ImageFormat_WEBP    ImageFormat = 3
+	ImageFormat_GIF     ImageFormat = 4
+	ImageFormat_TIFF    ImageFormat = 5
 )
 
 var ImageFormat_name = map[int32]string{
 	0: "UNKNOWN",
 	1: "JPG",
 	2: "PNG",
+	3: "WEBP",
+	4: "GIF",
+	5: "TIFF",
 }
 var ImageFormat_value = map[string]int32{
 	"UNKNOWN": 0,
 	"JPG":     1,
 	"PNG":     2,
+	"WEBP":    3,
+	"GIF":     4,
+	"TIFF":    5,
 }
 
 func (x ImageFormat) String() string {
@@ -59,10 +100,41 @@ func (x ImageFormat) String() string {
 }
 func (ImageFormat) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+type ImagePart int32
+
+const (
+	ImagePart_PART_UNKNOWN ImagePart = 0
+	ImagePart_PART_STYLE   ImagePart = 1
+	ImagePart_PART_CONTENT ImagePart = 2
+	ImagePart_PART_RESULT  ImagePart = 3
+	ImagePart_PART_MASK    ImagePart = 4
+)
+
+var ImagePart_name = map[int32]string{
+	0: "PART_UNKNOWN",
+	1: "PART_STYLE",
+	2: "PART_CONTENT",
+	3: "PART_RESULT",
+	4: "PART_MASK",
+}
+var ImagePart_value = map[string]int32{
+	"PART_UNKNOWN": 0,
+	"PART_STYLE":   1,
+	"PART_CONTENT": 2,
+	"PART_RESULT":  3,
+	"PART_MASK":    4,
+}
+
+func (x ImagePart) String() string {
+	return proto.EnumName(ImagePart_name, int32(x))
+}
+func (ImagePart) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 type InputImage struct {
 	Title  string      `protobuf:"bytes,1,opt,name=title" json:"title,omitempty"`
 	Format ImageFormat `protobuf:"varint,2,opt,name=format,enum=ImageFormat" json:"format,omitempty"`
 	Image  []byte      `protobuf:"bytes,3,opt,name=image,proto3" json:"image,omitempty"`
+	Digest string      `protobuf:"bytes,4,opt,name=digest" json:"digest,omitempty"`
 }
 
 func (m *InputImage) Reset()                    { *m = InputImage{} }
@@ -70,23 +142,43 @@ func (m *InputImage) String() string            { return proto.CompactTextString
 func (*InputImage) ProtoMessage()               {}
 func (*InputImage) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+type ImageChunk struct {
+	Part ImagePart `protobuf:"varint,1,opt,name=part,enum=ImagePart" json:"part,omitempty"`
+	Data []byte    `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *ImageChunk) Reset()                    { *m = ImageChunk{} }
+func (m *ImageChunk) String() string            { return proto.CompactTextString(m) }
+func (*ImageChunk) ProtoMessage()               {}
+func (*ImageChunk) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 func init() {
 	proto.RegisterType((*InputImage)(nil), "InputImage")
+	proto.RegisterType((*ImageChunk)(nil), "ImageChunk")
 	proto.RegisterEnum("ImageFormat", ImageFormat_name, ImageFormat_value)
+	proto.RegisterEnum("ImagePart", ImagePart_name, ImagePart_value)
 }
 
 var fileDescriptor0 = []byte{
-	// 181 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0xce, 0xcc, 0x4d, 0x4c,
-	0x4f, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x57, 0x8a, 0xe3, 0xe2, 0xf2, 0xcc, 0x2b, 0x28, 0x2d,
-	0xf1, 0x04, 0x89, 0x09, 0x89, 0x70, 0xb1, 0x96, 0x64, 0x96, 0xe4, 0xa4, 0x4a, 0x30, 0x2a, 0x30,
-	0x6a, 0x70, 0x06, 0x41, 0x38, 0x42, 0x2a, 0x5c, 0x6c, 0x69, 0xf9, 0x45, 0xb9, 0x89, 0x25, 0x12,
-	0x4c, 0x40, 0x61, 0x3e, 0x23, 0x1e, 0x3d, 0xb0, 0x6a, 0x37, 0xb0, 0x58, 0x10, 0x54, 0x0e, 0xa4,
-	0x17, 0x6c, 0xb0, 0x04, 0x33, 0x50, 0x11, 0x4f, 0x10, 0x84, 0xa3, 0xa5, 0xc3, 0xc5, 0x8d, 0xa4,
-	0x58, 0x88, 0x9b, 0x8b, 0x3d, 0xd4, 0xcf, 0xdb, 0xcf, 0x3f, 0xdc, 0x4f, 0x80, 0x41, 0x88, 0x9d,
-	0x8b, 0xd9, 0x2b, 0xc0, 0x5d, 0x80, 0x11, 0xc4, 0x08, 0xf0, 0x73, 0x17, 0x60, 0x72, 0x52, 0xe6,
-	0x52, 0xcc, 0x4b, 0x2d, 0xd1, 0x4b, 0x2b, 0x4a, 0xcc, 0x4b, 0xce, 0x28, 0xd5, 0xcb, 0x4b, 0x2d,
-	0x2d, 0x4a, 0xcc, 0x29, 0x2e, 0xa9, 0xcc, 0x49, 0x4d, 0x2c, 0x2a, 0x01, 0x3a, 0x38, 0x2b, 0x35,
-	0xb9, 0x24, 0x89, 0x0d, 0xec, 0x72, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0xc9, 0xb1, 0x5a,
-	0x81, 0xc8, 0x00, 0x00, 0x00,
+	// 314 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x4c, 0x91, 0x4d, 0x6f, 0xe2, 0x30,
+	0x10, 0x86, 0x37, 0x24, 0xc0, 0x32, 0xc9, 0xb2, 0x96, 0xb5, 0x5a, 0xe5, 0x54, 0x51, 0xda, 0x03,
+	0xe2, 0x90, 0x03, 0xfd, 0x03, 0x05, 0x94, 0xa0, 0x14, 0x1a, 0x22, 0x13, 0x84, 0x7a, 0x42, 0x2e,
+	0x98, 0x8f, 0x16, 0x92, 0xc8, 0x4c, 0x2a, 0xf5, 0xdf, 0x57, 0x19, 0x10, 0xed, 0x6d, 0xde, 0xc7,
+	0xe3, 0xe7, 0xb5, 0x64, 0xb0, 0xf7, 0x47, 0xb9, 0x55, 0x5e, 0xae, 0x33, 0xcc, 0xda, 0x1f, 0x00,
+	0x61, 0x9a, 0x17, 0x18, 0x96, 0x8c, 0xff, 0x83, 0x2a, 0xee, 0xf1, 0xa0, 0x5c, 0xa3, 0x65, 0x74,
+	0x1a, 0xe2, 0x1c, 0xf8, 0x3d, 0xd4, 0x36, 0x99, 0x3e, 0x4a, 0x74, 0x2b, 0x2d, 0xa3, 0xd3, 0xec,
+	0x39, 0x1e, 0x6d, 0x07, 0xc4, 0xc4, 0xe5, 0xac, 0xbc, 0x4b, 0x62, 0xd7, 0x6c, 0x19, 0x1d, 0x47,
+	0x9c, 0x03, 0xff, 0x0f, 0xb5, 0xf5, 0x7e, 0xab, 0x4e, 0xe8, 0x5a, 0xa4, 0xbc, 0xa4, 0xf6, 0x23,
+	0x00, 0x49, 0x86, 0xbb, 0x22, 0x7d, 0xe7, 0x37, 0x60, 0xe5, 0x52, 0x23, 0xd5, 0x36, 0x7b, 0x70,
+	0xf6, 0xc7, 0x52, 0xa3, 0x20, 0xce, 0x39, 0x58, 0x6b, 0x89, 0x92, 0xfa, 0x1d, 0x41, 0x73, 0x37,
+	0x04, 0xfb, 0xc7, 0x33, 0xb8, 0x0d, 0xf5, 0x79, 0x34, 0x8e, 0xa6, 0x8b, 0x88, 0xfd, 0xe2, 0x75,
+	0x30, 0x9f, 0xe2, 0x11, 0x33, 0xca, 0x21, 0x8e, 0x46, 0xac, 0xc2, 0x7f, 0x83, 0xb5, 0xf0, 0x07,
+	0x31, 0x33, 0x4b, 0x34, 0x0a, 0x03, 0x66, 0x95, 0x28, 0x09, 0x83, 0x80, 0x55, 0xbb, 0x4b, 0x68,
+	0x5c, 0x1b, 0x39, 0x03, 0x27, 0xee, 0x8b, 0x64, 0xf9, 0x6d, 0x6b, 0x02, 0x10, 0x99, 0x25, 0x2f,
+	0x13, 0x9f, 0x19, 0xd7, 0x8d, 0xe1, 0x34, 0x4a, 0xfc, 0x28, 0x61, 0x15, 0xfe, 0x17, 0x6c, 0x22,
+	0xc2, 0x9f, 0xcd, 0x27, 0x09, 0x33, 0xf9, 0x1f, 0x68, 0x10, 0x78, 0xee, 0xcf, 0xc6, 0xcc, 0x1a,
+	0xdc, 0xc1, 0x6d, 0xaa, 0xd0, 0xdb, 0x68, 0x99, 0xae, 0x76, 0x85, 0x97, 0xaa, 0x42, 0xcb, 0xc3,
+	0x09, 0x3f, 0x0f, 0x4a, 0x6a, 0xcc, 0x75, 0xf6, 0xa6, 0x56, 0xf8, 0x5a, 0xa3, 0x1f, 0x79, 0xf8,
+	0x1a, 0x00, 0xce, 0x40, 0x26, 0x85, 0xa0, 0x01, 0x00, 0x00,
 }
@@ -19,8 +19,10 @@ var _ = fmt.Errorf
 var _ = math.Inf
 
 type CreateJobRequest struct {
-	Name    string      `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
-	Content *InputImage `protobuf:"bytes,5,opt,name=content" json:"content,omitempty"`
+	Name    string         `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Content *InputImage    `protobuf:"bytes,5,opt,name=content" json:"content,omitempty"`
+	Params  *JobParameters `protobuf:"bytes,6,opt,name=params" json:"params,omitempty"`
+	Mask    *InputImage    `protobuf:"bytes,7,opt,name=mask" json:"mask,omitempty"`
 }
 
 func (m *CreateJobRequest) Reset()                    { *m = CreateJobRequest{} }
@@ -35,6 +37,20 @@ func (m *CreateJobRequest) GetContent() *InputImage {
 	return nil
 }
 
+func (m *CreateJobRequest) GetParams() *JobParameters {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+func (m *CreateJobRequest) GetMask() *InputImage {
+	if m != nil {
+		return m.Mask
+	}
+	return nil
+}
+
 type CreateJobResponse struct {
 }
 
@@ -44,9 +60,11 @@ func (*CreateJobResponse) ProtoMessage()               {}
 func (*CreateJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{1} }
 
 type CreateFullJobRequest struct {
-	Name    string      `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
-	Style   *InputImage `protobuf:"bytes,3,opt,name=style" json:"style,omitempty"`
-	Content *InputImage `protobuf:"bytes,5,opt,name=content" json:"content,omitempty"`
+	Name    string         `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Style   *InputImage    `protobuf:"bytes,3,opt,name=style" json:"style,omitempty"`
+	Content *InputImage    `protobuf:"bytes,5,opt,name=content" json:"content,omitempty"`
+	Params  *JobParameters `protobuf:"bytes,6,opt,name=params" json:"params,omitempty"`
+	Mask    *InputImage    `protobuf:"bytes,7,opt,name=mask" json:"mask,omitempty"`
 }
 
 func (m *CreateFullJobRequest) Reset()                    { *m = CreateFullJobRequest{} }
@@ -68,6 +86,20 @@ func (m *CreateFullJobRequest) GetContent() *InputImage {
 	return nil
 }
 
+func (m *CreateFullJobRequest) GetParams() *JobParameters {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
+func (m *CreateFullJobRequest) GetMask() *InputImage {
+	if m != nil {
+		return m.Mask
+	}
+	return nil
+}
+
 type CreateFullJobResponse struct {
 }
 
@@ -76,11 +108,106 @@ func (m *CreateFullJobResponse) String() string            { return proto.Compac
 func (*CreateFullJobResponse) ProtoMessage()               {}
 func (*CreateFullJobResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{3} }
 
+type JobUpload struct {
+	Job   *CreateFullJobRequest `protobuf:"bytes,1,opt,name=job" json:"job,omitempty"`
+	Chunk *ImageChunk           `protobuf:"bytes,2,opt,name=chunk" json:"chunk,omitempty"`
+}
+
+func (m *JobUpload) Reset()                    { *m = JobUpload{} }
+func (m *JobUpload) String() string            { return proto.CompactTextString(m) }
+func (*JobUpload) ProtoMessage()               {}
+func (*JobUpload) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{4} }
+
+func (m *JobUpload) GetJob() *CreateFullJobRequest {
+	if m != nil {
+		return m.Job
+	}
+	return nil
+}
+
+func (m *JobUpload) GetChunk() *ImageChunk {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+type JobLogRequest struct {
+	Id   string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Name string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+}
+
+func (m *JobLogRequest) Reset()                    { *m = JobLogRequest{} }
+func (m *JobLogRequest) String() string            { return proto.CompactTextString(m) }
+func (*JobLogRequest) ProtoMessage()               {}
+func (*JobLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{5} }
+
+type JobLog struct {
+	AttemptId string `protobuf:"bytes,1,opt,name=attempt_id" json:"attempt_id,omitempty"`
+	WorkerId  string `protobuf:"bytes,2,opt,name=worker_id" json:"worker_id,omitempty"`
+	Log       string `protobuf:"bytes,3,opt,name=log" json:"log,omitempty"`
+	Truncated bool   `protobuf:"varint,4,opt,name=truncated" json:"truncated,omitempty"`
+	CreatedAt int64  `protobuf:"varint,5,opt,name=created_at" json:"created_at,omitempty"`
+}
+
+func (m *JobLog) Reset()                    { *m = JobLog{} }
+func (m *JobLog) String() string            { return proto.CompactTextString(m) }
+func (*JobLog) ProtoMessage()               {}
+func (*JobLog) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{6} }
+
+type JobLogResponse struct {
+	Logs []*JobLog `protobuf:"bytes,1,rep,name=logs" json:"logs,omitempty"`
+}
+
+func (m *JobLogResponse) Reset()                    { *m = JobLogResponse{} }
+func (m *JobLogResponse) String() string            { return proto.CompactTextString(m) }
+func (*JobLogResponse) ProtoMessage()               {}
+func (*JobLogResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{7} }
+
+func (m *JobLogResponse) GetLogs() []*JobLog {
+	if m != nil {
+		return m.Logs
+	}
+	return nil
+}
+
+type PostProcessRequest struct {
+	Id          string       `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Name        string       `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	PostProcess *PostProcess `protobuf:"bytes,3,opt,name=post_process" json:"post_process,omitempty"`
+}
+
+func (m *PostProcessRequest) Reset()                    { *m = PostProcessRequest{} }
+func (m *PostProcessRequest) String() string            { return proto.CompactTextString(m) }
+func (*PostProcessRequest) ProtoMessage()               {}
+func (*PostProcessRequest) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{8} }
+
+func (m *PostProcessRequest) GetPostProcess() *PostProcess {
+	if m != nil {
+		return m.PostProcess
+	}
+	return nil
+}
+
+type PostProcessResponse struct {
+}
+
+func (m *PostProcessResponse) Reset()                    { *m = PostProcessResponse{} }
+func (m *PostProcessResponse) String() string            { return proto.CompactTextString(m) }
+func (*PostProcessResponse) ProtoMessage()               {}
+func (*PostProcessResponse) Descriptor() ([]byte, []int) { return fileDescriptor1, []int{9} }
+
 func init() {
 	proto.RegisterType((*CreateJobRequest)(nil), "CreateJobRequest")
 	proto.RegisterType((*CreateJobResponse)(nil), "CreateJobResponse")
 	proto.RegisterType((*CreateFullJobRequest)(nil), "CreateFullJobRequest")
 	proto.RegisterType((*CreateFullJobResponse)(nil), "CreateFullJobResponse")
+	proto.RegisterType((*JobUpload)(nil), "JobUpload")
+	proto.RegisterType((*JobLogRequest)(nil), "JobLogRequest")
+	proto.RegisterType((*JobLog)(nil), "JobLog")
+	proto.RegisterType((*JobLogResponse)(nil), "JobLogResponse")
+	proto.RegisterType((*PostProcessRequest)(nil), "PostProcessRequest")
+	proto.RegisterType((*PostProcessResponse)(nil), "PostProcessResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -92,6 +219,9 @@ var _ grpc.ClientConn
 type NeuralStyleImagerClient interface {
 	CreateJob(ctx context.Context, in *CreateJobRequest, opts ...grpc.CallOption) (*CreateJobResponse, error)
 	CreateFullJob(ctx context.Context, in *CreateFullJobRequest, opts ...grpc.CallOption) (*CreateFullJobResponse, error)
+	GetJobLog(ctx context.Context, in *JobLogRequest, opts ...grpc.CallOption) (*JobLogResponse, error)
+	UploadJob(ctx context.Context, opts ...grpc.CallOption) (NeuralStyleImager_UploadJobClient, error)
+	PostProcessJob(ctx context.Context, in *PostProcessRequest, opts ...grpc.CallOption) (*PostProcessResponse, error)
 }
 
 type neuralStyleImagerClient struct {
@@ -120,11 +250,66 @@ func (c *neuralStyleImagerClient) CreateFullJob(ctx context.Context, in *CreateF
 	return out, nil
 }
 
+func (c *neuralStyleImagerClient) GetJobLog(ctx context.Context, in *JobLogRequest, opts ...grpc.CallOption) (*JobLogResponse, error) {
+	out := new(JobLogResponse)
+	err := grpc.Invoke(ctx, "/NeuralStyleImager/GetJobLog", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *neuralStyleImagerClient) UploadJob(ctx context.Context, opts ...grpc.CallOption) (NeuralStyleImager_UploadJobClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_NeuralStyleImager_serviceDesc.Streams[0], c.cc, "/NeuralStyleImager/UploadJob", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &neuralStyleImagerUploadJobClient{stream}
+	return x, nil
+}
+
+type NeuralStyleImager_UploadJobClient interface {
+	Send(*JobUpload) error
+	CloseAndRecv() (*CreateJobResponse, error)
+	grpc.ClientStream
+}
+
+type neuralStyleImagerUploadJobClient struct {
+	grpc.ClientStream
+}
+
+func (x *neuralStyleImagerUploadJobClient) Send(m *JobUpload) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *neuralStyleImagerUploadJobClient) CloseAndRecv() (*CreateJobResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(CreateJobResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *neuralStyleImagerClient) PostProcessJob(ctx context.Context, in *PostProcessRequest, opts ...grpc.CallOption) (*PostProcessResponse, error) {
+	out := new(PostProcessResponse)
+	err := grpc.Invoke(ctx, "/NeuralStyleImager/PostProcessJob", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for NeuralStyleImager service
 
 type NeuralStyleImagerServer interface {
 	CreateJob(context.Context, *CreateJobRequest) (*CreateJobResponse, error)
 	CreateFullJob(context.Context, *CreateFullJobRequest) (*CreateFullJobResponse, error)
+	GetJobLog(context.Context, *JobLogRequest) (*JobLogResponse, error)
+	UploadJob(NeuralStyleImager_UploadJobServer) error
+	PostProcessJob(context.Context, *PostProcessRequest) (*PostProcessResponse, error)
 }
 
 func RegisterNeuralStyleImagerServer(s *grpc.Server, srv NeuralStyleImagerServer) {
@@ -155,6 +340,56 @@ func _NeuralStyleImager_CreateFullJob_Handler(srv interface{}, ctx context.Conte
 	return out, nil
 }
 
+func _NeuralStyleImager_GetJobLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(JobLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(NeuralStyleImagerServer).GetJobLog(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _NeuralStyleImager_UploadJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NeuralStyleImagerServer).UploadJob(&neuralStyleImagerUploadJobServer{stream})
+}
+
+type NeuralStyleImager_UploadJobServer interface {
+	SendAndClose(*CreateJobResponse) error
+	Recv() (*JobUpload, error)
+	grpc.ServerStream
+}
+
+type neuralStyleImagerUploadJobServer struct {
+	grpc.ServerStream
+}
+
+func (x *neuralStyleImagerUploadJobServer) SendAndClose(m *CreateJobResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *neuralStyleImagerUploadJobServer) Recv() (*JobUpload, error) {
+	m := new(JobUpload)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _NeuralStyleImager_PostProcessJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(PostProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(NeuralStyleImagerServer).PostProcessJob(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _NeuralStyleImager_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "NeuralStyleImager",
 	HandlerType: (*NeuralStyleImagerServer)(nil),
@@ -167,26 +402,59 @@ var _NeuralStyleImager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CreateFullJob",
 			Handler:    _NeuralStyleImager_CreateFullJob_Handler,
 		},
+		{
+			MethodName: "GetJobLog",
+			Handler:    _NeuralStyleImager_GetJobLog_Handler,
+		},
+		{
+			MethodName: "PostProcessJob",
+			Handler:    _NeuralStyleImager_PostProcessJob_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadJob",
+			Handler:       _NeuralStyleImager_UploadJob_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams: []grpc.StreamDesc{},
 }
 
 var fileDescriptor1 = []byte{
-	// 247 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xe2, 0xe2, 0xc9, 0xcc, 0x4d, 0x4c,
-	0x4f, 0x2d, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x97, 0xe2, 0x06, 0xf3, 0x20, 0x1c, 0x25, 0x5f,
-	0x2e, 0x01, 0xe7, 0xa2, 0xd4, 0xc4, 0x92, 0x54, 0xaf, 0xfc, 0xa4, 0xa0, 0xd4, 0xc2, 0xd2, 0xd4,
-	0xe2, 0x12, 0x21, 0x21, 0x2e, 0x96, 0xbc, 0xc4, 0xdc, 0x54, 0x09, 0x26, 0x05, 0x46, 0x0d, 0xce,
-	0x20, 0x30, 0x5b, 0x48, 0x95, 0x8b, 0x3d, 0x39, 0x3f, 0xaf, 0x24, 0x35, 0xaf, 0x44, 0x82, 0x15,
-	0x28, 0xcc, 0x6d, 0xc4, 0xad, 0xe7, 0x99, 0x57, 0x50, 0x5a, 0xe2, 0x09, 0x32, 0x2b, 0x08, 0x26,
-	0xa7, 0x24, 0xcc, 0x25, 0x88, 0x64, 0x5c, 0x71, 0x41, 0x7e, 0x5e, 0x71, 0xaa, 0x52, 0x09, 0x97,
-	0x08, 0x44, 0xd0, 0xad, 0x34, 0x27, 0x87, 0x80, 0x3d, 0x8a, 0x5c, 0xac, 0xc5, 0x25, 0x95, 0x39,
-	0xa9, 0x12, 0xcc, 0x98, 0xb6, 0x40, 0x64, 0x88, 0x75, 0x8a, 0x38, 0x97, 0x28, 0x9a, 0xad, 0x10,
-	0xe7, 0x18, 0xb5, 0x33, 0x72, 0x09, 0xfa, 0xa5, 0x96, 0x16, 0x25, 0xe6, 0x04, 0x83, 0xcc, 0x03,
-	0x6b, 0x2b, 0x12, 0x32, 0xe2, 0xe2, 0x84, 0xbb, 0x5c, 0x48, 0x50, 0x0f, 0x3d, 0x50, 0xa4, 0x84,
-	0xf4, 0x30, 0x3c, 0x26, 0x64, 0xc7, 0xc5, 0x8b, 0x62, 0x85, 0x90, 0xa8, 0x1e, 0x36, 0x8f, 0x4a,
-	0x89, 0xe9, 0x61, 0x75, 0x89, 0x93, 0x32, 0x97, 0x62, 0x5e, 0x6a, 0x89, 0x5e, 0x5a, 0x51, 0x62,
-	0x5e, 0x72, 0x46, 0xa9, 0x5e, 0x1e, 0xd8, 0x51, 0x60, 0x4f, 0x26, 0x16, 0x95, 0x00, 0xe3, 0x27,
-	0x2b, 0x35, 0xb9, 0x24, 0x89, 0x0d, 0x1c, 0x51, 0xc6, 0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0x0c,
-	0x7b, 0x70, 0xda, 0xc5, 0x01, 0x00, 0x00,
+	// 552 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xbc, 0x54, 0x5f, 0x8b, 0x13, 0x3f,
+	0x14, 0x65, 0xda, 0x6e, 0xbb, 0xb9, 0xed, 0xf6, 0xb7, 0x4d, 0xb7, 0x3f, 0x87, 0xae, 0x62, 0x77,
+	0x44, 0xed, 0x83, 0x1b, 0xa1, 0xfb, 0x28, 0x08, 0xba, 0xa0, 0xb4, 0x88, 0x94, 0x11, 0xf1, 0xb1,
+	0x64, 0x66, 0x62, 0xb7, 0xdb, 0xe9, 0x24, 0x26, 0x19, 0xc4, 0xcf, 0x21, 0xf8, 0xee, 0x07, 0xf1,
+	0xbb, 0x49, 0x92, 0x69, 0xb7, 0x7f, 0x06, 0xc4, 0x17, 0xdf, 0xe6, 0x9e, 0x9b, 0xfb, 0xe7, 0x9c,
+	0x9c, 0x0c, 0xb4, 0x16, 0x2b, 0x3a, 0x67, 0x92, 0x08, 0xc9, 0x35, 0xef, 0x37, 0x6d, 0x54, 0x04,
+	0x2d, 0x41, 0x25, 0x5d, 0x29, 0x17, 0x05, 0x3f, 0x3c, 0x38, 0xbd, 0x96, 0x8c, 0x6a, 0x36, 0xe1,
+	0x51, 0xc8, 0xbe, 0xe4, 0x4c, 0x69, 0x8c, 0xa1, 0x96, 0xd1, 0x15, 0xf3, 0x2b, 0x03, 0x6f, 0x88,
+	0x42, 0xfb, 0x8d, 0x1f, 0x43, 0x23, 0xe6, 0x99, 0x66, 0x99, 0xf6, 0x8f, 0x06, 0xde, 0xb0, 0x39,
+	0x6a, 0x92, 0x71, 0x26, 0x72, 0x3d, 0x36, 0xad, 0xc3, 0x75, 0x0e, 0x3f, 0x81, 0xba, 0xeb, 0xef,
+	0xd7, 0xed, 0xa9, 0x36, 0x99, 0xf0, 0x68, 0x6a, 0x10, 0xa6, 0x99, 0x54, 0x61, 0x91, 0xc5, 0x0f,
+	0xa1, 0xb6, 0xa2, 0x6a, 0xe9, 0x37, 0x0e, 0x7b, 0xd9, 0x44, 0xd0, 0x85, 0xce, 0xd6, 0x5e, 0x4a,
+	0xf0, 0x4c, 0xb1, 0xe0, 0x97, 0x07, 0x67, 0x0e, 0x7d, 0x93, 0xa7, 0xe9, 0x1f, 0x36, 0xbe, 0x80,
+	0x23, 0xa5, 0xbf, 0xa5, 0xcc, 0xaf, 0x1e, 0xce, 0x70, 0x99, 0x7f, 0x4e, 0xea, 0x1e, 0xf4, 0xf6,
+	0xd6, 0x2f, 0x88, 0x7d, 0x02, 0x34, 0xe1, 0xd1, 0x47, 0x91, 0x72, 0x9a, 0xe0, 0xa7, 0x50, 0xbd,
+	0xe5, 0x91, 0xef, 0xd9, 0x2e, 0x3d, 0x52, 0x46, 0x38, 0x34, 0x27, 0x0c, 0xc3, 0xf8, 0x26, 0xcf,
+	0x96, 0x7e, 0x65, 0x3d, 0xd0, 0xcc, 0xba, 0x36, 0x50, 0xe8, 0x32, 0xc1, 0x15, 0x9c, 0x4c, 0x78,
+	0xf4, 0x8e, 0xcf, 0xd7, 0x4a, 0xb5, 0xa1, 0xb2, 0x48, 0x6c, 0x6f, 0x14, 0x56, 0x16, 0x49, 0x99,
+	0x72, 0xc1, 0x77, 0x0f, 0xea, 0xae, 0x0a, 0x3f, 0x00, 0xa0, 0x5a, 0xb3, 0x95, 0xd0, 0xb3, 0x4d,
+	0x19, 0x2a, 0x90, 0x71, 0x82, 0xcf, 0x01, 0x7d, 0xe5, 0x72, 0xc9, 0xa4, 0xc9, 0xba, 0x16, 0xc7,
+	0x0e, 0x18, 0x27, 0xf8, 0x14, 0xaa, 0x29, 0x9f, 0x5b, 0xf9, 0x51, 0x68, 0x3e, 0xf1, 0x7d, 0x40,
+	0x5a, 0xe6, 0x59, 0x4c, 0x35, 0x4b, 0xfc, 0xda, 0xc0, 0x1b, 0x1e, 0x87, 0x77, 0x80, 0x99, 0x15,
+	0x5b, 0xae, 0xc9, 0x8c, 0xba, 0x0b, 0xa9, 0x86, 0xa8, 0x40, 0x5e, 0xe9, 0xe0, 0x12, 0xda, 0x6b,
+	0x2a, 0x4e, 0x35, 0x7c, 0x0e, 0xb5, 0x94, 0xcf, 0x95, 0xef, 0x0d, 0xaa, 0xc3, 0xe6, 0xa8, 0x41,
+	0x8a, 0xb4, 0x05, 0x83, 0x05, 0xe0, 0x29, 0x57, 0x7a, 0x2a, 0x79, 0xcc, 0x94, 0xfa, 0x0b, 0xfa,
+	0xf8, 0x39, 0xb4, 0x04, 0x57, 0x7a, 0x26, 0x5c, 0x69, 0xe1, 0x9f, 0x16, 0xd9, 0x6e, 0xd7, 0x14,
+	0x77, 0x41, 0xd0, 0x83, 0xee, 0xce, 0x28, 0xb7, 0xde, 0xe8, 0x67, 0x05, 0x3a, 0xef, 0x59, 0x2e,
+	0x69, 0xfa, 0xc1, 0xb8, 0xcd, 0x5e, 0x8e, 0xc4, 0x23, 0x40, 0x1b, 0x63, 0xe3, 0x0e, 0xd9, 0x7f,
+	0x7c, 0x7d, 0x4c, 0x0e, 0x7c, 0x8f, 0x5f, 0xc2, 0xc9, 0x8e, 0x0b, 0x70, 0xb9, 0x2b, 0xfa, 0xff,
+	0x93, 0x52, 0x7b, 0xe1, 0x67, 0x80, 0xde, 0x32, 0x5d, 0x5c, 0x69, 0x9b, 0xec, 0x38, 0xa2, 0xff,
+	0x1f, 0xd9, 0x93, 0xf5, 0x12, 0x90, 0x73, 0xa2, 0x99, 0x04, 0x64, 0x63, 0xcc, 0xb2, 0xd5, 0x86,
+	0x1e, 0x7e, 0x01, 0xed, 0x2d, 0xf6, 0xa6, 0xa6, 0x4b, 0x0e, 0x95, 0xef, 0x9f, 0x91, 0x12, 0x8d,
+	0x5e, 0x3f, 0x82, 0x8b, 0x8c, 0x69, 0xf2, 0x59, 0xd2, 0x2c, 0xbe, 0xc9, 0x49, 0x66, 0xe5, 0xb2,
+	0x8f, 0x93, 0x4a, 0x2d, 0x24, 0xbf, 0x65, 0xb1, 0x8e, 0xea, 0xf6, 0x5f, 0x75, 0xf5, 0x7b, 0x00,
+	0xb1, 0xd2, 0x2c, 0xee, 0xd6, 0x04, 0x00, 0x00,
 }
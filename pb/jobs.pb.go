@@ -19,6 +19,9 @@ var _ = fmt.Errorf
 var _ = math.Inf
 
 type JobRequest struct {
+	WorkerId      string   `protobuf:"bytes,1,opt,name=worker_id" json:"worker_id,omitempty"`
+	Slot          int32    `protobuf:"varint,2,opt,name=slot" json:"slot,omitempty"`
+	CachedDigests []string `protobuf:"bytes,3,rep,name=cached_digests" json:"cached_digests,omitempty"`
 }
 
 func (m *JobRequest) Reset()                    { *m = JobRequest{} }
@@ -35,10 +38,15 @@ func (*JobAck) ProtoMessage()               {}
 func (*JobAck) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{1} }
 
 type Job struct {
-	Id      string      `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Name    string      `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
-	Style   *InputImage `protobuf:"bytes,4,opt,name=style" json:"style,omitempty"`
-	Content *InputImage `protobuf:"bytes,5,opt,name=content" json:"content,omitempty"`
+	Id             string         `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Name           string         `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Style          *InputImage    `protobuf:"bytes,4,opt,name=style" json:"style,omitempty"`
+	Content        *InputImage    `protobuf:"bytes,5,opt,name=content" json:"content,omitempty"`
+	Command        WorkerCommand  `protobuf:"varint,6,opt,name=command,enum=WorkerCommand" json:"command,omitempty"`
+	Init           *InputImage    `protobuf:"bytes,7,opt,name=init" json:"init,omitempty"`
+	StartIteration int32          `protobuf:"varint,8,opt,name=start_iteration" json:"start_iteration,omitempty"`
+	AttemptId      string         `protobuf:"bytes,9,opt,name=attempt_id" json:"attempt_id,omitempty"`
+	Params         *JobParameters `protobuf:"bytes,10,opt,name=params" json:"params,omitempty"`
 }
 
 func (m *Job) Reset()                    { *m = Job{} }
@@ -60,12 +68,31 @@ func (m *Job) GetContent() *InputImage {
 	return nil
 }
 
+func (m *Job) GetInit() *InputImage {
+	if m != nil {
+		return m.Init
+	}
+	return nil
+}
+
+func (m *Job) GetParams() *JobParameters {
+	if m != nil {
+		return m.Params
+	}
+	return nil
+}
+
 type JobResult struct {
-	Id            string      `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Name          string      `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
-	ProgressCount int32       `protobuf:"varint,3,opt,name=progress_count" json:"progress_count,omitempty"`
-	Format        ImageFormat `protobuf:"varint,4,opt,name=format,enum=ImageFormat" json:"format,omitempty"`
-	Image         []byte      `protobuf:"bytes,5,opt,name=image,proto3" json:"image,omitempty"`
+	Id            string        `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Name          string        `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	ProgressCount int32         `protobuf:"varint,3,opt,name=progress_count" json:"progress_count,omitempty"`
+	Format        ImageFormat   `protobuf:"varint,4,opt,name=format,enum=ImageFormat" json:"format,omitempty"`
+	Image         []byte        `protobuf:"bytes,5,opt,name=image,proto3" json:"image,omitempty"`
+	AttemptId     string        `protobuf:"bytes,6,opt,name=attempt_id" json:"attempt_id,omitempty"`
+	Sequence      int64         `protobuf:"varint,7,opt,name=sequence" json:"sequence,omitempty"`
+	Losses        []*LossSample `protobuf:"bytes,8,rep,name=losses" json:"losses,omitempty"`
+	Annotation    string        `protobuf:"bytes,9,opt,name=annotation" json:"annotation,omitempty"`
+	Digest        string        `protobuf:"bytes,10,opt,name=digest" json:"digest,omitempty"`
 }
 
 func (m *JobResult) Reset()                    { *m = JobResult{} }
@@ -73,23 +100,73 @@ func (m *JobResult) String() string            { return proto.CompactTextString(
 func (*JobResult) ProtoMessage()               {}
 func (*JobResult) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{3} }
 
+func (m *JobResult) GetLosses() []*LossSample {
+	if m != nil {
+		return m.Losses
+	}
+	return nil
+}
+
+type ResultUpload struct {
+	Result   *JobResult  `protobuf:"bytes,1,opt,name=result" json:"result,omitempty"`
+	Complete bool        `protobuf:"varint,2,opt,name=complete" json:"complete,omitempty"`
+	Chunk    *ImageChunk `protobuf:"bytes,3,opt,name=chunk" json:"chunk,omitempty"`
+}
+
+func (m *ResultUpload) Reset()                    { *m = ResultUpload{} }
+func (m *ResultUpload) String() string            { return proto.CompactTextString(m) }
+func (*ResultUpload) ProtoMessage()               {}
+func (*ResultUpload) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{4} }
+
+func (m *ResultUpload) GetResult() *JobResult {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+func (m *ResultUpload) GetChunk() *ImageChunk {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+type LossSample struct {
+	Iteration int32   `protobuf:"varint,1,opt,name=iteration" json:"iteration,omitempty"`
+	Content   float64 `protobuf:"fixed64,2,opt,name=content" json:"content,omitempty"`
+	Style     float64 `protobuf:"fixed64,3,opt,name=style" json:"style,omitempty"`
+	Total     float64 `protobuf:"fixed64,4,opt,name=total" json:"total,omitempty"`
+}
+
+func (m *LossSample) Reset()                    { *m = LossSample{} }
+func (m *LossSample) String() string            { return proto.CompactTextString(m) }
+func (*LossSample) ProtoMessage()               {}
+func (*LossSample) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{5} }
+
 type JobResultResponse struct {
 }
 
 func (m *JobResultResponse) Reset()                    { *m = JobResultResponse{} }
 func (m *JobResultResponse) String() string            { return proto.CompactTextString(m) }
 func (*JobResultResponse) ProtoMessage()               {}
-func (*JobResultResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{4} }
+func (*JobResultResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{6} }
 
 type JobFail struct {
-	Id   string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
-	Name string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Id        string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Name      string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Reason    string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
+	Permanent bool   `protobuf:"varint,4,opt,name=permanent" json:"permanent,omitempty"`
+	AttemptId string `protobuf:"bytes,5,opt,name=attempt_id" json:"attempt_id,omitempty"`
+	LogTail   string `protobuf:"bytes,6,opt,name=log_tail" json:"log_tail,omitempty"`
+	Preempted bool   `protobuf:"varint,7,opt,name=preempted" json:"preempted,omitempty"`
+	TimedOut  bool   `protobuf:"varint,8,opt,name=timed_out" json:"timed_out,omitempty"`
 }
 
 func (m *JobFail) Reset()                    { *m = JobFail{} }
 func (m *JobFail) String() string            { return proto.CompactTextString(m) }
 func (*JobFail) ProtoMessage()               {}
-func (*JobFail) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{5} }
+func (*JobFail) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{7} }
 
 type JobProgressResponse struct {
 }
@@ -97,16 +174,106 @@ type JobProgressResponse struct {
 func (m *JobProgressResponse) Reset()                    { *m = JobProgressResponse{} }
 func (m *JobProgressResponse) String() string            { return proto.CompactTextString(m) }
 func (*JobProgressResponse) ProtoMessage()               {}
-func (*JobProgressResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{6} }
+func (*JobProgressResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{8} }
+
+type WorkerRegistration struct {
+	WorkerId     string      `protobuf:"bytes,1,opt,name=worker_id" json:"worker_id,omitempty"`
+	Hostname     string      `protobuf:"bytes,2,opt,name=hostname" json:"hostname,omitempty"`
+	Version      string      `protobuf:"bytes,3,opt,name=version" json:"version,omitempty"`
+	Capabilities []string    `protobuf:"bytes,4,rep,name=capabilities" json:"capabilities,omitempty"`
+	Slots        []*SlotInfo `protobuf:"bytes,5,rep,name=slots" json:"slots,omitempty"`
+	Problem      string      `protobuf:"bytes,6,opt,name=problem" json:"problem,omitempty"`
+}
+
+func (m *WorkerRegistration) Reset()                    { *m = WorkerRegistration{} }
+func (m *WorkerRegistration) String() string            { return proto.CompactTextString(m) }
+func (*WorkerRegistration) ProtoMessage()               {}
+func (*WorkerRegistration) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{9} }
+
+func (m *WorkerRegistration) GetSlots() []*SlotInfo {
+	if m != nil {
+		return m.Slots
+	}
+	return nil
+}
+
+type WorkerRegistrationResponse struct {
+	HeartbeatInterval int32 `protobuf:"varint,1,opt,name=heartbeat_interval" json:"heartbeat_interval,omitempty"`
+}
+
+func (m *WorkerRegistrationResponse) Reset()                    { *m = WorkerRegistrationResponse{} }
+func (m *WorkerRegistrationResponse) String() string            { return proto.CompactTextString(m) }
+func (*WorkerRegistrationResponse) ProtoMessage()               {}
+func (*WorkerRegistrationResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{10} }
+
+type WorkerHeartbeat struct {
+	WorkerId string      `protobuf:"bytes,1,opt,name=worker_id" json:"worker_id,omitempty"`
+	State    WorkerState `protobuf:"varint,2,opt,name=state,enum=WorkerState" json:"state,omitempty"`
+	JobId    string      `protobuf:"bytes,3,opt,name=job_id" json:"job_id,omitempty"`
+	JobName  string      `protobuf:"bytes,4,opt,name=job_name" json:"job_name,omitempty"`
+	Slots    []*SlotInfo `protobuf:"bytes,5,rep,name=slots" json:"slots,omitempty"`
+	Problem  string      `protobuf:"bytes,6,opt,name=problem" json:"problem,omitempty"`
+}
+
+func (m *WorkerHeartbeat) Reset()                    { *m = WorkerHeartbeat{} }
+func (m *WorkerHeartbeat) String() string            { return proto.CompactTextString(m) }
+func (*WorkerHeartbeat) ProtoMessage()               {}
+func (*WorkerHeartbeat) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{11} }
+
+func (m *WorkerHeartbeat) GetSlots() []*SlotInfo {
+	if m != nil {
+		return m.Slots
+	}
+	return nil
+}
+
+type HeartbeatResponse struct {
+	Command WorkerCommand `protobuf:"varint,1,opt,name=command,enum=WorkerCommand" json:"command,omitempty"`
+}
+
+func (m *HeartbeatResponse) Reset()                    { *m = HeartbeatResponse{} }
+func (m *HeartbeatResponse) String() string            { return proto.CompactTextString(m) }
+func (*HeartbeatResponse) ProtoMessage()               {}
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{12} }
+
+type JobLogUpload struct {
+	Id        string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Name      string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	AttemptId string `protobuf:"bytes,3,opt,name=attempt_id" json:"attempt_id,omitempty"`
+	WorkerId  string `protobuf:"bytes,4,opt,name=worker_id" json:"worker_id,omitempty"`
+	Log       string `protobuf:"bytes,5,opt,name=log" json:"log,omitempty"`
+	Truncated bool   `protobuf:"varint,6,opt,name=truncated" json:"truncated,omitempty"`
+}
+
+func (m *JobLogUpload) Reset()                    { *m = JobLogUpload{} }
+func (m *JobLogUpload) String() string            { return proto.CompactTextString(m) }
+func (*JobLogUpload) ProtoMessage()               {}
+func (*JobLogUpload) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{13} }
+
+type JobLogUploadResponse struct {
+}
+
+func (m *JobLogUploadResponse) Reset()                    { *m = JobLogUploadResponse{} }
+func (m *JobLogUploadResponse) String() string            { return proto.CompactTextString(m) }
+func (*JobLogUploadResponse) ProtoMessage()               {}
+func (*JobLogUploadResponse) Descriptor() ([]byte, []int) { return fileDescriptor2, []int{14} }
 
 func init() {
 	proto.RegisterType((*JobRequest)(nil), "JobRequest")
 	proto.RegisterType((*JobAck)(nil), "JobAck")
 	proto.RegisterType((*Job)(nil), "Job")
 	proto.RegisterType((*JobResult)(nil), "JobResult")
+	proto.RegisterType((*ResultUpload)(nil), "ResultUpload")
+	proto.RegisterType((*LossSample)(nil), "LossSample")
 	proto.RegisterType((*JobResultResponse)(nil), "JobResultResponse")
 	proto.RegisterType((*JobFail)(nil), "JobFail")
 	proto.RegisterType((*JobProgressResponse)(nil), "JobProgressResponse")
+	proto.RegisterType((*WorkerRegistration)(nil), "WorkerRegistration")
+	proto.RegisterType((*WorkerRegistrationResponse)(nil), "WorkerRegistrationResponse")
+	proto.RegisterType((*WorkerHeartbeat)(nil), "WorkerHeartbeat")
+	proto.RegisterType((*HeartbeatResponse)(nil), "HeartbeatResponse")
+	proto.RegisterType((*JobLogUpload)(nil), "JobLogUpload")
+	proto.RegisterType((*JobLogUploadResponse)(nil), "JobLogUploadResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -121,6 +288,10 @@ type NeuralStyleWorkerClient interface {
 	ProgressReport(ctx context.Context, in *JobResult, opts ...grpc.CallOption) (*JobProgressResponse, error)
 	CompleteJob(ctx context.Context, in *JobResult, opts ...grpc.CallOption) (*JobResultResponse, error)
 	FailJob(ctx context.Context, in *JobFail, opts ...grpc.CallOption) (*JobFail, error)
+	RegisterWorker(ctx context.Context, in *WorkerRegistration, opts ...grpc.CallOption) (*WorkerRegistrationResponse, error)
+	Heartbeat(ctx context.Context, in *WorkerHeartbeat, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	UploadLog(ctx context.Context, in *JobLogUpload, opts ...grpc.CallOption) (*JobLogUploadResponse, error)
+	UploadResult(ctx context.Context, opts ...grpc.CallOption) (NeuralStyleWorker_UploadResultClient, error)
 }
 
 type neuralStyleWorkerClient struct {
@@ -176,6 +347,67 @@ func (c *neuralStyleWorkerClient) FailJob(ctx context.Context, in *JobFail, opts
 	return out, nil
 }
 
+func (c *neuralStyleWorkerClient) RegisterWorker(ctx context.Context, in *WorkerRegistration, opts ...grpc.CallOption) (*WorkerRegistrationResponse, error) {
+	out := new(WorkerRegistrationResponse)
+	err := grpc.Invoke(ctx, "/NeuralStyleWorker/RegisterWorker", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *neuralStyleWorkerClient) Heartbeat(ctx context.Context, in *WorkerHeartbeat, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := grpc.Invoke(ctx, "/NeuralStyleWorker/Heartbeat", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *neuralStyleWorkerClient) UploadLog(ctx context.Context, in *JobLogUpload, opts ...grpc.CallOption) (*JobLogUploadResponse, error) {
+	out := new(JobLogUploadResponse)
+	err := grpc.Invoke(ctx, "/NeuralStyleWorker/UploadLog", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *neuralStyleWorkerClient) UploadResult(ctx context.Context, opts ...grpc.CallOption) (NeuralStyleWorker_UploadResultClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_NeuralStyleWorker_serviceDesc.Streams[0], c.cc, "/NeuralStyleWorker/UploadResult", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &neuralStyleWorkerUploadResultClient{stream}
+	return x, nil
+}
+
+type NeuralStyleWorker_UploadResultClient interface {
+	Send(*ResultUpload) error
+	CloseAndRecv() (*JobResultResponse, error)
+	grpc.ClientStream
+}
+
+type neuralStyleWorkerUploadResultClient struct {
+	grpc.ClientStream
+}
+
+func (x *neuralStyleWorkerUploadResultClient) Send(m *ResultUpload) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *neuralStyleWorkerUploadResultClient) CloseAndRecv() (*JobResultResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(JobResultResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Server API for NeuralStyleWorker service
 
 type NeuralStyleWorkerServer interface {
@@ -184,6 +416,10 @@ type NeuralStyleWorkerServer interface {
 	ProgressReport(context.Context, *JobResult) (*JobProgressResponse, error)
 	CompleteJob(context.Context, *JobResult) (*JobResultResponse, error)
 	FailJob(context.Context, *JobFail) (*JobFail, error)
+	RegisterWorker(context.Context, *WorkerRegistration) (*WorkerRegistrationResponse, error)
+	Heartbeat(context.Context, *WorkerHeartbeat) (*HeartbeatResponse, error)
+	UploadLog(context.Context, *JobLogUpload) (*JobLogUploadResponse, error)
+	UploadResult(NeuralStyleWorker_UploadResultServer) error
 }
 
 func RegisterNeuralStyleWorkerServer(s *grpc.Server, srv NeuralStyleWorkerServer) {
@@ -250,6 +486,68 @@ func _NeuralStyleWorker_FailJob_Handler(srv interface{}, ctx context.Context, de
 	return out, nil
 }
 
+func _NeuralStyleWorker_RegisterWorker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(WorkerRegistration)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(NeuralStyleWorkerServer).RegisterWorker(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _NeuralStyleWorker_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(WorkerHeartbeat)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(NeuralStyleWorkerServer).Heartbeat(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _NeuralStyleWorker_UploadLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(JobLogUpload)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(NeuralStyleWorkerServer).UploadLog(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _NeuralStyleWorker_UploadResult_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NeuralStyleWorkerServer).UploadResult(&neuralStyleWorkerUploadResultServer{stream})
+}
+
+type NeuralStyleWorker_UploadResultServer interface {
+	SendAndClose(*JobResultResponse) error
+	Recv() (*ResultUpload, error)
+	grpc.ServerStream
+}
+
+type neuralStyleWorkerUploadResultServer struct {
+	grpc.ServerStream
+}
+
+func (x *neuralStyleWorkerUploadResultServer) SendAndClose(m *JobResultResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *neuralStyleWorkerUploadResultServer) Recv() (*ResultUpload, error) {
+	m := new(ResultUpload)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _NeuralStyleWorker_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "NeuralStyleWorker",
 	HandlerType: (*NeuralStyleWorkerServer)(nil),
@@ -274,35 +572,96 @@ var _NeuralStyleWorker_serviceDesc = grpc.ServiceDesc{
 			MethodName: "FailJob",
 			Handler:    _NeuralStyleWorker_FailJob_Handler,
 		},
+		{
+			MethodName: "RegisterWorker",
+			Handler:    _NeuralStyleWorker_RegisterWorker_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _NeuralStyleWorker_Heartbeat_Handler,
+		},
+		{
+			MethodName: "UploadLog",
+			Handler:    _NeuralStyleWorker_UploadLog_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadResult",
+			Handler:       _NeuralStyleWorker_UploadResult_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams: []grpc.StreamDesc{},
 }
 
 var fileDescriptor2 = []byte{
-	// 387 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x52, 0xc1, 0x8e, 0xda, 0x30,
-	0x10, 0x55, 0x80, 0x10, 0x98, 0xd0, 0x48, 0x18, 0x2a, 0x45, 0x91, 0xaa, 0x16, 0xb7, 0x48, 0xbd,
-	0xe0, 0x43, 0xfa, 0x05, 0x2d, 0x12, 0x12, 0x3d, 0x54, 0x55, 0x7a, 0xe8, 0x71, 0x15, 0x82, 0x61,
-	0x81, 0xc4, 0xce, 0xda, 0x8e, 0x56, 0xfb, 0x1b, 0xfb, 0x79, 0xfb, 0x35, 0x6b, 0x3b, 0x09, 0xac,
-	0xc4, 0x1e, 0xb8, 0x44, 0x33, 0x6f, 0x26, 0x33, 0x6f, 0xde, 0x33, 0xc0, 0x91, 0x6f, 0x24, 0x29,
-	0x05, 0x57, 0x3c, 0xf2, 0x0f, 0x45, 0xba, 0xa7, 0x75, 0x82, 0x47, 0x00, 0xbf, 0xf9, 0x26, 0xa1,
-	0x0f, 0x15, 0x95, 0x0a, 0x0f, 0xa0, 0xaf, 0xb3, 0x9f, 0xd9, 0x09, 0x4b, 0xe8, 0xea, 0x08, 0x05,
-	0xd0, 0x39, 0x6c, 0x43, 0xe7, 0x8b, 0xf3, 0x7d, 0x98, 0xe8, 0x08, 0x21, 0xe8, 0xb1, 0xb4, 0xa0,
-	0x61, 0xc7, 0x22, 0x36, 0x46, 0x33, 0x70, 0xa5, 0x7a, 0xca, 0x69, 0xd8, 0xd3, 0xa0, 0x1f, 0xfb,
-	0x64, 0xcd, 0xca, 0x4a, 0xad, 0xcd, 0x92, 0xa4, 0xae, 0xa0, 0x39, 0x78, 0x19, 0x67, 0x8a, 0x32,
-	0x15, 0xba, 0xd7, 0x4d, 0x6d, 0x0d, 0x3f, 0x3b, 0x30, 0xb4, 0x6c, 0x64, 0x95, 0xab, 0x9b, 0x76,
-	0xcf, 0x21, 0xd0, 0x77, 0xec, 0x05, 0x95, 0xf2, 0x2e, 0xe3, 0x95, 0x9e, 0xdf, 0xd5, 0x55, 0x37,
-	0xf9, 0xd0, 0xa2, 0x4b, 0x03, 0xa2, 0x6f, 0xd0, 0xdf, 0x71, 0x51, 0xa4, 0xca, 0x72, 0x0c, 0xe2,
-	0x11, 0xb1, 0x9b, 0x57, 0x16, 0x4b, 0x9a, 0x1a, 0x9a, 0x82, 0x6b, 0xa5, 0xb1, 0x1c, 0x47, 0x49,
-	0x9d, 0xe0, 0x09, 0x8c, 0xcf, 0x9c, 0xf4, 0xb7, 0xe4, 0x4c, 0x52, 0xbc, 0x00, 0x4f, 0x83, 0xab,
-	0xf4, 0x90, 0xdf, 0x42, 0x13, 0x7f, 0x84, 0x89, 0x6e, 0xff, 0xdb, 0x70, 0x6a, 0xa7, 0xc4, 0x2f,
-	0x0e, 0x8c, 0xff, 0xd0, 0x4a, 0xa4, 0xf9, 0x3f, 0x23, 0xd3, 0x7f, 0x2e, 0x4e, 0x54, 0xa0, 0xcf,
-	0x00, 0x8d, 0x1f, 0xc6, 0x01, 0x9f, 0x5c, 0xfc, 0x89, 0x7a, 0x26, 0x41, 0x18, 0x02, 0x6d, 0x11,
-	0xe3, 0x8f, 0x39, 0xdd, 0xee, 0xa9, 0x41, 0x3c, 0x52, 0xdb, 0x16, 0xb5, 0x01, 0x8a, 0x21, 0xb8,
-	0xac, 0x2b, 0xb9, 0x50, 0x08, 0xc8, 0xf9, 0x8c, 0x68, 0x4a, 0xde, 0xa1, 0x83, 0x16, 0xe0, 0x2f,
-	0x79, 0x51, 0xe6, 0x54, 0xd9, 0xa1, 0x6f, 0x7f, 0x40, 0xe4, 0x4a, 0x03, 0xf4, 0x09, 0x3c, 0x23,
-	0x80, 0x69, 0x1d, 0x90, 0x46, 0x8d, 0xe8, 0x1c, 0xfd, 0xfa, 0x0a, 0x33, 0x46, 0x15, 0xd9, 0x89,
-	0x94, 0x65, 0xf7, 0x15, 0x61, 0xf6, 0x4e, 0xfb, 0x1c, 0x52, 0xa1, 0xb4, 0x3d, 0x47, 0x9a, 0xa9,
-	0x4d, 0xdf, 0xbe, 0xc2, 0x1f, 0xaf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x23, 0xa3, 0x3c, 0x9c, 0xa0,
-	0x02, 0x00, 0x00,
+	// 1077 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xa4, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
+	0x17, 0x05, 0xad, 0x5f, 0x5e, 0xc9, 0x4a, 0x3c, 0xfe, 0x01, 0x3f, 0xe6, 0x4b, 0xad, 0x30, 0x75,
+	0xab, 0x4d, 0x08, 0x44, 0x59, 0x77, 0x91, 0xb8, 0x08, 0x2a, 0xd5, 0x48, 0x8b, 0x71, 0x8b, 0x2e,
+	0x85, 0x21, 0x39, 0x96, 0x69, 0x93, 0x33, 0xcc, 0xcc, 0x28, 0x41, 0x5f, 0xa6, 0x0f, 0xd0, 0x45,
+	0xf7, 0x5d, 0x75, 0xd7, 0x67, 0xe8, 0xe3, 0x14, 0xf3, 0x43, 0x4a, 0x96, 0x0c, 0xc3, 0x40, 0x77,
+	0x73, 0xcf, 0xa5, 0x66, 0xe6, 0x9c, 0x7b, 0xee, 0x1d, 0x01, 0xdc, 0xf0, 0x44, 0xc6, 0x95, 0xe0,
+	0x8a, 0x87, 0x83, 0xbc, 0x24, 0x4b, 0xea, 0x82, 0x61, 0x45, 0x04, 0x29, 0xeb, 0xd4, 0xfe, 0x67,
+	0x2e, 0x6e, 0xa9, 0x70, 0x61, 0x94, 0x01, 0xcc, 0x79, 0x82, 0xe9, 0xc7, 0x15, 0x95, 0x0a, 0x3d,
+	0x03, 0xdf, 0xa6, 0x17, 0x79, 0x16, 0x78, 0x63, 0x6f, 0xe2, 0xe3, 0xbe, 0x05, 0x66, 0x19, 0x42,
+	0xd0, 0x96, 0x05, 0x57, 0xc1, 0xde, 0xd8, 0x9b, 0x74, 0xb0, 0x59, 0xa3, 0x33, 0x18, 0xa5, 0x24,
+	0xbd, 0xa6, 0xd9, 0x22, 0xcb, 0x97, 0x54, 0x2a, 0x19, 0xb4, 0xc6, 0xad, 0x89, 0x8f, 0xf7, 0x2d,
+	0xfa, 0xad, 0x05, 0xa3, 0x3e, 0x74, 0xe7, 0x3c, 0x79, 0x9b, 0xde, 0x46, 0x7f, 0xec, 0x41, 0x6b,
+	0xce, 0x13, 0x34, 0x82, 0xbd, 0xe6, 0x88, 0xbd, 0xdc, 0x6c, 0xce, 0x48, 0x49, 0xcd, 0xe6, 0x3e,
+	0x36, 0x6b, 0xf4, 0x02, 0x3a, 0x52, 0xfd, 0x5a, 0xd0, 0xa0, 0x3d, 0xf6, 0x26, 0x83, 0xe9, 0x20,
+	0x9e, 0xb1, 0x6a, 0xa5, 0x66, 0x9a, 0x1a, 0xb6, 0x19, 0x74, 0x06, 0xbd, 0x94, 0x33, 0x45, 0x99,
+	0x0a, 0x3a, 0xbb, 0x1f, 0xd5, 0x39, 0x34, 0xd1, 0x9f, 0x95, 0x25, 0x61, 0x59, 0xd0, 0x1d, 0x7b,
+	0x93, 0xd1, 0x74, 0x14, 0xff, 0x62, 0x68, 0x9d, 0x5b, 0x14, 0xd7, 0x69, 0x74, 0x0a, 0xed, 0x9c,
+	0xe5, 0x2a, 0xe8, 0xed, 0xee, 0x66, 0x12, 0xe8, 0x6b, 0x78, 0x22, 0x15, 0x11, 0x6a, 0x91, 0x2b,
+	0x2a, 0x88, 0xca, 0x39, 0x0b, 0xfa, 0x46, 0x90, 0x91, 0x81, 0x67, 0x35, 0x8a, 0x9e, 0x03, 0x10,
+	0xa5, 0x68, 0x59, 0x29, 0x2d, 0xa6, 0x6f, 0x78, 0xf9, 0x0e, 0x99, 0x65, 0xe8, 0x2b, 0xe8, 0xda,
+	0xba, 0x04, 0x60, 0x8e, 0x1a, 0xc5, 0x73, 0x9e, 0xfc, 0xa8, 0x11, 0xaa, 0xa8, 0x90, 0xd8, 0x65,
+	0xa3, 0xdf, 0xf7, 0xc0, 0x37, 0x15, 0x92, 0xab, 0x42, 0x3d, 0x4a, 0xb6, 0x33, 0x18, 0x55, 0x82,
+	0x2f, 0x05, 0x95, 0x72, 0x91, 0xf2, 0x15, 0x53, 0x41, 0xcb, 0x5c, 0x70, 0xbf, 0x46, 0xcf, 0x35,
+	0x88, 0xbe, 0x84, 0xee, 0x15, 0x17, 0x25, 0x51, 0x46, 0xde, 0xd1, 0x74, 0x18, 0x1b, 0x9a, 0xef,
+	0x0d, 0x86, 0x5d, 0x0e, 0x1d, 0x41, 0xc7, 0x78, 0xc9, 0xc8, 0x3b, 0xc4, 0x36, 0xd8, 0xe2, 0xd6,
+	0xdd, 0xe6, 0x16, 0x42, 0x5f, 0x6a, 0x47, 0xb1, 0x94, 0x1a, 0x21, 0x5b, 0xb8, 0x89, 0xd1, 0x4b,
+	0xe8, 0x16, 0x5c, 0x4a, 0x2a, 0x83, 0xfe, 0xb8, 0x65, 0x24, 0xbe, 0xe0, 0x52, 0x5e, 0x92, 0xb2,
+	0x2a, 0x28, 0x76, 0x29, 0xf4, 0x05, 0x00, 0x61, 0x8c, 0x2b, 0xab, 0xaf, 0xd5, 0x6e, 0x03, 0x41,
+	0x27, 0xd0, 0xb5, 0x7e, 0x33, 0xe2, 0xf9, 0xd8, 0x45, 0xd1, 0x47, 0x18, 0x5a, 0xa1, 0x7e, 0xae,
+	0x0a, 0x4e, 0x32, 0x14, 0x41, 0x57, 0x98, 0xd8, 0x48, 0x36, 0x98, 0x42, 0xdc, 0x48, 0x89, 0x5d,
+	0x46, 0x5f, 0x36, 0xe5, 0xfa, 0x74, 0x65, 0x65, 0xec, 0xe3, 0x26, 0xd6, 0x0e, 0x4c, 0xaf, 0x57,
+	0xec, 0xd6, 0x28, 0x68, 0xec, 0xa0, 0xe9, 0x9f, 0x6b, 0x08, 0xdb, 0x4c, 0x54, 0x01, 0xac, 0x09,
+	0xa0, 0xff, 0x83, 0xbf, 0xf6, 0x85, 0x67, 0x64, 0x5f, 0x03, 0x28, 0x58, 0xbb, 0x55, 0x9f, 0xe4,
+	0xad, 0x0d, 0x7a, 0x54, 0x5b, 0xbd, 0x65, 0x70, 0x1b, 0x68, 0x54, 0x71, 0x45, 0x0a, 0x53, 0x21,
+	0x0f, 0xdb, 0x20, 0x3a, 0x84, 0x83, 0x35, 0x0b, 0x2a, 0x2b, 0xce, 0x24, 0x8d, 0xfe, 0xf1, 0xa0,
+	0x37, 0xe7, 0xc9, 0x7b, 0x92, 0x17, 0x8f, 0x32, 0xc9, 0x89, 0x56, 0x86, 0x48, 0xce, 0xcc, 0x89,
+	0x3e, 0x76, 0x91, 0x26, 0x50, 0x51, 0x51, 0x12, 0xa6, 0x2f, 0xd9, 0x36, 0x72, 0xac, 0x81, 0xad,
+	0xba, 0x77, 0xb6, 0xeb, 0xfe, 0x3f, 0xe8, 0x17, 0x7c, 0xb9, 0x50, 0x24, 0x2f, 0x9c, 0x29, 0x7a,
+	0x05, 0x5f, 0xfe, 0xa4, 0xef, 0xa4, 0xf7, 0x15, 0x54, 0x7f, 0x47, 0xb3, 0xa0, 0xe7, 0xf6, 0xad,
+	0x01, 0x3d, 0x77, 0x54, 0x5e, 0xd2, 0x6c, 0xc1, 0x57, 0xca, 0xb4, 0x53, 0x1f, 0xf7, 0x0d, 0xf0,
+	0xc3, 0x4a, 0x45, 0xc7, 0x70, 0xa8, 0x5b, 0xc3, 0x99, 0xb7, 0x61, 0xfc, 0xb7, 0x07, 0xc8, 0x36,
+	0x31, 0xa6, 0xcb, 0x5c, 0x2a, 0xa7, 0xf1, 0x83, 0x23, 0x2c, 0x84, 0xfe, 0x35, 0x97, 0x6a, 0x43,
+	0x8d, 0x26, 0xd6, 0xc5, 0xf9, 0x44, 0x85, 0xcc, 0x1b, 0x49, 0xea, 0x10, 0x45, 0x30, 0x4c, 0x49,
+	0x45, 0x92, 0xbc, 0xc8, 0x55, 0x4e, 0x65, 0xd0, 0x36, 0x23, 0xee, 0x0e, 0x86, 0x4e, 0xa1, 0xa3,
+	0x07, 0xa2, 0x0c, 0x3a, 0xc6, 0xd5, 0x7e, 0x7c, 0x59, 0x70, 0x35, 0x63, 0x57, 0x1c, 0x5b, 0x5c,
+	0x6f, 0x5f, 0x09, 0x9e, 0x14, 0xb4, 0xac, 0xa5, 0x71, 0x61, 0xf4, 0x3d, 0x84, 0xbb, 0x3c, 0x6a,
+	0x9a, 0xe8, 0x15, 0xa0, 0x6b, 0x4a, 0x84, 0x4a, 0x28, 0x51, 0x8b, 0x9c, 0x29, 0x2a, 0x3e, 0x91,
+	0xc2, 0x59, 0xeb, 0xa0, 0xc9, 0xcc, 0x5c, 0x22, 0xfa, 0xcb, 0x83, 0x27, 0x76, 0xb7, 0xef, 0xea,
+	0xdc, 0xc3, 0x92, 0x44, 0xda, 0x79, 0xc4, 0x79, 0x5f, 0x4f, 0x01, 0xfb, 0xeb, 0x4b, 0x8d, 0x61,
+	0x9b, 0x42, 0xc7, 0xd0, 0xbd, 0xe1, 0x89, 0xfe, 0xb5, 0x55, 0xa6, 0x73, 0xc3, 0x13, 0x5b, 0x6e,
+	0x0d, 0x1b, 0x35, 0xdb, 0x96, 0xd3, 0x0d, 0x4f, 0x3e, 0x68, 0x31, 0xff, 0x83, 0x1c, 0xdf, 0xc0,
+	0x41, 0x73, 0xf5, 0x46, 0x85, 0x8d, 0x01, 0xee, 0x3d, 0x38, 0xc0, 0xa3, 0xdf, 0x3c, 0x18, 0xce,
+	0x79, 0x72, 0xc1, 0x97, 0x6e, 0x06, 0x3c, 0xa6, 0x1b, 0xee, 0xfa, 0xba, 0xb5, 0xed, 0xeb, 0x3b,
+	0x02, 0xb6, 0xb7, 0x04, 0x7c, 0x0a, 0xad, 0x82, 0x2f, 0x5d, 0x33, 0xe8, 0xa5, 0xf6, 0xba, 0x12,
+	0x2b, 0x96, 0x12, 0xed, 0xf5, 0xae, 0xf5, 0x7a, 0x03, 0x44, 0x27, 0x70, 0xb4, 0x79, 0xbf, 0x9a,
+	0xe2, 0xf4, 0xcf, 0x16, 0x1c, 0x7c, 0xa0, 0x2b, 0x41, 0x8a, 0x4b, 0xdd, 0xfc, 0x96, 0x1e, 0x3a,
+	0x05, 0x70, 0x8f, 0xb3, 0x7e, 0x35, 0x07, 0xf1, 0xfa, 0xb1, 0x0e, 0xdb, 0x3a, 0x40, 0x11, 0x8c,
+	0xde, 0xa6, 0xb7, 0x8c, 0x7f, 0x2e, 0x68, 0xb6, 0xa4, 0x1a, 0xe9, 0xc5, 0xf6, 0xad, 0x0d, 0xeb,
+	0x05, 0x9a, 0xc2, 0x68, 0xdd, 0x3e, 0x15, 0x17, 0x0a, 0x6d, 0x0c, 0xc2, 0xf0, 0x28, 0xbe, 0xa7,
+	0xbd, 0xd0, 0x2b, 0x18, 0x9c, 0xbb, 0x31, 0xa8, 0x37, 0xdd, 0xfc, 0x01, 0x8a, 0x77, 0xe6, 0x0f,
+	0x7a, 0x0e, 0x3d, 0x3d, 0x7b, 0xf4, 0xa7, 0xfd, 0xd8, 0x0d, 0xa2, 0xb0, 0x59, 0xa1, 0x77, 0x30,
+	0xb2, 0xee, 0xa6, 0xc2, 0x11, 0x3b, 0x8c, 0x77, 0x4d, 0x1f, 0x3e, 0x8b, 0x1f, 0xe8, 0x84, 0xd7,
+	0xe0, 0xaf, 0x3d, 0xfd, 0x34, 0xde, 0x72, 0x79, 0x88, 0xe2, 0x5d, 0xdb, 0xbc, 0x06, 0xdf, 0xaa,
+	0x7c, 0xc1, 0x97, 0x68, 0x3f, 0xde, 0xd4, 0x3d, 0x3c, 0x8e, 0xef, 0x2b, 0x03, 0x7a, 0x03, 0xc3,
+	0x06, 0xd1, 0xcf, 0xc3, 0x7e, 0xbc, 0xf9, 0xa2, 0xdc, 0xc7, 0x7d, 0xe2, 0xbd, 0x7b, 0x09, 0x2f,
+	0x18, 0x55, 0xf1, 0x95, 0x20, 0x2c, 0xbd, 0x5e, 0xc5, 0xcc, 0x94, 0xd1, 0xcc, 0x70, 0x22, 0x54,
+	0x25, 0xf8, 0x0d, 0x4d, 0x55, 0xd2, 0x35, 0xff, 0xb8, 0xde, 0xfc, 0x3b, 0x00, 0x25, 0x81, 0x36,
+	0xb4, 0xa9, 0x09, 0x00, 0x00,
 }
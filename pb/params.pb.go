@@ -0,0 +1,302 @@
+// Code generated by protoc-gen-go.
+// source: params.proto
+// DO NOT EDIT!
+
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type SweepParameter int32
+
+const (
+	SweepParameter_SWEEP_UNKNOWN  SweepParameter = 0
+	SweepParameter_CONTENT_WEIGHT SweepParameter = 1
+	SweepParameter_STYLE_WEIGHT   SweepParameter = 2
+	SweepParameter_STYLE_SCALE    SweepParameter = 3
+	SweepParameter_TV_WEIGHT      SweepParameter = 4
+)
+
+var SweepParameter_name = map[int32]string{
+	0: "SWEEP_UNKNOWN",
+	1: "CONTENT_WEIGHT",
+	2: "STYLE_WEIGHT",
+	3: "STYLE_SCALE",
+	4: "TV_WEIGHT",
+}
+var SweepParameter_value = map[string]int32{
+	"SWEEP_UNKNOWN":  0,
+	"CONTENT_WEIGHT": 1,
+	"STYLE_WEIGHT":   2,
+	"STYLE_SCALE":    3,
+	"TV_WEIGHT":      4,
+}
+
+func (x SweepParameter) String() string {
+	return proto.EnumName(SweepParameter_name, int32(x))
+}
+func (SweepParameter) EnumDescriptor() ([]byte, []int) { return fileDescriptor3, []int{0} }
+
+type ColorTransfer int32
+
+const (
+	ColorTransfer_KEEP_COLORS   ColorTransfer = 0
+	ColorTransfer_LUMINANCE     ColorTransfer = 1
+	ColorTransfer_MATCH_CONTENT ColorTransfer = 2
+	ColorTransfer_MATCH_STYLE   ColorTransfer = 3
+)
+
+var ColorTransfer_name = map[int32]string{
+	0: "KEEP_COLORS",
+	1: "LUMINANCE",
+	2: "MATCH_CONTENT",
+	3: "MATCH_STYLE",
+}
+var ColorTransfer_value = map[string]int32{
+	"KEEP_COLORS":   0,
+	"LUMINANCE":     1,
+	"MATCH_CONTENT": 2,
+	"MATCH_STYLE":   3,
+}
+
+func (x ColorTransfer) String() string {
+	return proto.EnumName(ColorTransfer_name, int32(x))
+}
+func (ColorTransfer) EnumDescriptor() ([]byte, []int) { return fileDescriptor3, []int{1} }
+
+type JobParameters struct {
+	Convergence   *Convergence `protobuf:"bytes,1,opt,name=convergence" json:"convergence,omitempty"`
+	Timeouts      *Timeouts    `protobuf:"bytes,2,opt,name=timeouts" json:"timeouts,omitempty"`
+	ImageSize     int32        `protobuf:"varint,3,opt,name=image_size" json:"image_size,omitempty"`
+	OutputFormat  ImageFormat  `protobuf:"varint,4,opt,name=output_format,enum=ImageFormat" json:"output_format,omitempty"`
+	Tiling        *Tiling      `protobuf:"bytes,5,opt,name=tiling" json:"tiling,omitempty"`
+	NumIterations int32        `protobuf:"varint,6,opt,name=num_iterations" json:"num_iterations,omitempty"`
+	Stages        []*Stage     `protobuf:"bytes,7,rep,name=stages" json:"stages,omitempty"`
+	Animation     *Animation   `protobuf:"bytes,8,opt,name=animation" json:"animation,omitempty"`
+	ContentWeight float64      `protobuf:"fixed64,9,opt,name=content_weight" json:"content_weight,omitempty"`
+	StyleWeight   float64      `protobuf:"fixed64,10,opt,name=style_weight" json:"style_weight,omitempty"`
+	StyleScale    float64      `protobuf:"fixed64,11,opt,name=style_scale" json:"style_scale,omitempty"`
+	TvWeight      float64      `protobuf:"fixed64,12,opt,name=tv_weight" json:"tv_weight,omitempty"`
+	Sweep         *Sweep       `protobuf:"bytes,13,opt,name=sweep" json:"sweep,omitempty"`
+	PostProcess   *PostProcess `protobuf:"bytes,14,opt,name=post_process" json:"post_process,omitempty"`
+}
+
+func (m *JobParameters) Reset()                    { *m = JobParameters{} }
+func (m *JobParameters) String() string            { return proto.CompactTextString(m) }
+func (*JobParameters) ProtoMessage()               {}
+func (*JobParameters) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{0} }
+
+func (m *JobParameters) GetConvergence() *Convergence {
+	if m != nil {
+		return m.Convergence
+	}
+	return nil
+}
+
+func (m *JobParameters) GetTimeouts() *Timeouts {
+	if m != nil {
+		return m.Timeouts
+	}
+	return nil
+}
+
+func (m *JobParameters) GetTiling() *Tiling {
+	if m != nil {
+		return m.Tiling
+	}
+	return nil
+}
+
+func (m *JobParameters) GetStages() []*Stage {
+	if m != nil {
+		return m.Stages
+	}
+	return nil
+}
+
+func (m *JobParameters) GetAnimation() *Animation {
+	if m != nil {
+		return m.Animation
+	}
+	return nil
+}
+
+func (m *JobParameters) GetSweep() *Sweep {
+	if m != nil {
+		return m.Sweep
+	}
+	return nil
+}
+
+func (m *JobParameters) GetPostProcess() *PostProcess {
+	if m != nil {
+		return m.PostProcess
+	}
+	return nil
+}
+
+type Convergence struct {
+	Threshold float64 `protobuf:"fixed64,1,opt,name=threshold" json:"threshold,omitempty"`
+	Window    int32   `protobuf:"varint,2,opt,name=window" json:"window,omitempty"`
+}
+
+func (m *Convergence) Reset()                    { *m = Convergence{} }
+func (m *Convergence) String() string            { return proto.CompactTextString(m) }
+func (*Convergence) ProtoMessage()               {}
+func (*Convergence) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{1} }
+
+type Tiling struct {
+	TileSize int32 `protobuf:"varint,1,opt,name=tile_size" json:"tile_size,omitempty"`
+	Overlap  int32 `protobuf:"varint,2,opt,name=overlap" json:"overlap,omitempty"`
+}
+
+func (m *Tiling) Reset()                    { *m = Tiling{} }
+func (m *Tiling) String() string            { return proto.CompactTextString(m) }
+func (*Tiling) ProtoMessage()               {}
+func (*Tiling) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{2} }
+
+type Stage struct {
+	ImageSize     int32 `protobuf:"varint,1,opt,name=image_size" json:"image_size,omitempty"`
+	NumIterations int32 `protobuf:"varint,2,opt,name=num_iterations" json:"num_iterations,omitempty"`
+}
+
+func (m *Stage) Reset()                    { *m = Stage{} }
+func (m *Stage) String() string            { return proto.CompactTextString(m) }
+func (*Stage) ProtoMessage()               {}
+func (*Stage) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{3} }
+
+type Animation struct {
+	ChainFrames bool `protobuf:"varint,1,opt,name=chain_frames" json:"chain_frames,omitempty"`
+}
+
+func (m *Animation) Reset()                    { *m = Animation{} }
+func (m *Animation) String() string            { return proto.CompactTextString(m) }
+func (*Animation) ProtoMessage()               {}
+func (*Animation) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{4} }
+
+type Sweep struct {
+	Axes []*SweepAxis `protobuf:"bytes,1,rep,name=axes" json:"axes,omitempty"`
+}
+
+func (m *Sweep) Reset()                    { *m = Sweep{} }
+func (m *Sweep) String() string            { return proto.CompactTextString(m) }
+func (*Sweep) ProtoMessage()               {}
+func (*Sweep) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{5} }
+
+func (m *Sweep) GetAxes() []*SweepAxis {
+	if m != nil {
+		return m.Axes
+	}
+	return nil
+}
+
+type SweepAxis struct {
+	Parameter   SweepParameter `protobuf:"varint,1,opt,name=parameter,enum=SweepParameter" json:"parameter,omitempty"`
+	From        float64        `protobuf:"fixed64,2,opt,name=from" json:"from,omitempty"`
+	To          float64        `protobuf:"fixed64,3,opt,name=to" json:"to,omitempty"`
+	Steps       int32          `protobuf:"varint,4,opt,name=steps" json:"steps,omitempty"`
+	Logarithmic bool           `protobuf:"varint,5,opt,name=logarithmic" json:"logarithmic,omitempty"`
+}
+
+func (m *SweepAxis) Reset()                    { *m = SweepAxis{} }
+func (m *SweepAxis) String() string            { return proto.CompactTextString(m) }
+func (*SweepAxis) ProtoMessage()               {}
+func (*SweepAxis) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{6} }
+
+type PostProcess struct {
+	ColorTransfer ColorTransfer `protobuf:"varint,1,opt,name=color_transfer,enum=ColorTransfer" json:"color_transfer,omitempty"`
+	Strength      float64       `protobuf:"fixed64,2,opt,name=strength" json:"strength,omitempty"`
+	MaskFeather   int32         `protobuf:"varint,3,opt,name=mask_feather" json:"mask_feather,omitempty"`
+	InvertMask    bool          `protobuf:"varint,4,opt,name=invert_mask" json:"invert_mask,omitempty"`
+}
+
+func (m *PostProcess) Reset()                    { *m = PostProcess{} }
+func (m *PostProcess) String() string            { return proto.CompactTextString(m) }
+func (*PostProcess) ProtoMessage()               {}
+func (*PostProcess) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{7} }
+
+type Timeouts struct {
+	MaxDuration int32 `protobuf:"varint,1,opt,name=max_duration" json:"max_duration,omitempty"`
+	Stall       int32 `protobuf:"varint,2,opt,name=stall" json:"stall,omitempty"`
+}
+
+func (m *Timeouts) Reset()                    { *m = Timeouts{} }
+func (m *Timeouts) String() string            { return proto.CompactTextString(m) }
+func (*Timeouts) ProtoMessage()               {}
+func (*Timeouts) Descriptor() ([]byte, []int) { return fileDescriptor3, []int{8} }
+
+func init() {
+	proto.RegisterType((*JobParameters)(nil), "JobParameters")
+	proto.RegisterType((*Convergence)(nil), "Convergence")
+	proto.RegisterType((*Tiling)(nil), "Tiling")
+	proto.RegisterType((*Stage)(nil), "Stage")
+	proto.RegisterType((*Animation)(nil), "Animation")
+	proto.RegisterType((*Sweep)(nil), "Sweep")
+	proto.RegisterType((*SweepAxis)(nil), "SweepAxis")
+	proto.RegisterType((*PostProcess)(nil), "PostProcess")
+	proto.RegisterType((*Timeouts)(nil), "Timeouts")
+	proto.RegisterEnum("SweepParameter", SweepParameter_name, SweepParameter_value)
+	proto.RegisterEnum("ColorTransfer", ColorTransfer_name, ColorTransfer_value)
+}
+
+var fileDescriptor3 = []byte{
+	// 824 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x6c, 0x94, 0x5d, 0x6f, 0xdb, 0xb6,
+	0x17, 0xc6, 0x2b, 0xbf, 0xc5, 0x3a, 0xb2, 0x5d, 0xff, 0x89, 0x3f, 0x06, 0xa1, 0xeb, 0x5a, 0xd7,
+	0x43, 0x31, 0xa3, 0xc0, 0x34, 0x2c, 0xc3, 0xae, 0x07, 0x4f, 0x73, 0xd6, 0xac, 0x89, 0x13, 0x50,
+	0xea, 0x82, 0x5d, 0x09, 0xac, 0x42, 0xdb, 0x5c, 0x25, 0x51, 0x20, 0xe9, 0x24, 0xeb, 0x07, 0xd9,
+	0xfd, 0x80, 0x7d, 0xd0, 0x81, 0x87, 0xf2, 0x4b, 0xbb, 0xdd, 0xf9, 0xf9, 0xf1, 0x21, 0x0f, 0xcd,
+	0xe7, 0x1c, 0xc1, 0xa0, 0x66, 0x8a, 0x95, 0x3a, 0xaa, 0x95, 0x34, 0xf2, 0x49, 0x20, 0x4a, 0xb6,
+	0xe6, 0x4e, 0x4c, 0xff, 0xea, 0xc0, 0xf0, 0x17, 0xf9, 0xee, 0xda, 0x1a, 0xb8, 0xe1, 0x4a, 0x93,
+	0x08, 0x82, 0x5c, 0x56, 0x77, 0x5c, 0xad, 0x79, 0x95, 0xf3, 0xd0, 0x9b, 0x78, 0xb3, 0xe0, 0x74,
+	0x10, 0xc5, 0x07, 0x46, 0x8f, 0x0d, 0xe4, 0x25, 0xf4, 0x8d, 0x28, 0xb9, 0xdc, 0x1a, 0x1d, 0xb6,
+	0xd0, 0xec, 0x47, 0x69, 0x03, 0xe8, 0x7e, 0x89, 0x7c, 0x01, 0x80, 0x75, 0x33, 0x2d, 0x3e, 0xf0,
+	0xb0, 0x3d, 0xf1, 0x66, 0x5d, 0xea, 0x23, 0x49, 0xc4, 0x07, 0x4e, 0xbe, 0x85, 0xa1, 0xdc, 0x9a,
+	0x7a, 0x6b, 0xb2, 0x95, 0x54, 0x25, 0x33, 0x61, 0x67, 0xe2, 0xcd, 0x46, 0xa7, 0x83, 0xe8, 0xdc,
+	0x5a, 0xce, 0x90, 0xd1, 0x81, 0xb3, 0x38, 0x45, 0x9e, 0x43, 0xcf, 0x88, 0x42, 0x54, 0xeb, 0xb0,
+	0x8b, 0x65, 0x4f, 0xa2, 0x14, 0x25, 0x6d, 0x30, 0x79, 0x09, 0xa3, 0x6a, 0x5b, 0x66, 0xc2, 0x70,
+	0xc5, 0x8c, 0x90, 0x95, 0x0e, 0x7b, 0x58, 0x76, 0x58, 0x6d, 0xcb, 0xf3, 0x3d, 0x24, 0xcf, 0xa0,
+	0xa7, 0x0d, 0x5b, 0x73, 0x1d, 0x9e, 0x4c, 0xda, 0xb3, 0xe0, 0xb4, 0x17, 0x25, 0x56, 0xd2, 0x86,
+	0x92, 0x19, 0xf8, 0xac, 0x12, 0x25, 0xba, 0xc3, 0x3e, 0x96, 0x82, 0x68, 0xbe, 0x23, 0xf4, 0xb0,
+	0x68, 0x0b, 0xe6, 0xb2, 0x32, 0xbc, 0x32, 0xd9, 0x3d, 0x17, 0xeb, 0x8d, 0x09, 0xfd, 0x89, 0x37,
+	0xf3, 0xe8, 0xb0, 0xa1, 0x37, 0x08, 0xc9, 0x0b, 0x18, 0x68, 0xf3, 0x47, 0xc1, 0x77, 0x26, 0x40,
+	0x53, 0x80, 0xac, 0xb1, 0x3c, 0x07, 0x27, 0x33, 0x9d, 0xb3, 0x82, 0x87, 0x01, 0x3a, 0x00, 0x51,
+	0x62, 0x09, 0xf9, 0x1c, 0x7c, 0x73, 0xb7, 0x3b, 0x60, 0x80, 0xcb, 0x7d, 0x73, 0xd7, 0xec, 0x7e,
+	0x0a, 0x5d, 0x7d, 0xcf, 0x79, 0x1d, 0x0e, 0xf1, 0xb6, 0xbd, 0x28, 0xb1, 0x8a, 0x3a, 0x48, 0xbe,
+	0x81, 0x41, 0x2d, 0xb5, 0xc9, 0x6a, 0x25, 0x73, 0xae, 0x75, 0x38, 0x6a, 0x12, 0xbe, 0x96, 0xda,
+	0x5c, 0x3b, 0x46, 0x83, 0xfa, 0x20, 0xa6, 0x31, 0x04, 0x47, 0xe9, 0x93, 0xa7, 0xe0, 0x9b, 0x8d,
+	0xe2, 0x7a, 0x23, 0x8b, 0x5b, 0x6c, 0x0f, 0x8f, 0x1e, 0x00, 0xf9, 0x0c, 0x7a, 0xf7, 0xa2, 0xba,
+	0x95, 0xf7, 0xd8, 0x0c, 0x5d, 0xda, 0xa8, 0xe9, 0x0f, 0xd0, 0x73, 0xf1, 0xe0, 0xd5, 0x45, 0xd1,
+	0x34, 0x82, 0x87, 0xa6, 0xbe, 0x05, 0xd8, 0x07, 0x21, 0x9c, 0xc8, 0x3b, 0xae, 0x0a, 0x56, 0x37,
+	0xfb, 0x77, 0x72, 0x7a, 0x09, 0x5d, 0xcc, 0xe5, 0x93, 0x4e, 0xf2, 0x3e, 0xed, 0xa4, 0x7f, 0xa7,
+	0xde, 0xfa, 0x8f, 0xd4, 0xa7, 0x11, 0xf8, 0xfb, 0x0c, 0x6d, 0x22, 0xf9, 0x86, 0x89, 0x2a, 0x5b,
+	0xd9, 0x31, 0xd0, 0x78, 0x68, 0x9f, 0x06, 0xc8, 0xce, 0x10, 0x4d, 0xbf, 0x82, 0x2e, 0xbe, 0x22,
+	0x79, 0x06, 0x1d, 0xf6, 0x80, 0x9e, 0x36, 0x76, 0x02, 0xd2, 0xf9, 0x83, 0xd0, 0x14, 0xf9, 0xf4,
+	0x4f, 0x0f, 0xfc, 0x3d, 0x23, 0x5f, 0x83, 0x5f, 0xef, 0x66, 0x0b, 0x8f, 0x1d, 0x9d, 0x3e, 0x76,
+	0x5b, 0xf6, 0x23, 0x47, 0x0f, 0x0e, 0x42, 0xa0, 0xb3, 0x52, 0xb2, 0xc4, 0x2b, 0x7b, 0x14, 0x7f,
+	0x93, 0x11, 0xb4, 0x8c, 0xc4, 0x89, 0xf1, 0x68, 0xcb, 0x48, 0xf2, 0x7f, 0xe8, 0x6a, 0xc3, 0x6b,
+	0x8d, 0x23, 0xd2, 0xa5, 0x4e, 0x90, 0x09, 0x04, 0x85, 0x5c, 0x33, 0x25, 0xcc, 0xa6, 0x14, 0x39,
+	0x8e, 0x44, 0x9f, 0x1e, 0xa3, 0xe9, 0xdf, 0x1e, 0x04, 0x47, 0x19, 0x93, 0xef, 0x6d, 0xb7, 0x16,
+	0x52, 0x65, 0x46, 0xb1, 0x4a, 0xaf, 0xf6, 0xf7, 0x1b, 0x45, 0xb1, 0xc5, 0x69, 0x43, 0x6d, 0xf7,
+	0x1e, 0x49, 0xf2, 0x04, 0xfa, 0xda, 0x28, 0x5e, 0xad, 0xcd, 0xa6, 0xb9, 0xe6, 0x5e, 0xdb, 0x77,
+	0x2c, 0x99, 0x7e, 0x9f, 0xad, 0x38, 0x33, 0x1b, 0xae, 0x9a, 0x31, 0x0f, 0x2c, 0x3b, 0x73, 0xc8,
+	0x76, 0xb6, 0xb0, 0xbd, 0x64, 0x32, 0x4b, 0xf1, 0x3f, 0xf4, 0x29, 0x38, 0x74, 0xc9, 0xf4, 0xfb,
+	0x69, 0x0c, 0xfd, 0xdd, 0xe7, 0xc3, 0x9d, 0xf7, 0x90, 0xdd, 0x6e, 0x5d, 0x6a, 0x4d, 0xd8, 0x41,
+	0xc9, 0x1e, 0x7e, 0x6a, 0x90, 0x7b, 0x0d, 0x56, 0x14, 0x4d, 0xca, 0x4e, 0xbc, 0x12, 0x30, 0xfa,
+	0xf8, 0x91, 0xc9, 0xff, 0x60, 0x98, 0xdc, 0x2c, 0x16, 0xd7, 0xd9, 0xdb, 0xe5, 0x9b, 0xe5, 0xd5,
+	0xcd, 0x72, 0xfc, 0x88, 0x10, 0x18, 0xc5, 0x57, 0xcb, 0x74, 0xb1, 0x4c, 0xb3, 0x9b, 0xc5, 0xf9,
+	0xcf, 0xaf, 0xd3, 0xb1, 0x47, 0xc6, 0x30, 0x48, 0xd2, 0xdf, 0x2e, 0x16, 0x3b, 0xd2, 0x22, 0x8f,
+	0x21, 0x70, 0x24, 0x89, 0xe7, 0x17, 0x8b, 0x71, 0x9b, 0x0c, 0xc1, 0x4f, 0x7f, 0xdd, 0xad, 0x77,
+	0x5e, 0x25, 0x30, 0xfc, 0xe8, 0xbd, 0xec, 0x86, 0x37, 0xb6, 0x50, 0x7c, 0x75, 0x71, 0x45, 0x93,
+	0xf1, 0x23, 0xbb, 0xe1, 0xe2, 0xed, 0xe5, 0xf9, 0x72, 0xbe, 0x8c, 0x17, 0x63, 0xcf, 0xde, 0xe4,
+	0x72, 0x9e, 0xc6, 0xaf, 0xb3, 0xa6, 0xb8, 0xab, 0xe1, 0x10, 0x56, 0x1a, 0xb7, 0x7f, 0xfc, 0x12,
+	0x5e, 0x54, 0xdc, 0x44, 0x2b, 0xc5, 0xaa, 0x7c, 0xb3, 0x8d, 0x2a, 0xbe, 0x55, 0xac, 0xc0, 0xf1,
+	0x67, 0xca, 0xd4, 0x4a, 0xfe, 0xce, 0x73, 0xf3, 0xae, 0x87, 0x9f, 0xf0, 0xef, 0xfe, 0x19, 0x00,
+	0xdf, 0xa9, 0x5e, 0x76, 0xdf, 0x05, 0x00, 0x00,
+}
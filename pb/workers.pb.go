@@ -0,0 +1,317 @@
+// Code generated by protoc-gen-go.
+// source: workers.proto
+// DO NOT EDIT!
+
+package pb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+type WorkerState int32
+
+const (
+	WorkerState_WORKER_UNKNOWN   WorkerState = 0
+	WorkerState_WORKER_IDLE      WorkerState = 1
+	WorkerState_WORKER_BUSY      WorkerState = 2
+	WorkerState_WORKER_OFFLINE   WorkerState = 3
+	WorkerState_WORKER_PAUSED    WorkerState = 4
+	WorkerState_WORKER_DRAINED   WorkerState = 5
+	WorkerState_WORKER_UNHEALTHY WorkerState = 6
+)
+
+var WorkerState_name = map[int32]string{
+	0: "WORKER_UNKNOWN",
+	1: "WORKER_IDLE",
+	2: "WORKER_BUSY",
+	3: "WORKER_OFFLINE",
+	4: "WORKER_PAUSED",
+	5: "WORKER_DRAINED",
+	6: "WORKER_UNHEALTHY",
+}
+var WorkerState_value = map[string]int32{
+	"WORKER_UNKNOWN":   0,
+	"WORKER_IDLE":      1,
+	"WORKER_BUSY":      2,
+	"WORKER_OFFLINE":   3,
+	"WORKER_PAUSED":    4,
+	"WORKER_DRAINED":   5,
+	"WORKER_UNHEALTHY": 6,
+}
+
+func (x WorkerState) String() string {
+	return proto.EnumName(WorkerState_name, int32(x))
+}
+func (WorkerState) EnumDescriptor() ([]byte, []int) { return fileDescriptor4, []int{0} }
+
+type WorkerCommand int32
+
+const (
+	WorkerCommand_COMMAND_NONE     WorkerCommand = 0
+	WorkerCommand_COMMAND_DRAIN    WorkerCommand = 1
+	WorkerCommand_COMMAND_PAUSE    WorkerCommand = 2
+	WorkerCommand_COMMAND_RESUME   WorkerCommand = 3
+	WorkerCommand_COMMAND_SHUTDOWN WorkerCommand = 4
+)
+
+var WorkerCommand_name = map[int32]string{
+	0: "COMMAND_NONE",
+	1: "COMMAND_DRAIN",
+	2: "COMMAND_PAUSE",
+	3: "COMMAND_RESUME",
+	4: "COMMAND_SHUTDOWN",
+}
+var WorkerCommand_value = map[string]int32{
+	"COMMAND_NONE":     0,
+	"COMMAND_DRAIN":    1,
+	"COMMAND_PAUSE":    2,
+	"COMMAND_RESUME":   3,
+	"COMMAND_SHUTDOWN": 4,
+}
+
+func (x WorkerCommand) String() string {
+	return proto.EnumName(WorkerCommand_name, int32(x))
+}
+func (WorkerCommand) EnumDescriptor() ([]byte, []int) { return fileDescriptor4, []int{1} }
+
+type WorkerInfo struct {
+	Id             string        `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Hostname       string        `protobuf:"bytes,2,opt,name=hostname" json:"hostname,omitempty"`
+	Version        string        `protobuf:"bytes,3,opt,name=version" json:"version,omitempty"`
+	Capabilities   []string      `protobuf:"bytes,4,rep,name=capabilities" json:"capabilities,omitempty"`
+	State          WorkerState   `protobuf:"varint,5,opt,name=state,enum=WorkerState" json:"state,omitempty"`
+	JobId          string        `protobuf:"bytes,6,opt,name=job_id" json:"job_id,omitempty"`
+	JobName        string        `protobuf:"bytes,7,opt,name=job_name" json:"job_name,omitempty"`
+	RegisteredAt   int64         `protobuf:"varint,8,opt,name=registered_at" json:"registered_at,omitempty"`
+	LastSeen       int64         `protobuf:"varint,9,opt,name=last_seen" json:"last_seen,omitempty"`
+	JobsCompleted  int32         `protobuf:"varint,10,opt,name=jobs_completed" json:"jobs_completed,omitempty"`
+	JobsFailed     int32         `protobuf:"varint,11,opt,name=jobs_failed" json:"jobs_failed,omitempty"`
+	PendingCommand WorkerCommand `protobuf:"varint,12,opt,name=pending_command,json=pendingCommand,enum=WorkerCommand" json:"pending_command,omitempty"`
+	Slots          []*SlotInfo   `protobuf:"bytes,13,rep,name=slots" json:"slots,omitempty"`
+	Problem        string        `protobuf:"bytes,14,opt,name=problem" json:"problem,omitempty"`
+}
+
+func (m *WorkerInfo) Reset()                    { *m = WorkerInfo{} }
+func (m *WorkerInfo) String() string            { return proto.CompactTextString(m) }
+func (*WorkerInfo) ProtoMessage()               {}
+func (*WorkerInfo) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{0} }
+
+func (m *WorkerInfo) GetSlots() []*SlotInfo {
+	if m != nil {
+		return m.Slots
+	}
+	return nil
+}
+
+type SlotInfo struct {
+	Index   int32       `protobuf:"varint,1,opt,name=index" json:"index,omitempty"`
+	Device  string      `protobuf:"bytes,2,opt,name=device" json:"device,omitempty"`
+	State   WorkerState `protobuf:"varint,3,opt,name=state,enum=WorkerState" json:"state,omitempty"`
+	JobId   string      `protobuf:"bytes,4,opt,name=job_id" json:"job_id,omitempty"`
+	JobName string      `protobuf:"bytes,5,opt,name=job_name" json:"job_name,omitempty"`
+}
+
+func (m *SlotInfo) Reset()                    { *m = SlotInfo{} }
+func (m *SlotInfo) String() string            { return proto.CompactTextString(m) }
+func (*SlotInfo) ProtoMessage()               {}
+func (*SlotInfo) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{1} }
+
+type ListWorkersRequest struct {
+}
+
+func (m *ListWorkersRequest) Reset()                    { *m = ListWorkersRequest{} }
+func (m *ListWorkersRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListWorkersRequest) ProtoMessage()               {}
+func (*ListWorkersRequest) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{2} }
+
+type ListWorkersResponse struct {
+	Workers []*WorkerInfo `protobuf:"bytes,1,rep,name=workers" json:"workers,omitempty"`
+}
+
+func (m *ListWorkersResponse) Reset()                    { *m = ListWorkersResponse{} }
+func (m *ListWorkersResponse) String() string            { return proto.CompactTextString(m) }
+func (*ListWorkersResponse) ProtoMessage()               {}
+func (*ListWorkersResponse) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{3} }
+
+func (m *ListWorkersResponse) GetWorkers() []*WorkerInfo {
+	if m != nil {
+		return m.Workers
+	}
+	return nil
+}
+
+type WorkerCommandRequest struct {
+	WorkerId string        `protobuf:"bytes,1,opt,name=worker_id" json:"worker_id,omitempty"`
+	Command  WorkerCommand `protobuf:"varint,2,opt,name=command,enum=WorkerCommand" json:"command,omitempty"`
+}
+
+func (m *WorkerCommandRequest) Reset()                    { *m = WorkerCommandRequest{} }
+func (m *WorkerCommandRequest) String() string            { return proto.CompactTextString(m) }
+func (*WorkerCommandRequest) ProtoMessage()               {}
+func (*WorkerCommandRequest) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{4} }
+
+type WorkerCommandResponse struct {
+	WorkerIds []string `protobuf:"bytes,1,rep,name=worker_ids" json:"worker_ids,omitempty"`
+}
+
+func (m *WorkerCommandResponse) Reset()                    { *m = WorkerCommandResponse{} }
+func (m *WorkerCommandResponse) String() string            { return proto.CompactTextString(m) }
+func (*WorkerCommandResponse) ProtoMessage()               {}
+func (*WorkerCommandResponse) Descriptor() ([]byte, []int) { return fileDescriptor4, []int{5} }
+
+func init() {
+	proto.RegisterType((*WorkerInfo)(nil), "WorkerInfo")
+	proto.RegisterType((*SlotInfo)(nil), "SlotInfo")
+	proto.RegisterType((*ListWorkersRequest)(nil), "ListWorkersRequest")
+	proto.RegisterType((*ListWorkersResponse)(nil), "ListWorkersResponse")
+	proto.RegisterType((*WorkerCommandRequest)(nil), "WorkerCommandRequest")
+	proto.RegisterType((*WorkerCommandResponse)(nil), "WorkerCommandResponse")
+	proto.RegisterEnum("WorkerState", WorkerState_name, WorkerState_value)
+	proto.RegisterEnum("WorkerCommand", WorkerCommand_name, WorkerCommand_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// Client API for NeuralStyleAdmin service
+
+type NeuralStyleAdminClient interface {
+	ListWorkers(ctx context.Context, in *ListWorkersRequest, opts ...grpc.CallOption) (*ListWorkersResponse, error)
+	SendCommand(ctx context.Context, in *WorkerCommandRequest, opts ...grpc.CallOption) (*WorkerCommandResponse, error)
+}
+
+type neuralStyleAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewNeuralStyleAdminClient(cc *grpc.ClientConn) NeuralStyleAdminClient {
+	return &neuralStyleAdminClient{cc}
+}
+
+func (c *neuralStyleAdminClient) ListWorkers(ctx context.Context, in *ListWorkersRequest, opts ...grpc.CallOption) (*ListWorkersResponse, error) {
+	out := new(ListWorkersResponse)
+	err := grpc.Invoke(ctx, "/NeuralStyleAdmin/ListWorkers", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *neuralStyleAdminClient) SendCommand(ctx context.Context, in *WorkerCommandRequest, opts ...grpc.CallOption) (*WorkerCommandResponse, error) {
+	out := new(WorkerCommandResponse)
+	err := grpc.Invoke(ctx, "/NeuralStyleAdmin/SendCommand", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for NeuralStyleAdmin service
+
+type NeuralStyleAdminServer interface {
+	ListWorkers(context.Context, *ListWorkersRequest) (*ListWorkersResponse, error)
+	SendCommand(context.Context, *WorkerCommandRequest) (*WorkerCommandResponse, error)
+}
+
+func RegisterNeuralStyleAdminServer(s *grpc.Server, srv NeuralStyleAdminServer) {
+	s.RegisterService(&_NeuralStyleAdmin_serviceDesc, srv)
+}
+
+func _NeuralStyleAdmin_ListWorkers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(ListWorkersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(NeuralStyleAdminServer).ListWorkers(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _NeuralStyleAdmin_SendCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(WorkerCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(NeuralStyleAdminServer).SendCommand(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+var _NeuralStyleAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "NeuralStyleAdmin",
+	HandlerType: (*NeuralStyleAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListWorkers",
+			Handler:    _NeuralStyleAdmin_ListWorkers_Handler,
+		},
+		{
+			MethodName: "SendCommand",
+			Handler:    _NeuralStyleAdmin_SendCommand_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+var fileDescriptor4 = []byte{
+	// 696 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x94, 0xcf, 0x4f, 0xdb, 0x48,
+	0x14, 0xc7, 0x71, 0x12, 0xe7, 0xc7, 0x73, 0x12, 0xbc, 0x43, 0x40, 0xb3, 0xac, 0x56, 0x64, 0x8d,
+	0x90, 0x22, 0x0e, 0x3e, 0xb0, 0xd2, 0x6e, 0x0f, 0x5c, 0x02, 0x31, 0x22, 0x22, 0x38, 0xd5, 0x98,
+	0x08, 0x71, 0xa8, 0x22, 0x27, 0x1e, 0xc0, 0xa9, 0x33, 0x13, 0x3c, 0x03, 0x6d, 0xff, 0x85, 0x1e,
+	0x7a, 0xea, 0x5f, 0xd5, 0xbf, 0xaa, 0xf2, 0xd8, 0x0e, 0x49, 0x9b, 0x4a, 0x3d, 0xbe, 0xcf, 0x7b,
+	0x7e, 0xef, 0xfb, 0xe6, 0xfb, 0x64, 0x68, 0x7c, 0xe0, 0xf1, 0x7b, 0x1a, 0x0b, 0x7b, 0x11, 0x73,
+	0xc9, 0xad, 0x6f, 0x45, 0x80, 0x5b, 0x45, 0xfa, 0xec, 0x9e, 0xa3, 0x26, 0x14, 0xc2, 0x00, 0x6b,
+	0x6d, 0xad, 0x53, 0x23, 0x85, 0x30, 0x40, 0xfb, 0x50, 0x7d, 0xe4, 0x42, 0x32, 0x7f, 0x4e, 0x71,
+	0x41, 0xd1, 0x65, 0x8c, 0x30, 0x54, 0x5e, 0x68, 0x2c, 0x42, 0xce, 0x70, 0x51, 0xa5, 0xf2, 0x10,
+	0x59, 0x50, 0x9f, 0xfa, 0x0b, 0x7f, 0x12, 0x46, 0xa1, 0x0c, 0xa9, 0xc0, 0xa5, 0x76, 0xb1, 0x53,
+	0x23, 0x6b, 0x0c, 0x59, 0xa0, 0x0b, 0xe9, 0x4b, 0x8a, 0xf5, 0xb6, 0xd6, 0x69, 0x9e, 0xd4, 0xed,
+	0x54, 0x85, 0x97, 0x30, 0x92, 0xa6, 0xd0, 0x2e, 0x94, 0x67, 0x7c, 0x32, 0x0e, 0x03, 0x5c, 0x56,
+	0x03, 0xf4, 0x19, 0x9f, 0xf4, 0x03, 0xf4, 0x27, 0x54, 0x13, 0xac, 0x44, 0x55, 0xd2, 0xc9, 0x33,
+	0x3e, 0x71, 0x13, 0x4d, 0x87, 0xd0, 0x88, 0xe9, 0x43, 0x28, 0x24, 0x8d, 0x69, 0x30, 0xf6, 0x25,
+	0xae, 0xb6, 0xb5, 0x4e, 0x91, 0xd4, 0x5f, 0x61, 0x57, 0xa2, 0xbf, 0xa0, 0x16, 0xf9, 0x42, 0x8e,
+	0x05, 0xa5, 0x0c, 0xd7, 0x54, 0x41, 0x35, 0x01, 0x1e, 0xa5, 0x0c, 0x1d, 0x41, 0x73, 0xc6, 0x27,
+	0x62, 0x3c, 0xe5, 0xf3, 0x45, 0x44, 0x25, 0x0d, 0x30, 0xb4, 0xb5, 0x8e, 0x4e, 0x1a, 0x09, 0x3d,
+	0xcf, 0x21, 0x3a, 0x00, 0x43, 0x95, 0xdd, 0xfb, 0x61, 0x44, 0x03, 0x6c, 0xa8, 0x1a, 0x48, 0xd0,
+	0x85, 0x22, 0xe8, 0x7f, 0xd8, 0x5e, 0x50, 0x16, 0x84, 0xec, 0x21, 0x69, 0x35, 0xf7, 0x59, 0x80,
+	0xeb, 0x6a, 0xd3, 0x66, 0xb6, 0xe9, 0x79, 0x4a, 0x49, 0x33, 0x2b, 0xcb, 0x62, 0x74, 0x00, 0xba,
+	0x88, 0xb8, 0x14, 0xb8, 0xd1, 0x2e, 0x76, 0x8c, 0x93, 0x9a, 0xed, 0x45, 0x5c, 0x26, 0xe6, 0x90,
+	0x94, 0x27, 0xef, 0xbe, 0x88, 0xf9, 0x24, 0xa2, 0x73, 0xdc, 0x4c, 0xb7, 0xcf, 0x42, 0xeb, 0x8b,
+	0x06, 0xd5, 0xbc, 0x1a, 0xb5, 0x40, 0x0f, 0x59, 0x40, 0x3f, 0x2a, 0x37, 0x75, 0x92, 0x06, 0x68,
+	0x0f, 0xca, 0x01, 0x7d, 0x09, 0xa7, 0xb9, 0x9d, 0x59, 0xf4, 0x6a, 0x47, 0xf1, 0x77, 0xec, 0x28,
+	0xfd, 0xca, 0x0e, 0x7d, 0xcd, 0x0e, 0xab, 0x05, 0x68, 0x10, 0x0a, 0x99, 0xf6, 0x12, 0x84, 0x3e,
+	0x3d, 0x53, 0x21, 0xad, 0x53, 0xd8, 0x59, 0xa3, 0x62, 0xc1, 0x99, 0xa0, 0xe8, 0x08, 0x2a, 0xd9,
+	0x6d, 0x62, 0x4d, 0xad, 0x6e, 0xd8, 0xaf, 0x97, 0x49, 0xf2, 0x9c, 0xf5, 0x0e, 0x5a, 0xeb, 0x0f,
+	0x98, 0x76, 0x4d, 0x5c, 0x4d, 0x4b, 0xc6, 0xcb, 0x0b, 0xae, 0xa6, 0xa0, 0x1f, 0xa0, 0x0e, 0x54,
+	0x72, 0x17, 0x0a, 0x1b, 0x5d, 0xc8, 0xd3, 0xd6, 0x7f, 0xb0, 0xfb, 0x43, 0xfb, 0x4c, 0xde, 0xdf,
+	0x00, 0xcb, 0xfe, 0xa9, 0xc2, 0x1a, 0xa9, 0xe5, 0x03, 0xc4, 0xf1, 0x57, 0x0d, 0x8c, 0x95, 0x37,
+	0x43, 0x08, 0x9a, 0xb7, 0x43, 0x72, 0xe5, 0x90, 0xf1, 0xc8, 0xbd, 0x72, 0x87, 0xb7, 0xae, 0xb9,
+	0x85, 0xb6, 0xc1, 0xc8, 0x58, 0xbf, 0x37, 0x70, 0x4c, 0x6d, 0x05, 0x9c, 0x8d, 0xbc, 0x3b, 0xb3,
+	0xb0, 0xf2, 0xd5, 0xf0, 0xe2, 0x62, 0xd0, 0x77, 0x1d, 0xb3, 0x88, 0xfe, 0x80, 0x46, 0xc6, 0xde,
+	0x76, 0x47, 0x9e, 0xd3, 0x33, 0x4b, 0x2b, 0x65, 0x3d, 0xd2, 0xed, 0xbb, 0x4e, 0xcf, 0xd4, 0x51,
+	0x0b, 0xcc, 0xe5, 0xc0, 0x4b, 0xa7, 0x3b, 0xb8, 0xb9, 0xbc, 0x33, 0xcb, 0xc7, 0x4f, 0xd0, 0x58,
+	0x5b, 0x07, 0x99, 0x50, 0x3f, 0x1f, 0x5e, 0x5f, 0x77, 0xdd, 0xde, 0xd8, 0x1d, 0xba, 0x8e, 0xb9,
+	0x95, 0xf4, 0xcf, 0x89, 0xea, 0x66, 0x6a, 0xab, 0x48, 0xcd, 0x4c, 0x95, 0xe5, 0x88, 0x38, 0xde,
+	0xe8, 0x3a, 0x51, 0xd6, 0x02, 0x33, 0x67, 0xde, 0xe5, 0xe8, 0xa6, 0x97, 0x6c, 0x59, 0x3a, 0xf9,
+	0xac, 0x81, 0xe9, 0xd2, 0xe7, 0xd8, 0x8f, 0x3c, 0xf9, 0x29, 0xa2, 0xdd, 0x60, 0x1e, 0x32, 0xf4,
+	0x06, 0x8c, 0x15, 0xcf, 0xd1, 0x8e, 0xfd, 0xf3, 0x5d, 0xec, 0xb7, 0xec, 0x4d, 0x67, 0x71, 0x0a,
+	0x86, 0x47, 0x59, 0x90, 0xeb, 0xdf, 0xb5, 0x37, 0xb9, 0xbf, 0xbf, 0x67, 0x6f, 0x74, 0xed, 0xec,
+	0x10, 0xfe, 0x61, 0x54, 0xda, 0xf7, 0xb1, 0xcf, 0xa6, 0x8f, 0xcf, 0x36, 0x53, 0xba, 0x44, 0xa2,
+	0xcb, 0x8f, 0xe5, 0x22, 0xe6, 0x33, 0x3a, 0x95, 0x93, 0xb2, 0xfa, 0x17, 0xfe, 0xfb, 0x7d, 0x00,
+	0x36, 0x0f, 0x37, 0xaf, 0x1c, 0x05, 0x00, 0x00,
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+)
+
+// maxFrames bounds the frames of an animated job, each one is a render of
+// its own
+const maxFrames = 100
+
+// Animation is an animated GIF rendered a frame at a time
+type Animation struct {
+	Frames    []checkedImage
+	Delays    []int
+	LoopCount int
+	Chained   bool
+}
+
+// decodeFrames splits an animated GIF into whole frames. GIF frames only
+// hold what changed, so each one is drawn over what the previous ones left.
+func decodeFrames(b []byte) ([]image.Image, *gif.GIF, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(g.Image) > maxFrames {
+		return nil, nil, fmt.Errorf("The animation has %d frames, at most %d are allowed", len(g.Image), maxFrames)
+	}
+
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+
+	canvas := image.NewRGBA(bounds)
+	frames := make([]image.Image, 0, len(g.Image))
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		frames = append(frames, cloneRGBA(canvas))
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, g, nil
+}
+
+// encode puts the rendered frames back together with the timing of the
+// original animation
+func (a *Animation) encode(frames [][]byte) ([]byte, error) {
+	g := &gif.GIF{LoopCount: a.LoopCount}
+	for i, b := range frames {
+		img, _, err := image.Decode(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("Frame %d: %v", i, err)
+		}
+
+		bounds := img.Bounds()
+		p := image.NewPaletted(bounds, palette.Plan9)
+		draw.FloydSteinberg.Draw(p, bounds, img, bounds.Min)
+
+		g.Image = append(g.Image, p)
+		g.Delay = append(g.Delay, a.Delays[i])
+	}
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, g)
+	return buf.Bytes(), err
+}
+
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	c := image.NewRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+	return c
+}
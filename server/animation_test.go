@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	white = color.RGBA{255, 255, 255, 255}
+
+	framePalette = color.Palette{color.RGBA{}, red, blue, green, white}
+)
+
+// gifFrame is a frame of a 4×1 animation covering x0 to x1, in the given
+// colours
+func gifFrame(x0, x1 int, colours ...color.Color) *image.Paletted {
+	p := image.NewPaletted(image.Rect(x0, 0, x1, 1), framePalette)
+	for i, c := range colours {
+		p.Set(x0+i, 0, c)
+	}
+	return p
+}
+
+func encodeGIF(t *testing.T, g *gif.GIF) []byte {
+	var b bytes.Buffer
+	if err := gif.EncodeAll(&b, g); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestDecodeFrames(t *testing.T) {
+	transparent := color.RGBA{}
+	g := &gif.GIF{
+		Image: []*image.Paletted{
+			gifFrame(0, 4, red, red, red, red),
+			gifFrame(1, 2, blue),
+			gifFrame(2, 3, green),
+			gifFrame(0, 4, transparent, transparent, transparent, white),
+		},
+		Delay:    []int{10, 20, 30, 40},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone},
+		Config:   image.Config{Width: 4, Height: 1, ColorModel: framePalette},
+	}
+
+	//The second frame is cleared after showing, the third one is undone
+	want := [][]color.RGBA{
+		{red, red, red, red},
+		{red, blue, red, red},
+		{red, transparent, green, red},
+		{red, transparent, red, white},
+	}
+
+	frames, decoded, err := decodeFrames(encodeGIF(t, g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != len(want) || len(decoded.Delay) != len(want) {
+		t.Fatalf("got %d frames and %d delays, want %d", len(frames), len(decoded.Delay), len(want))
+	}
+	for i, frame := range frames {
+		if b := frame.Bounds(); b.Dx() != 4 || b.Dy() != 1 {
+			t.Errorf("frame %d is %v, want the whole canvas", i, b)
+			continue
+		}
+		for x, c := range want[i] {
+			if got := color.RGBAModel.Convert(frame.At(x, 0)); got != c {
+				t.Errorf("frame %d, pixel %d is %v, want %v", i, x, got, c)
+			}
+		}
+	}
+}
+
+func TestDecodeFramesErrors(t *testing.T) {
+	if _, _, err := decodeFrames([]byte("GIF89a")); err == nil {
+		t.Errorf("decoded a truncated animation")
+	}
+
+	g := &gif.GIF{Config: image.Config{Width: 4, Height: 1, ColorModel: framePalette}}
+	for i := 0; i <= maxFrames; i++ {
+		g.Image = append(g.Image, gifFrame(0, 4, red))
+		g.Delay = append(g.Delay, 0)
+	}
+	if _, _, err := decodeFrames(encodeGIF(t, g)); err == nil {
+		t.Errorf("decoded %d frames", len(g.Image))
+	}
+}
+
+func TestAnimationEncode(t *testing.T) {
+	a := &Animation{Delays: []int{10, 50}, LoopCount: 3}
+
+	b, err := a.encode([][]byte{uniformPNG(t, 8, 4, red), uniformPNG(t, 8, 4, blue)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 2 || g.Delay[0] != 10 || g.Delay[1] != 50 || g.LoopCount != 3 {
+		t.Errorf("got %d frames with delays %v looping %d times", len(g.Image), g.Delay, g.LoopCount)
+	}
+	if c := color.RGBAModel.Convert(g.Image[1].At(0, 0)); c != blue {
+		t.Errorf("second frame is %v, want blue", c)
+	}
+
+	if _, err := a.encode([][]byte{uniformPNG(t, 8, 4, red), nil}); err == nil {
+		t.Errorf("encoded a missing frame")
+	}
+}
+
+// createAnimated submits a job for a three frame animation
+func createAnimated(t *testing.T, s *memoryServer, params *pb.JobParameters) error {
+	g := &gif.GIF{
+		Delay:     []int{10, 20, 30},
+		LoopCount: 0,
+		Config:    image.Config{Width: 4, Height: 1, ColorModel: framePalette},
+	}
+	for _, c := range []color.Color{red, blue, green} {
+		g.Image = append(g.Image, gifFrame(0, 4, c, c, c, c))
+	}
+
+	_, err := s.CreateFullJob(context.Background(), &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 8, 8, white)},
+		Content: &pb.InputImage{Title: "cat", Image: encodeGIF(t, g)},
+		Params:  params,
+	})
+	return err
+}
+
+// renderFrame takes a frame and completes it with an image of its size
+func renderFrame(t *testing.T, s *memoryServer) *pb.Job {
+	ctx := context.Background()
+	job, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1,
+		Image: uniformPNG(t, 4, 1, white)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestAnimatedJob(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	if err := createAnimated(t, s, &pb.JobParameters{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.ParentJobs) != 1 || len(s.PendingJobs) != 3 {
+		t.Fatalf("got %d parent and %d pending jobs, want a job per frame", len(s.ParentJobs), len(s.PendingJobs))
+	}
+	for i := 0; i < 3; i++ {
+		job := renderFrame(t, s)
+		if job.Init != nil {
+			t.Errorf("frame %q started from an image", job.Name)
+		}
+	}
+
+	var parent *Job
+	for i := 0; i < 100 && parent == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		s.lock.RLock()
+		for k, v := range s.CompletedJobs {
+			if k.Name == "cat" {
+				parent = v
+			}
+		}
+		s.lock.RUnlock()
+	}
+	if parent == nil {
+		t.Fatalf("animated job not completed")
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(parent.Result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 3 || g.Delay[2] != 30 {
+		t.Errorf("got %d frames with delays %v", len(g.Image), g.Delay)
+	}
+}
+
+func TestChainedAnimation(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	err := createAnimated(t, s, &pb.JobParameters{Animation: &pb.Animation{ChainFrames: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Each frame starts from the result of the one before
+	var previous *pb.Job
+	for i := 0; i < 3; i++ {
+		if len(s.PendingJobs) != 1 {
+			t.Fatalf("frame %d: %d jobs pending, want one frame at a time", i, len(s.PendingJobs))
+		}
+		job := renderFrame(t, s)
+		if (previous == nil) != (job.Init == nil) {
+			t.Errorf("frame %d started from %v", i, job.Init)
+		}
+		previous = job
+	}
+}
+
+func TestAnimatedJobLimits(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, params := range []*pb.JobParameters{
+		{Tiling: &pb.Tiling{TileSize: 64}},
+		{Stages: []*pb.Stage{{ImageSize: 64}}},
+	} {
+		if err := createAnimated(t, s, params); grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: got %v, want InvalidArgument", params, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// NewAPIHandler serves the UIServer as a JSON API under /api/
+func NewAPIHandler(s UIServer) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := s.GetAllJobs(context.Background())
+		writeJSON(w, resp, err)
+	})
+
+	mux.HandleFunc("/api/workers", func(w http.ResponseWriter, r *http.Request) {
+		resp, err := s.GetAllWorkers(context.Background())
+		writeJSON(w, resp, err)
+	})
+
+	mux.HandleFunc("/api/losses/", func(w http.ResponseWriter, r *http.Request) {
+		name, id, ok := jobFromPath(r.URL.Path, "/api/losses/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		resp, err := s.GetLosses(context.Background(), id, name)
+		writeJSON(w, resp, err)
+	})
+
+	mux.HandleFunc("/api/logs/", func(w http.ResponseWriter, r *http.Request) {
+		name, id, ok := jobFromPath(r.URL.Path, "/api/logs/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		resp, err := s.GetLogs(context.Background(), id, name)
+		writeJSON(w, resp, err)
+	})
+
+	images := map[string]func(context.Context, string, string) (*ImageResponse, error){
+		"/api/style/":   s.GetStyleImage,
+		"/api/content/": s.GetContentImage,
+		"/api/result/":  s.GetResultImage,
+		"/api/raw/":     s.GetRawResultImage,
+	}
+	for prefix, get := range images {
+		prefix, get := prefix, get
+		mux.HandleFunc(prefix, func(w http.ResponseWriter, r *http.Request) {
+			name, id, ok := jobFromPath(r.URL.Path, prefix)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			resp, err := get(context.Background(), id, name)
+			writeImage(w, resp, err)
+		})
+	}
+
+	mux.HandleFunc("/api/timelapse/", func(w http.ResponseWriter, r *http.Request) {
+		name, id, ok := jobFromPath(r.URL.Path, "/api/timelapse/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		resp, err := s.GetTimelapse(context.Background(), id, name)
+		if err == nil {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"_timelapse"+formatExtension(resp.Format)))
+		}
+		writeImage(w, resp, err)
+	})
+
+	mux.HandleFunc("/api/progress/", func(w http.ResponseWriter, r *http.Request) {
+		//Paths look like /api/progress/name/id/index
+		p := strings.TrimPrefix(r.URL.Path, "/api/progress/")
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			http.NotFound(w, r)
+			return
+		}
+		index, err := strconv.Atoi(p[i+1:])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		name, id, ok := jobFromPath(p[:i], "")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		resp, err := s.GetProgressImage(context.Background(), id, name, index)
+		writeImage(w, resp, err)
+	})
+
+	return mux
+}
+
+// jobFromPath extracts the job name and ID from paths like prefix/name/id
+func jobFromPath(p string, prefix string) (string, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(p, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
+
+// writeImage serves an image with the Content-Type of its format
+func writeImage(w http.ResponseWriter, img *ImageResponse, err error) {
+	if err != nil {
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", formatContentType(img.Format))
+	if _, err := w.Write(img.Image); err != nil {
+		log.Println(err)
+	}
+}
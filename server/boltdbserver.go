@@ -71,6 +71,10 @@ func (s *boltDbServer) CreateJob(ctx context.Context, in *pb.CreateJobRequest) (
 	return &pb.CreateJobResponse{}, err
 }
 
+func (s *boltDbServer) UploadJob(stream pb.NeuralStyleImager_UploadJobServer) error {
+	return fmt.Errorf("Not implemented")
+}
+
 func (s *boltDbServer) RequestJob(ctx context.Context, in *pb.JobRequest) (*pb.Job, error) {
 	return nil, fmt.Errorf("Not implemented")
 }
@@ -91,22 +95,74 @@ func (s *boltDbServer) FailJob(ctx context.Context, in *pb.JobFail) (*pb.JobFail
 	return nil, fmt.Errorf("Not implemented")
 }
 
+func (s *boltDbServer) RegisterWorker(ctx context.Context, in *pb.WorkerRegistration) (*pb.WorkerRegistrationResponse, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) Heartbeat(ctx context.Context, in *pb.WorkerHeartbeat) (*pb.HeartbeatResponse, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) ListWorkers(ctx context.Context, in *pb.ListWorkersRequest) (*pb.ListWorkersResponse, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) SendCommand(ctx context.Context, in *pb.WorkerCommandRequest) (*pb.WorkerCommandResponse, error) {
+	return nil, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) GetAllWorkers(ctx context.Context) (*AllWorkersResponse, error) {
+	return &AllWorkersResponse{}, fmt.Errorf("Not implemented")
+}
+
 func (s *boltDbServer) GetAllJobs(ctx context.Context) (*AllJobsResponse, error) {
 	return &AllJobsResponse{}, fmt.Errorf("Not implemented")
 }
 
-func (s *boltDbServer) GetStyleImage(ctx context.Context, jobId string, name string) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+func (s *boltDbServer) GetStyleImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	return &ImageResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) GetContentImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	return &ImageResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) GetResultImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	return &ImageResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) GetRawResultImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	return &ImageResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) GetProgressImage(ctx context.Context, jobId string, name string, index int) (*ImageResponse, error) {
+	return &ImageResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) GetTimelapse(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	return &ImageResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) GetLosses(ctx context.Context, jobId string, name string) (*LossesResponse, error) {
+	return &LossesResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) UploadLog(ctx context.Context, in *pb.JobLogUpload) (*pb.JobLogUploadResponse, error) {
+	return &pb.JobLogUploadResponse{}, fmt.Errorf("Not implemented")
+}
+
+func (s *boltDbServer) UploadResult(stream pb.NeuralStyleWorker_UploadResultServer) error {
+	return fmt.Errorf("Not implemented")
 }
 
-func (s *boltDbServer) GetContentImage(ctx context.Context, jobId string, name string) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+func (s *boltDbServer) GetJobLog(ctx context.Context, in *pb.JobLogRequest) (*pb.JobLogResponse, error) {
+	return &pb.JobLogResponse{}, fmt.Errorf("Not implemented")
 }
 
-func (s *boltDbServer) GetResultImage(ctx context.Context, jobId string, name string) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+func (s *boltDbServer) PostProcessJob(ctx context.Context, in *pb.PostProcessRequest) (*pb.PostProcessResponse, error) {
+	return &pb.PostProcessResponse{}, fmt.Errorf("Not implemented")
 }
 
-func (s *boltDbServer) GetProgressImage(ctx context.Context, jobId string, name string, index int) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+func (s *boltDbServer) GetLogs(ctx context.Context, jobId string, name string) (*LogsResponse, error) {
+	return &LogsResponse{}, fmt.Errorf("Not implemented")
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/mgilbir/neural-style-art-project/pb"
 	"github.com/mgilbir/neural-style-art-project/server"
+	"github.com/rakyll/statik/fs"
 	"google.golang.org/grpc"
 )
 
@@ -19,16 +20,19 @@ var (
 	httpConnStr  = flag.String("http", ":9081", "The HTTP connection string")
 	grpcConnStr  = flag.String("grpc", ":8081", "The gRPC connection string")
 	stylesConfig = flag.String("styles", "styles.json", "The file with the styles")
+	normalize    = flag.String("normalize", "orient,srgb,resize", "Comma separated steps applied to submitted images: orient, srgb, resize. Empty to keep them as submitted")
+	imageSize    = flag.Int("imagesize", server.DefaultImageSize, "The output size of jobs that don't set one. Submitted images are scaled down to it")
+	maxMsgSize   = flag.Int("msgsize", 64*1024*1024, "The largest gRPC message accepted, in bytes. Larger images must be streamed")
 )
 
 func main() {
 	flag.Parse()
 	var err error
 
-	// httpfs, err := fs.New()
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
+	httpfs, err := fs.New()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	err = os.MkdirAll(*outputDir, 0700)
 	if err != nil {
@@ -37,7 +41,15 @@ func main() {
 
 	var s server.Server
 
-	s, err = server.NewMemoryServer(*outputDir)
+	steps, err := server.ParseNormalizeSteps(*normalize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s, err = server.NewMemoryServer(*outputDir, server.Normalizer{
+		Steps:     steps,
+		ImageSize: *imageSize,
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -70,6 +82,8 @@ func main() {
 	errC := make(chan error)
 
 	http.Handle("/", http.FileServer(http.Dir(*outputDir)))
+	http.Handle("/dashboard/", http.StripPrefix("/dashboard/", http.FileServer(httpfs)))
+	http.Handle("/api/", server.NewAPIHandler(s))
 	go func(errC chan error) {
 		if err := http.ListenAndServe(*httpConnStr, nil); err != nil {
 			errC <- err
@@ -91,9 +105,10 @@ func main() {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	gs := grpc.NewServer()
+	gs := grpc.NewServer(grpc.MaxMsgSize(*maxMsgSize))
 	pb.RegisterNeuralStyleImagerServer(gs, s)
 	pb.RegisterNeuralStyleWorkerServer(gs, s)
+	pb.RegisterNeuralStyleAdminServer(gs, s)
 
 	go func(errC chan error) {
 		errC <- gs.Serve(lis)
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+// composite blends a result with the content of its job through a greyscale
+// mask, white areas showing the result and black ones the content. The
+// content and the mask are scaled to the size of the result, and the edges
+// of the mask are softened so the result fades into the content. The blend
+// is encoded as PNG.
+func composite(result []byte, content []byte, mask []byte, pp *pb.PostProcess) ([]byte, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(result))
+	if err != nil {
+		return nil, fmt.Errorf("Result: %v", err)
+	}
+	img := cloneRGBA(toRGBA(decoded))
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	decoded, _, err = image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("Content: %v", err)
+	}
+	original := toRGBA(scaleTo(decoded, w, h))
+
+	decoded, _, err = image.Decode(bytes.NewReader(mask))
+	if err != nil {
+		return nil, fmt.Errorf("Mask: %v", err)
+	}
+	scaled := toRGBA(scaleTo(decoded, w, h))
+
+	alpha := make([]float64, w*h)
+	for i := range alpha {
+		y, _, _ := color.RGBToYCbCr(scaled.Pix[i*4], scaled.Pix[i*4+1], scaled.Pix[i*4+2])
+		alpha[i] = float64(y) / 255
+		if pp != nil && pp.InvertMask {
+			alpha[i] = 1 - alpha[i]
+		}
+	}
+
+	feather := 0
+	if pp != nil {
+		feather = int(pp.MaskFeather)
+	}
+	if feather <= 0 {
+		feather = maxInt(w, h) / 100
+	}
+	featherMask(alpha, w, h, feather)
+
+	for i, a := range alpha {
+		for c := 0; c < 4; c++ {
+			j := i*4 + c
+			img.Pix[j] = uint8(float64(img.Pix[j])*a + float64(original.Pix[j])*(1-a) + 0.5)
+		}
+	}
+
+	return encodeImage(img, pb.ImageFormat_PNG)
+}
+
+// featherMask softens the edges of a w×h mask so they fade over about
+// feather pixels either side. Two box blurs are close enough to a gaussian.
+func featherMask(alpha []float64, w, h, feather int) {
+	radius := feather / 2
+	if radius < 1 {
+		return
+	}
+
+	line := make([]float64, maxInt(w, h))
+	for pass := 0; pass < 2; pass++ {
+		for y := 0; y < h; y++ {
+			boxBlur(alpha[y*w:], line[:w], 1, radius)
+		}
+		for x := 0; x < w; x++ {
+			boxBlur(alpha[x:], line[:h], w, radius)
+		}
+	}
+}
+
+// boxBlur averages each of len(line) values, stride apart in data, with
+// those within radius of it. Values past the ends repeat the last one.
+func boxBlur(data []float64, line []float64, stride int, radius int) {
+	n := len(line)
+	for i := range line {
+		line[i] = data[i*stride]
+	}
+
+	at := func(i int) float64 {
+		if i < 0 {
+			return line[0]
+		}
+		if i >= n {
+			return line[n-1]
+		}
+		return line[i]
+	}
+
+	sum := 0.0
+	for i := -radius; i <= radius; i++ {
+		sum += at(i)
+	}
+	size := float64(2*radius + 1)
+	for i := 0; i < n; i++ {
+		data[i*stride] = sum / size
+		sum += at(i+radius+1) - at(i-radius)
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
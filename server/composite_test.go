@@ -0,0 +1,229 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestBoxBlur(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []float64
+		stride int
+		radius int
+		want   []float64
+	}{
+		{"flat", []float64{1, 1, 1, 1}, 1, 1, []float64{1, 1, 1, 1}},
+		{"step", []float64{0, 0, 3, 3}, 1, 1, []float64{0, 1, 2, 3}},
+		{"spike", []float64{0, 0, 3, 0, 0}, 1, 1, []float64{0, 1, 1, 1, 0}},
+		{"edges repeat", []float64{3, 0, 0}, 1, 2, []float64{1.8, 1.2, 0.6}},
+		//Every other value, as a column of a two column image
+		{"strided", []float64{0, 9, 0, 9, 3, 9, 3, 9}, 2, 1, []float64{0, 9, 1, 9, 2, 9, 3, 9}},
+	}
+
+	for _, tt := range tests {
+		n := (len(tt.data) + tt.stride - 1) / tt.stride
+		boxBlur(tt.data, make([]float64, n), tt.stride, tt.radius)
+		for i := range tt.data {
+			if math.Abs(tt.data[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: got %v, want %v", tt.name, tt.data, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFeatherMask(t *testing.T) {
+	//A 20×1 mask, black on the left half and white on the right one
+	hard := func() []float64 {
+		alpha := make([]float64, 20)
+		for i := 10; i < 20; i++ {
+			alpha[i] = 1
+		}
+		return alpha
+	}
+
+	tests := []struct {
+		name    string
+		feather int
+		//Whether the edge gets softened at all
+		soft bool
+	}{
+		{"none", 0, false},
+		{"too narrow", 1, false},
+		{"narrow", 2, true},
+		{"wide", 8, true},
+	}
+
+	for _, tt := range tests {
+		alpha := hard()
+		featherMask(alpha, 20, 1, tt.feather)
+
+		if !tt.soft {
+			for i, a := range hard() {
+				if alpha[i] != a {
+					t.Errorf("%s: got %v, want it unchanged", tt.name, alpha)
+					break
+				}
+			}
+			continue
+		}
+
+		for i := 1; i < len(alpha); i++ {
+			if alpha[i] < alpha[i-1]-1e-9 {
+				t.Errorf("%s: not increasing across the edge: %v", tt.name, alpha)
+				break
+			}
+		}
+		if alpha[9] <= 0 || alpha[10] >= 1 {
+			t.Errorf("%s: edge not softened: %v", tt.name, alpha)
+		}
+		if math.Abs(alpha[0]) > 1e-9 || math.Abs(alpha[19]-1) > 1e-9 {
+			t.Errorf("%s: softened further than the feather: %v", tt.name, alpha)
+		}
+		if math.Abs(alpha[9]+alpha[10]-1) > 1e-9 {
+			t.Errorf("%s: edge moved: %v", tt.name, alpha)
+		}
+	}
+}
+
+// maskPNG encodes a w×h mask, white on the right half
+func maskPNG(t *testing.T, w, h int) []byte {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := w / 2; x < w; x++ {
+			img.SetGray(x, y, color.Gray{255})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestComposite(t *testing.T) {
+	red := color.RGBA{200, 0, 0, 255}
+	blue := color.RGBA{0, 0, 200, 255}
+	result := uniformPNG(t, 200, 20, red)
+	content := uniformPNG(t, 400, 40, blue)
+
+	tests := []struct {
+		name string
+		mask []byte
+		pp   *pb.PostProcess
+		//Expected colour at x, y = 10
+		want map[int]color.RGBA
+	}{
+		{"hard edge", maskPNG(t, 200, 20), &pb.PostProcess{MaskFeather: 1}, map[int]color.RGBA{0: blue, 99: blue, 100: red, 199: red}},
+		{"inverted", maskPNG(t, 200, 20), &pb.PostProcess{MaskFeather: 1, InvertMask: true}, map[int]color.RGBA{0: red, 99: red, 100: blue, 199: blue}},
+		{"scaled mask", maskPNG(t, 50, 5), &pb.PostProcess{MaskFeather: 1}, map[int]color.RGBA{0: blue, 99: blue, 100: red, 199: red}},
+		{"default feather", maskPNG(t, 200, 20), nil, map[int]color.RGBA{0: blue, 199: red}},
+	}
+
+	for _, tt := range tests {
+		b, err := composite(result, content, tt.mask, tt.pp)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		decoded, err := png.Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		img := toRGBA(decoded)
+		if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 20 {
+			t.Errorf("%s: got size %v, want that of the result", tt.name, b)
+			continue
+		}
+		for x, want := range tt.want {
+			if got := img.RGBAAt(x, 10); got != want {
+				t.Errorf("%s: pixel %d is %v, want %v", tt.name, x, got, want)
+			}
+		}
+	}
+
+	//A wider feather blends across the edge
+	b, err := composite(result, content, maskPNG(t, 200, 20), &pb.PostProcess{MaskFeather: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := toRGBA(decoded).RGBAAt(100, 10); c.R == 0 || c.B == 0 {
+		t.Errorf("edge is %v, want a mix of both", c)
+	}
+
+	if _, err := composite(result, content, []byte("not an image"), nil); err == nil {
+		t.Errorf("composited with an unreadable mask")
+	}
+}
+
+func TestMaskedJob(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	red := color.RGBA{200, 0, 0, 255}
+	blue := color.RGBA{0, 0, 200, 255}
+	_, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 40, 20, red)},
+		Content: &pb.InputImage{Title: "cat", Image: uniformPNG(t, 40, 20, blue)},
+		Mask:    &pb.InputImage{Title: "cat", Image: maskPNG(t, 40, 20)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1, Image: uniformPNG(t, 40, 20, red)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Re-run it with a hard edge so the halves can be told apart
+	_, err = s.PostProcessJob(ctx, &pb.PostProcessRequest{Id: job.Id, Name: job.Name, PostProcess: &pb.PostProcess{MaskFeather: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := s.GetResultImage(ctx, job.Id, job.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(img.Image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := toRGBA(decoded)
+	if got := result.RGBAAt(0, 10); got != blue {
+		t.Errorf("unmasked side is %v, want the content", got)
+	}
+	if got := result.RGBAAt(39, 10); got != red {
+		t.Errorf("masked side is %v, want the result", got)
+	}
+
+	_, err = s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "dog",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 40, 20, red)},
+		Content: &pb.InputImage{Title: "dog", Image: uniformPNG(t, 40, 20, blue)},
+		Mask:    &pb.InputImage{Title: "dog", Image: []byte("not an image")},
+	})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("job with an unreadable mask: got %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	exifOrientationTag = 0x0112
+	exifIFDTag         = 0x8769
+	exifColorSpaceTag  = 0xa001
+	exifInteropTag     = 0xa005
+	interopIndexTag    = 0x0001
+	tiffICCProfileTag  = 0x8773
+)
+
+// EXIF colour spaces. Uncalibrated images are Adobe RGB when their
+// interoperability index is R03.
+const (
+	exifSRGB         = 1
+	exifUncalibrated = 0xffff
+)
+
+// exifOrientation reads the EXIF orientation of a JPEG, from 1 to 8. Images
+// without one, or that aren't JPEGs, are upright and get 1.
+func exifOrientation(b []byte) int {
+	return tiffOrientation(exifData(b))
+}
+
+// exifColorSpace reads the EXIF colour space of a JPEG and its
+// interoperability index, such as R98 or R03. Images without EXIF data get 0.
+func exifColorSpace(b []byte) (int, string) {
+	t, ok := newTIFFReader(exifData(b))
+	if !ok {
+		return 0, ""
+	}
+
+	exif, ok := t.offset(t.first(), exifIFDTag)
+	if !ok {
+		return 0, ""
+	}
+	space, ok := t.short(exif, exifColorSpaceTag)
+	if !ok {
+		return 0, ""
+	}
+
+	interop := ""
+	if ifd, ok := t.offset(exif, exifInteropTag); ok {
+		if v, ok := t.value(ifd, interopIndexTag); ok {
+			interop = string(bytes.TrimRight(v, "\x00"))
+		}
+	}
+	return space, interop
+}
+
+// exifData finds the TIFF structure EXIF data is stored in in a JPEG
+func exifData(b []byte) []byte {
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 {
+		return nil
+	}
+
+	//Walk the segments up to the image data looking for APP1
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xff {
+			return nil
+		}
+		marker := b[i+1]
+		if marker == 0xda || marker == 0xd9 {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(b[i+2:]))
+		if size < 2 || i+2+size > len(b) {
+			return nil
+		}
+		segment := b[i+4 : i+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		i += 2 + size
+	}
+	return nil
+}
+
+// tiffOrientation looks for the orientation tag in the first IFD of a TIFF
+// structure
+func tiffOrientation(b []byte) int {
+	t, ok := newTIFFReader(b)
+	if !ok {
+		return 1
+	}
+
+	o, ok := t.short(t.first(), exifOrientationTag)
+	if !ok || o < 1 || o > 8 {
+		return 1
+	}
+	return o
+}
+
+// tiffReader reads tags from the IFDs of a TIFF structure, as used by TIFF
+// images and EXIF data
+type tiffReader struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+func newTIFFReader(b []byte) (*tiffReader, bool) {
+	if len(b) < 8 {
+		return nil, false
+	}
+
+	switch string(b[:2]) {
+	case "II":
+		return &tiffReader{b: b, order: binary.LittleEndian}, true
+	case "MM":
+		return &tiffReader{b: b, order: binary.BigEndian}, true
+	}
+	return nil, false
+}
+
+// first is the offset of the first IFD
+func (t *tiffReader) first() int {
+	return int(t.order.Uint32(t.b[4:]))
+}
+
+// entry finds a tag in the IFD at offset ifd and returns its type, the
+// number of values and where they are stored
+func (t *tiffReader) entry(ifd int, tag uint16) (uint16, int, int, bool) {
+	if ifd < 8 || ifd+2 > len(t.b) {
+		return 0, 0, 0, false
+	}
+
+	entries := int(t.order.Uint16(t.b[ifd:]))
+	for n := 0; n < entries; n++ {
+		e := ifd + 2 + n*12
+		if e+12 > len(t.b) {
+			return 0, 0, 0, false
+		}
+		if t.order.Uint16(t.b[e:]) != tag {
+			continue
+		}
+
+		typ := t.order.Uint16(t.b[e+2:])
+		count := int(t.order.Uint32(t.b[e+4:]))
+
+		//Values that fit in 4 bytes are stored in the entry itself
+		at := e + 8
+		if count*tiffTypeSize(typ) > 4 {
+			at = int(t.order.Uint32(t.b[e+8:]))
+		}
+		return typ, count, at, true
+	}
+	return 0, 0, 0, false
+}
+
+// value returns the bytes of the values of a tag
+func (t *tiffReader) value(ifd int, tag uint16) ([]byte, bool) {
+	typ, count, at, ok := t.entry(ifd, tag)
+	if !ok {
+		return nil, false
+	}
+	size := count * tiffTypeSize(typ)
+	if count < 0 || at < 0 || size < 0 || at+size > len(t.b) {
+		return nil, false
+	}
+	return t.b[at : at+size], true
+}
+
+// short returns the first value of a SHORT tag
+func (t *tiffReader) short(ifd int, tag uint16) (int, bool) {
+	typ, count, at, ok := t.entry(ifd, tag)
+	if !ok || typ != 3 || count < 1 || at+2 > len(t.b) {
+		return 0, false
+	}
+	return int(t.order.Uint16(t.b[at:])), true
+}
+
+// offset returns the offset stored in a LONG tag, such as those pointing to
+// other IFDs
+func (t *tiffReader) offset(ifd int, tag uint16) (int, bool) {
+	typ, count, at, ok := t.entry(ifd, tag)
+	if !ok || (typ != 4 && typ != 13) || count < 1 || at+4 > len(t.b) {
+		return 0, false
+	}
+	return int(t.order.Uint32(t.b[at:])), true
+}
+
+// tiffTypeSize is the size in bytes of one value of a TIFF type
+func tiffTypeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11, 13:
+		return 4
+	case 5, 10, 12:
+		return 8
+	}
+	return 1
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// exifJPEG builds the start of a JPEG with an APP1 segment holding EXIF data
+// with the given orientation, preceded by other segments
+func exifJPEG(order binary.ByteOrder, orientation uint16, before ...[]byte) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	b := []byte{0xff, 0xd8}
+	for _, segment := range before {
+		b = append(b, segment...)
+	}
+	return append(append(b, segmentHeader(0xe1, 6+len(tiff))...), append([]byte("Exif\x00\x00"), tiff...)...)
+}
+
+// segmentHeader starts a JPEG segment with size bytes of data
+func segmentHeader(marker byte, size int) []byte {
+	b := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], uint16(size+2))
+	return b
+}
+
+func TestExifOrientation(t *testing.T) {
+	app0 := append(segmentHeader(0xe0, 5), []byte("JFIF\x00")...)
+	truncated := exifJPEG(binary.BigEndian, 6)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"empty", nil, 1},
+		{"not a JPEG", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"no EXIF", append([]byte{0xff, 0xd8}, append(app0, 0xff, 0xda)...), 1},
+		{"little endian", exifJPEG(binary.LittleEndian, 6), 6},
+		{"big endian", exifJPEG(binary.BigEndian, 8), 8},
+		{"after other segments", exifJPEG(binary.BigEndian, 3, app0), 3},
+		{"upright", exifJPEG(binary.LittleEndian, 1), 1},
+		{"out of range", exifJPEG(binary.LittleEndian, 9), 1},
+		{"truncated", truncated[:len(truncated)-8], 1},
+		{"image data first", append([]byte{0xff, 0xd8, 0xff, 0xda}, exifJPEG(binary.BigEndian, 6)[2:]...), 1},
+	}
+
+	for _, tt := range tests {
+		if got := exifOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// colorSpaceJPEG builds the start of a JPEG with EXIF data holding a colour
+// space and, unless empty, an interoperability index
+func colorSpaceJPEG(order binary.ByteOrder, space uint16, interop string) []byte {
+	entries := 1
+	if interop != "" {
+		entries = 2
+	}
+	exif := 26
+	tiff := make([]byte, exif+2+entries*12+4+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifIFDTag)
+	order.PutUint16(tiff[12:], 4)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint32(tiff[18:], uint32(exif))
+
+	order.PutUint16(tiff[exif:], uint16(entries))
+	e := tiff[exif+2:]
+	order.PutUint16(e, exifColorSpaceTag)
+	order.PutUint16(e[2:], 3)
+	order.PutUint32(e[4:], 1)
+	order.PutUint16(e[8:], space)
+	if interop != "" {
+		ifd := exif + 2 + entries*12 + 4
+		order.PutUint16(e[12:], exifInteropTag)
+		order.PutUint16(e[14:], 4)
+		order.PutUint32(e[16:], 1)
+		order.PutUint32(e[20:], uint32(ifd))
+
+		order.PutUint16(tiff[ifd:], 1)
+		order.PutUint16(tiff[ifd+2:], interopIndexTag)
+		order.PutUint16(tiff[ifd+4:], 2)
+		order.PutUint32(tiff[ifd+6:], 4)
+		copy(tiff[ifd+10:], interop+"\x00")
+	}
+
+	b := []byte{0xff, 0xd8}
+	return append(append(b, segmentHeader(0xe1, 6+len(tiff))...), append([]byte("Exif\x00\x00"), tiff...)...)
+}
+
+func TestExifColorSpace(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		space   int
+		interop string
+	}{
+		{"no EXIF", []byte{0xff, 0xd8, 0xff, 0xda}, 0, ""},
+		{"orientation only", exifJPEG(binary.LittleEndian, 6), 0, ""},
+		{"sRGB", colorSpaceJPEG(binary.LittleEndian, exifSRGB, "R98"), exifSRGB, "R98"},
+		{"Adobe RGB", colorSpaceJPEG(binary.BigEndian, exifUncalibrated, "R03"), exifUncalibrated, "R03"},
+		{"no interoperability index", colorSpaceJPEG(binary.BigEndian, exifUncalibrated, ""), exifUncalibrated, ""},
+	}
+
+	for _, tt := range tests {
+		space, interop := exifColorSpace(tt.data)
+		if space != tt.space || interop != tt.interop {
+			t.Errorf("%s: got %d %q, want %d %q", tt.name, space, interop, tt.space, tt.interop)
+		}
+	}
+}
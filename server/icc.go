@@ -0,0 +1,472 @@
+package server
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// matrix3 is a 3x3 matrix converting between RGB and XYZ
+type matrix3 [3][3]float64
+
+func (m matrix3) mul(n matrix3) matrix3 {
+	var r matrix3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return r
+}
+
+func (m matrix3) inverse() matrix3 {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+
+	var r matrix3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			//The cofactor of the transposed element
+			a, b := (j+1)%3, (j+2)%3
+			c, d := (i+1)%3, (i+2)%3
+			r[i][j] = (m[a][c]*m[b][d] - m[a][d]*m[b][c]) / det
+		}
+	}
+	return r
+}
+
+// toneCurve maps encoded channel values to linear light, both from 0 to 1.
+// It is either a parametric curve, as in ICC para tags, or a lookup table.
+type toneCurve struct {
+	//g, a, b, c, d, e, f of the ICC parametric curves
+	params [7]float64
+	table  []float64
+}
+
+func gammaCurve(g float64) toneCurve {
+	return toneCurve{params: [7]float64{g, 1, 0, 0, 0, 0, 0}}
+}
+
+// srgbCurve is the sRGB transfer function
+var srgbCurve = toneCurve{params: [7]float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045, 0, 0}}
+
+func (c toneCurve) eval(x float64) float64 {
+	if c.table != nil {
+		if len(c.table) == 1 {
+			return c.table[0]
+		}
+		pos := x * float64(len(c.table)-1)
+		i := int(pos)
+		if i >= len(c.table)-1 {
+			return c.table[len(c.table)-1]
+		}
+		return c.table[i] + (c.table[i+1]-c.table[i])*(pos-float64(i))
+	}
+
+	g, a, b, cc, d, e, f := c.params[0], c.params[1], c.params[2], c.params[3], c.params[4], c.params[5], c.params[6]
+	if x < d {
+		return cc*x + f
+	}
+	v := a*x + b
+	if v <= 0 {
+		return e
+	}
+	return math.Pow(v, g) + e
+}
+
+// rgbProfile is an RGB colour profile made of a tone curve per channel and a
+// matrix to the D50 XYZ connection space of ICC profiles
+type rgbProfile struct {
+	curves [3]toneCurve
+	toXYZ  matrix3
+}
+
+// srgbProfile is sRGB adapted to D50 like ICC profiles
+var srgbProfile = rgbProfile{
+	curves: [3]toneCurve{srgbCurve, srgbCurve, srgbCurve},
+	toXYZ: matrix3{
+		{0.4360747, 0.3850649, 0.1430804},
+		{0.2225045, 0.7168786, 0.0606169},
+		{0.0139322, 0.0971045, 0.7141733},
+	},
+}
+
+// adobeRGBProfile is Adobe RGB (1998) adapted to D50. EXIF data refers to it
+// when there is no embedded profile.
+var adobeRGBProfile = rgbProfile{
+	curves: [3]toneCurve{gammaCurve(563.0 / 256), gammaCurve(563.0 / 256), gammaCurve(563.0 / 256)},
+	toXYZ: matrix3{
+		{0.6097559, 0.2052401, 0.1492240},
+		{0.3111242, 0.6256560, 0.0632197},
+		{0.0194811, 0.0608902, 0.7448387},
+	},
+}
+
+var xyzToSRGB = srgbProfile.toXYZ.inverse()
+
+// isSRGB tells whether a profile is close enough to sRGB that converting
+// would only add rounding errors
+func (p *rgbProfile) isSRGB() bool {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if math.Abs(p.toXYZ[i][j]-srgbProfile.toXYZ[i][j]) > 0.002 {
+				return false
+			}
+		}
+	}
+	for c := 0; c < 3; c++ {
+		for v := 0; v < 256; v += 5 {
+			x := float64(v) / 255
+			if math.Abs(p.curves[c].eval(x)-srgbCurve.eval(x)) > 0.001 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// errUnsupportedProfile is returned for colour profiles that are too complex
+// to apply here, such as those made of lookup tables
+var errUnsupportedProfile = errors.New("only matrix based RGB colour profiles are supported")
+
+// parseICC reads the matrix and tone curves of an ICC profile. Profiles of
+// other colour spaces than RGB, such as those of greyscale or CMYK images,
+// return nil.
+func parseICC(p []byte) (*rgbProfile, error) {
+	if len(p) < 132 || string(p[36:40]) != "acsp" {
+		return nil, errors.New("not an ICC profile")
+	}
+	if string(p[16:20]) != "RGB " {
+		return nil, nil
+	}
+	if string(p[20:24]) != "XYZ " {
+		return nil, errUnsupportedProfile
+	}
+
+	tags := map[string][]byte{}
+	count := int(binary.BigEndian.Uint32(p[128:]))
+	for i := 0; i < count; i++ {
+		t := 132 + i*12
+		if t+12 > len(p) {
+			return nil, errors.New("truncated ICC tag table")
+		}
+		offset := int(binary.BigEndian.Uint32(p[t+4:]))
+		size := int(binary.BigEndian.Uint32(p[t+8:]))
+		if offset < 0 || size < 0 || offset+size > len(p) {
+			return nil, errors.New("ICC tag out of bounds")
+		}
+		tags[string(p[t:t+4])] = p[offset : offset+size]
+	}
+
+	var profile rgbProfile
+	for c, name := range []string{"r", "g", "b"} {
+		xyz, ok := tags[name+"XYZ"]
+		trc, ok2 := tags[name+"TRC"]
+		if !ok || !ok2 {
+			return nil, errUnsupportedProfile
+		}
+
+		if len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+			return nil, fmt.Errorf("invalid %sXYZ tag", name)
+		}
+		for i := 0; i < 3; i++ {
+			profile.toXYZ[i][c] = s15Fixed16(xyz[8+i*4:])
+		}
+
+		curve, err := parseCurve(trc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %sTRC tag: %v", name, err)
+		}
+		profile.curves[c] = curve
+	}
+	return &profile, nil
+}
+
+// parametricCurveParams is the number of parameters of each type of ICC
+// parametric curve
+var parametricCurveParams = []int{1, 3, 4, 5, 7}
+
+// parseCurve reads an ICC curv or para tag
+func parseCurve(b []byte) (toneCurve, error) {
+	if len(b) < 12 {
+		return toneCurve{}, errors.New("truncated")
+	}
+
+	switch string(b[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(b[8:]))
+		if n < 0 || 12+n*2 > len(b) {
+			return toneCurve{}, errors.New("truncated")
+		}
+		switch n {
+		case 0:
+			return gammaCurve(1), nil
+		case 1:
+			return gammaCurve(float64(binary.BigEndian.Uint16(b[12:])) / 256), nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(b[12+i*2:])) / 65535
+		}
+		return toneCurve{table: table}, nil
+
+	case "para":
+		kind := int(binary.BigEndian.Uint16(b[8:]))
+		if kind >= len(parametricCurveParams) {
+			return toneCurve{}, fmt.Errorf("unknown parametric curve %d", kind)
+		}
+		n := parametricCurveParams[kind]
+		if 12+n*4 > len(b) {
+			return toneCurve{}, errors.New("truncated")
+		}
+		var v [7]float64
+		for i := 0; i < n; i++ {
+			v[i] = s15Fixed16(b[12+i*4:])
+		}
+
+		//Fill in the parameters the simpler types leave out
+		g, a, bb, c, d, e, f := v[0], 1.0, 0.0, 0.0, 0.0, 0.0, 0.0
+		switch kind {
+		case 1:
+			a, bb = v[1], v[2]
+			d = -bb / a
+		case 2:
+			a, bb, e = v[1], v[2], v[3]
+			d, f = -bb/a, e
+		case 3:
+			a, bb, c, d = v[1], v[2], v[3], v[4]
+		case 4:
+			a, bb, c, d, e, f = v[1], v[2], v[3], v[4], v[5], v[6]
+		}
+		return toneCurve{params: [7]float64{g, a, bb, c, d, e, f}}, nil
+	}
+	return toneCurve{}, fmt.Errorf("unknown curve type %q", b[:4])
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// embeddedProfile extracts the ICC profile embedded in an image, nil if it
+// has none
+func embeddedProfile(b []byte, format pb.ImageFormat) ([]byte, error) {
+	switch format {
+	case pb.ImageFormat_JPG:
+		return jpegProfile(b)
+	case pb.ImageFormat_PNG:
+		return pngProfile(b)
+	case pb.ImageFormat_WEBP:
+		return webpProfile(b), nil
+	case pb.ImageFormat_TIFF:
+		if t, ok := newTIFFReader(b); ok {
+			if p, ok := t.value(t.first(), tiffICCProfileTag); ok {
+				return p, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// jpegProfile joins the chunks of a profile stored in APP2 segments
+func jpegProfile(b []byte) ([]byte, error) {
+	if len(b) < 4 || b[0] != 0xff || b[1] != 0xd8 {
+		return nil, nil
+	}
+
+	var chunks [][]byte
+	for i := 2; i+4 <= len(b); {
+		marker := b[i+1]
+		if b[i] != 0xff || marker == 0xda || marker == 0xd9 {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(b[i+2:]))
+		if size < 2 || i+2+size > len(b) {
+			break
+		}
+		segment := b[i+4 : i+2+size]
+		if marker == 0xe2 && len(segment) >= 14 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")) {
+			seq, count := int(segment[12]), int(segment[13])
+			if chunks == nil {
+				chunks = make([][]byte, count)
+			}
+			if seq < 1 || seq > len(chunks) || count != len(chunks) {
+				return nil, errors.New("invalid ICC profile chunk")
+			}
+			chunks[seq-1] = segment[14:]
+		}
+		i += 2 + size
+	}
+	if chunks == nil {
+		return nil, nil
+	}
+
+	var profile []byte
+	for _, chunk := range chunks {
+		if chunk == nil {
+			return nil, errors.New("ICC profile chunk missing")
+		}
+		profile = append(profile, chunk...)
+	}
+	return profile, nil
+}
+
+// pngProfile decompresses the profile of an iCCP chunk
+func pngProfile(b []byte) ([]byte, error) {
+	for i := 8; i+12 <= len(b); {
+		size := int(binary.BigEndian.Uint32(b[i:]))
+		kind := string(b[i+4 : i+8])
+		if size < 0 || i+12+size > len(b) || kind == "IDAT" {
+			break
+		}
+		if kind == "iCCP" {
+			data := b[i+8 : i+8+size]
+			name := bytes.IndexByte(data, 0)
+			if name < 0 || name+2 > len(data) || data[name+1] != 0 {
+				return nil, errors.New("invalid iCCP chunk")
+			}
+			r, err := zlib.NewReader(bytes.NewReader(data[name+2:]))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return ioutil.ReadAll(r)
+		}
+		i += 12 + size
+	}
+	return nil, nil
+}
+
+// webpProfile finds the ICCP chunk of an extended WebP file
+func webpProfile(b []byte) []byte {
+	if len(b) < 12 || string(b[:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return nil
+	}
+	for i := 12; i+8 <= len(b); {
+		size := int(binary.LittleEndian.Uint32(b[i+4:]))
+		if size < 0 || i+8+size > len(b) {
+			break
+		}
+		if string(b[i:i+4]) == "ICCP" {
+			return b[i+8 : i+8+size]
+		}
+		//Chunks are padded to an even size
+		i += 8 + size + size&1
+	}
+	return nil
+}
+
+// imageProfile finds the colour profile of a submitted image, from its
+// embedded ICC profile or else from the EXIF colour space of a JPEG. Images
+// without either are sRGB and get nil.
+func imageProfile(b []byte, format pb.ImageFormat) (*rgbProfile, error) {
+	icc, err := embeddedProfile(b, format)
+	if err != nil {
+		return nil, err
+	}
+	if icc != nil {
+		return parseICC(icc)
+	}
+
+	if format == pb.ImageFormat_JPG {
+		space, interop := exifColorSpace(b)
+		if space == exifUncalibrated {
+			if interop == "R03" {
+				return &adobeRGBProfile, nil
+			}
+			return nil, errors.New("the EXIF colour space is uncalibrated and there is no colour profile")
+		}
+	}
+	return nil, nil
+}
+
+// toSRGB converts an image to 8 bit sRGB, applying its colour profile. Images
+// whose profile can't be applied are rejected rather than shown with the
+// wrong colours.
+func toSRGB(img image.Image, b []byte, format pb.ImageFormat) (*image.RGBA, error) {
+	profile, err := imageProfile(b, format)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Could not apply the colour profile: %v", err)
+	}
+
+	src := toRGBA(img)
+	if profile == nil || profile.isSRGB() {
+		return src, nil
+	}
+
+	var linear [3][256]float64
+	for c := 0; c < 3; c++ {
+		for v := 0; v < 256; v++ {
+			linear[c][v] = profile.curves[c].eval(float64(v) / 255)
+		}
+	}
+
+	//Encode linear sRGB with a table fine enough for the dark end of the curve
+	const steps = 16384
+	var encode [steps + 1]uint8
+	for i := range encode {
+		x := float64(i) / steps
+		var v float64
+		if x <= 0.0031308 {
+			v = 12.92 * x
+		} else {
+			v = 1.055*math.Pow(x, 1/2.4) - 0.055
+		}
+		encode[i] = uint8(v*255 + 0.5)
+	}
+
+	m := xyzToSRGB.mul(profile.toXYZ)
+	dst := image.NewRGBA(src.Bounds())
+	b0 := src.Bounds()
+	for y := b0.Min.Y; y < b0.Max.Y; y++ {
+		i := src.PixOffset(b0.Min.X, y)
+		for x := b0.Min.X; x < b0.Max.X; x, i = x+1, i+4 {
+			p := src.Pix[i : i+4 : i+4]
+			a := int(p[3])
+			if a == 0 {
+				continue
+			}
+
+			//RGBA is premultiplied, the curves apply to the colour itself
+			var rgb [3]float64
+			for c := 0; c < 3; c++ {
+				v := int(p[c])
+				if a < 255 {
+					v = (v*255 + a/2) / a
+					if v > 255 {
+						v = 255
+					}
+				}
+				rgb[c] = linear[c][v]
+			}
+
+			q := dst.Pix[i : i+4 : i+4]
+			for c := 0; c < 3; c++ {
+				v := m[c][0]*rgb[0] + m[c][1]*rgb[1] + m[c][2]*rgb[2]
+				if v < 0 {
+					v = 0
+				} else if v > 1 {
+					v = 1
+				}
+				e := int(encode[int(v*steps+0.5)])
+				if a < 255 {
+					e = (e*a + 127) / 255
+				}
+				q[c] = uint8(e)
+			}
+			q[3] = uint8(a)
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// displayP3 is the matrix of Display P3 adapted to D50
+var displayP3 = matrix3{
+	{0.5151, 0.2920, 0.1571},
+	{0.2412, 0.6922, 0.0666},
+	{-0.0011, 0.0419, 0.7841},
+}
+
+// srgbPara is the sRGB curve as an ICC parametric curve
+var srgbPara = paraTag(3, 2.4, 1/1.055, 0.055/1.055, 1/12.92, 0.04045)
+
+func paraTag(kind uint16, params ...float64) []byte {
+	b := make([]byte, 12+len(params)*4)
+	copy(b, "para")
+	binary.BigEndian.PutUint16(b[8:], kind)
+	for i, v := range params {
+		binary.BigEndian.PutUint32(b[12+i*4:], uint32(int32(v*65536+0.5)))
+	}
+	return b
+}
+
+func gammaTag(g float64) []byte {
+	b := make([]byte, 14)
+	copy(b, "curv")
+	binary.BigEndian.PutUint32(b[8:], 1)
+	binary.BigEndian.PutUint16(b[12:], uint16(g*256+0.5))
+	return b
+}
+
+// iccProfile builds an ICC profile of a colour space with the given tags
+func iccProfile(space string, tags map[string][]byte) []byte {
+	names := []string{}
+	for name := range tags {
+		names = append(names, name)
+	}
+
+	p := make([]byte, 132+len(names)*12)
+	copy(p[16:], space)
+	copy(p[20:], "XYZ ")
+	copy(p[36:], "acsp")
+	binary.BigEndian.PutUint32(p[128:], uint32(len(names)))
+	for i, name := range names {
+		t := p[132+i*12:]
+		copy(t, name)
+		binary.BigEndian.PutUint32(t[4:], uint32(len(p)))
+		binary.BigEndian.PutUint32(t[8:], uint32(len(tags[name])))
+		p = append(p, tags[name]...)
+	}
+	binary.BigEndian.PutUint32(p, uint32(len(p)))
+	return p
+}
+
+// matrixProfile builds an RGB ICC profile from a matrix and one curve for
+// every channel
+func matrixProfile(m matrix3, curve []byte) []byte {
+	tags := map[string][]byte{}
+	for c, name := range []string{"r", "g", "b"} {
+		xyz := make([]byte, 20)
+		copy(xyz, "XYZ ")
+		for i := 0; i < 3; i++ {
+			binary.BigEndian.PutUint32(xyz[8+i*4:], uint32(int32(m[i][c]*65536+0.5)))
+		}
+		tags[name+"XYZ"] = xyz
+		tags[name+"TRC"] = curve
+	}
+	return iccProfile("RGB ", tags)
+}
+
+// iccJPEG builds the start of a JPEG with a profile split in APP2 segments,
+// stored last first
+func iccJPEG(profile []byte, chunks int) []byte {
+	b := []byte{0xff, 0xd8}
+	size := (len(profile) + chunks - 1) / chunks
+	for i := chunks - 1; i >= 0; i-- {
+		end := (i + 1) * size
+		if end > len(profile) {
+			end = len(profile)
+		}
+		chunk := profile[i*size : end]
+		b = append(b, segmentHeader(0xe2, 14+len(chunk))...)
+		b = append(b, "ICC_PROFILE\x00"...)
+		b = append(b, byte(i+1), byte(chunks))
+		b = append(b, chunk...)
+	}
+	return b
+}
+
+// iccPNG encodes an image as a PNG with an iCCP chunk
+func iccPNG(t *testing.T, img image.Image, profile []byte) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write(profile)
+	w.Close()
+
+	data := append([]byte("iCCP"), "profile\x00\x00"...)
+	data = append(data, z.Bytes()...)
+	chunk := make([]byte, 4, len(data)+8)
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)-4))
+	chunk = append(chunk, data...)
+	chunk = append(chunk, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(chunk[len(chunk)-4:], crc32.ChecksumIEEE(data))
+
+	//The chunk goes right after the signature and IHDR
+	b := buf.Bytes()
+	return append(append(append([]byte{}, b[:33]...), chunk...), b[33:]...)
+}
+
+func TestParseICC(t *testing.T) {
+	lut := iccProfile("RGB ", map[string][]byte{"A2B0": make([]byte, 32)})
+	gray := iccProfile("GRAY", map[string][]byte{"kTRC": gammaTag(2.2)})
+	lab := matrixProfile(displayP3, srgbPara)
+	copy(lab[20:], "Lab ")
+
+	tests := []struct {
+		name    string
+		profile []byte
+		srgb    bool
+		none    bool
+		err     bool
+	}{
+		{name: "sRGB", profile: matrixProfile(srgbProfile.toXYZ, srgbPara), srgb: true},
+		{name: "Display P3", profile: matrixProfile(displayP3, srgbPara)},
+		{name: "Adobe RGB", profile: matrixProfile(adobeRGBProfile.toXYZ, gammaTag(2.2))},
+		{name: "greyscale", profile: gray, none: true},
+		{name: "lookup tables", profile: lut, err: true},
+		{name: "Lab connection space", profile: lab, err: true},
+		{name: "not a profile", profile: make([]byte, 200), err: true},
+		{name: "truncated", profile: matrixProfile(displayP3, srgbPara)[:150], err: true},
+	}
+
+	for _, tt := range tests {
+		p, err := parseICC(tt.profile)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if (p == nil) != tt.none {
+			t.Errorf("%s: got profile %v", tt.name, p)
+			continue
+		}
+		if p != nil && p.isSRGB() != tt.srgb {
+			t.Errorf("%s: isSRGB is %v", tt.name, !tt.srgb)
+		}
+	}
+}
+
+func TestParseCurve(t *testing.T) {
+	table := make([]byte, 12+3*2)
+	copy(table, "curv")
+	binary.BigEndian.PutUint32(table[8:], 3)
+	binary.BigEndian.PutUint16(table[14:], 65535/4)
+	binary.BigEndian.PutUint16(table[16:], 65535)
+
+	tests := []struct {
+		name  string
+		curve []byte
+		x     float64
+		want  float64
+	}{
+		{"identity", append([]byte("curv"), make([]byte, 8)...), 0.5, 0.5},
+		{"gamma", gammaTag(2), 0.5, 0.25},
+		{"table", table, 0.75, 0.625},
+		{"sRGB", srgbPara, 0.5, 0.2140},
+		{"sRGB linear part", srgbPara, 0.02, 0.02 / 12.92},
+		{"parametric gamma", paraTag(0, 2), 0.5, 0.25},
+		{"offset", paraTag(2, 1, 1, 0, 0.25), 0.5, 0.75},
+	}
+
+	for _, tt := range tests {
+		c, err := parseCurve(tt.curve)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := c.eval(tt.x); got < tt.want-0.001 || got > tt.want+0.001 {
+			t.Errorf("%s: got %.4f at %v, want %.4f", tt.name, got, tt.x, tt.want)
+		}
+	}
+
+	if _, err := parseCurve(paraTag(5, 1)); err == nil {
+		t.Errorf("unknown parametric curve accepted")
+	}
+}
+
+func TestEmbeddedProfile(t *testing.T) {
+	profile := matrixProfile(displayP3, srgbPara)
+
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8X\x0a\x00\x00\x00")
+	webp = append(webp, make([]byte, 10)...)
+	webp = append(webp, "ODDS\x03\x00\x00\x00abc\x00"...)
+	webp = append(webp, "ICCP"...)
+	webp = append(webp, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(webp[len(webp)-4:], uint32(len(profile)))
+	webp = append(webp, profile...)
+
+	tiff := make([]byte, 8+2+12+4)
+	copy(tiff, "II")
+	binary.LittleEndian.PutUint16(tiff[2:], 42)
+	binary.LittleEndian.PutUint32(tiff[4:], 8)
+	binary.LittleEndian.PutUint16(tiff[8:], 1)
+	binary.LittleEndian.PutUint16(tiff[10:], tiffICCProfileTag)
+	binary.LittleEndian.PutUint16(tiff[12:], 7)
+	binary.LittleEndian.PutUint32(tiff[14:], uint32(len(profile)))
+	binary.LittleEndian.PutUint32(tiff[18:], uint32(len(tiff)))
+	tiff = append(tiff, profile...)
+
+	missing := iccJPEG(profile, 3)
+	missing = append(missing[:2], missing[2+4+14+len(profile)/3:]...)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format pb.ImageFormat
+		want   []byte
+		err    bool
+	}{
+		{name: "JPEG", data: iccJPEG(profile, 1), format: pb.ImageFormat_JPG, want: profile},
+		{name: "JPEG in chunks", data: iccJPEG(profile, 3), format: pb.ImageFormat_JPG, want: profile},
+		{name: "JPEG missing a chunk", data: missing, format: pb.ImageFormat_JPG, err: true},
+		{name: "JPEG without profile", data: exifJPEG(binary.BigEndian, 1), format: pb.ImageFormat_JPG},
+		{name: "PNG", data: iccPNG(t, image.NewRGBA(image.Rect(0, 0, 2, 2)), profile), format: pb.ImageFormat_PNG, want: profile},
+		{name: "PNG without profile", data: sampleImage(t, pb.ImageFormat_PNG, 2, 2), format: pb.ImageFormat_PNG},
+		{name: "WebP", data: webp, format: pb.ImageFormat_WEBP, want: profile},
+		{name: "TIFF", data: tiff, format: pb.ImageFormat_TIFF, want: profile},
+		{name: "GIF", data: sampleImage(t, pb.ImageFormat_GIF, 2, 2), format: pb.ImageFormat_GIF},
+	}
+
+	for _, tt := range tests {
+		got, err := embeddedProfile(tt.data, tt.format)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: got error %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got a %d byte profile, want %d bytes", tt.name, len(got), len(tt.want))
+		}
+	}
+}
+
+func TestToSRGB(t *testing.T) {
+	p3 := iccJPEG(matrixProfile(displayP3, srgbPara), 1)
+	adobe := colorSpaceJPEG(binary.LittleEndian, exifUncalibrated, "R03")
+
+	tests := []struct {
+		name string
+		data []byte
+		in   color.NRGBA
+		want color.NRGBA
+	}{
+		{"no profile", nil, color.NRGBA{234, 51, 35, 255}, color.NRGBA{234, 51, 35, 255}},
+		{"sRGB profile", iccJPEG(matrixProfile(srgbProfile.toXYZ, srgbPara), 1), color.NRGBA{234, 51, 35, 255}, color.NRGBA{234, 51, 35, 255}},
+		{"sRGB EXIF", colorSpaceJPEG(binary.BigEndian, exifSRGB, "R98"), color.NRGBA{234, 51, 35, 255}, color.NRGBA{234, 51, 35, 255}},
+		{"Display P3 red", p3, color.NRGBA{234, 51, 35, 255}, color.NRGBA{255, 0, 0, 255}},
+		{"Display P3 grey", p3, color.NRGBA{128, 128, 128, 255}, color.NRGBA{128, 128, 128, 255}},
+		{"Display P3 translucent", p3, color.NRGBA{234, 51, 35, 128}, color.NRGBA{255, 0, 0, 128}},
+		{"Adobe RGB red", adobe, color.NRGBA{219, 0, 0, 255}, color.NRGBA{255, 0, 0, 255}},
+		{"Adobe RGB green", adobe, color.NRGBA{144, 255, 60, 255}, color.NRGBA{0, 255, 0, 255}},
+	}
+
+	for _, tt := range tests {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = tt.in.R, tt.in.G, tt.in.B, tt.in.A
+		}
+
+		out, err := toSRGB(img, tt.data, pb.ImageFormat_JPG)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := color.NRGBAModel.Convert(out.At(1, 1)).(color.NRGBA)
+		if !closeColor(got, tt.want, 3) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	rejected := map[string][]byte{
+		"lookup tables": iccJPEG(iccProfile("RGB ", map[string][]byte{"A2B0": make([]byte, 32)}), 1),
+		"uncalibrated":  colorSpaceJPEG(binary.LittleEndian, exifUncalibrated, ""),
+	}
+	for name, data := range rejected {
+		_, err := toSRGB(image.NewRGBA(image.Rect(0, 0, 1, 1)), data, pb.ImageFormat_JPG)
+		if grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", name, err)
+		}
+	}
+}
+
+func closeColor(a, b color.NRGBA, tolerance int) bool {
+	for _, d := range []int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B), int(a.A) - int(b.A)} {
+		if d < -tolerance || d > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNormalizeColourProfiles(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	s.Normalizer = Normalizer{Steps: []NormalizeStep{StepSRGB}}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < len(img.Pix); i += 4 {
+		copy(img.Pix[i:], []byte{234, 51, 35, 255})
+	}
+	lut := iccProfile("RGB ", map[string][]byte{"A2B0": make([]byte, 32)})
+
+	_, err := s.CreateFullJob(context.Background(), &pb.CreateFullJobRequest{
+		Name:    "lut",
+		Style:   &pb.InputImage{Title: "wave", Image: sampleImage(t, pb.ImageFormat_PNG, 4, 4)},
+		Content: &pb.InputImage{Title: "cat", Image: iccPNG(t, img, lut)},
+	})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("image with a lookup table profile: got %v, want InvalidArgument", err)
+	}
+
+	_, err = s.CreateFullJob(context.Background(), &pb.CreateFullJobRequest{
+		Name:    "p3",
+		Style:   &pb.InputImage{Title: "wave", Image: sampleImage(t, pb.ImageFormat_PNG, 4, 4)},
+		Content: &pb.InputImage{Title: "cat", Image: iccPNG(t, img, matrixProfile(displayP3, srgbPara))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range s.PendingJobs {
+		content, err := png.Decode(bytes.NewReader(v.ContentImage))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := color.NRGBAModel.Convert(content.At(0, 0)).(color.NRGBA)
+		if !closeColor(got, color.NRGBA{255, 0, 0, 255}, 3) {
+			t.Errorf("Display P3 content queued as %v", got)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"mime"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	//Not every system knows these, and the output directory is served as is
+	mime.AddExtensionType(".webp", "image/webp")
+	mime.AddExtensionType(".tiff", "image/tiff")
+}
+
+// Bounds on the images accepted for a job. The engine needs several times
+// the decoded size in GPU memory.
+const (
+	maxImageSize   = 100 * 1024 * 1024
+	maxImagePixels = 50 * 1000 * 1000
+)
+
+// checkedImage is an image that decodes, along with what it turned out to be
+type checkedImage struct {
+	Data    []byte
+	Format  pb.ImageFormat
+	Width   int
+	Height  int
+	Decoded image.Image
+}
+
+// checkImage decodes a submitted image to make sure it is complete and
+// detects its real format instead of trusting the one claimed by the client
+func checkImage(what string, b []byte) (checkedImage, error) {
+	if len(b) == 0 {
+		return checkedImage{}, grpc.Errorf(codes.InvalidArgument, "The %s image is empty", what)
+	}
+	if len(b) > maxImageSize {
+		return checkedImage{}, grpc.Errorf(codes.InvalidArgument, "The %s image is larger than %d bytes", what, maxImageSize)
+	}
+
+	//Check the size before decoding so a huge image can't exhaust memory
+	config, name, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return checkedImage{}, grpc.Errorf(codes.InvalidArgument, "The %s image can't be read: %v", what, err)
+	}
+	format := formatFromName(name)
+	if format == pb.ImageFormat_UNKNOWN {
+		return checkedImage{}, grpc.Errorf(codes.InvalidArgument, "The %s image is in unsupported format %q", what, name)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return checkedImage{}, grpc.Errorf(codes.InvalidArgument, "The %s image is %dx%d, it must have at most %d pixels", what, config.Width, config.Height, maxImagePixels)
+	}
+
+	//A truncated upload only shows up when decoding the whole image
+	decoded, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return checkedImage{}, grpc.Errorf(codes.InvalidArgument, "The %s image is corrupt: %v", what, err)
+	}
+
+	return checkedImage{
+		Data:    b,
+		Format:  format,
+		Width:   config.Width,
+		Height:  config.Height,
+		Decoded: decoded,
+	}, nil
+}
+
+// formatFromName maps the format names of the image package
+func formatFromName(name string) pb.ImageFormat {
+	switch name {
+	case "jpeg":
+		return pb.ImageFormat_JPG
+	case "png":
+		return pb.ImageFormat_PNG
+	case "webp":
+		return pb.ImageFormat_WEBP
+	case "gif":
+		return pb.ImageFormat_GIF
+	case "tiff":
+		return pb.ImageFormat_TIFF
+	}
+	return pb.ImageFormat_UNKNOWN
+}
+
+// sniffFormat tells the format of an image that has already been checked
+func sniffFormat(b []byte) pb.ImageFormat {
+	_, name, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return pb.ImageFormat_UNKNOWN
+	}
+	return formatFromName(name)
+}
+
+// formatExtension is the file extension images in a format are stored with
+func formatExtension(format pb.ImageFormat) string {
+	switch format {
+	case pb.ImageFormat_JPG:
+		return ".jpg"
+	case pb.ImageFormat_PNG:
+		return ".png"
+	case pb.ImageFormat_WEBP:
+		return ".webp"
+	case pb.ImageFormat_GIF:
+		return ".gif"
+	case pb.ImageFormat_TIFF:
+		return ".tiff"
+	}
+	return ""
+}
+
+// formatContentType is the MIME type images in a format are served with
+func formatContentType(format pb.ImageFormat) string {
+	switch format {
+	case pb.ImageFormat_JPG:
+		return "image/jpeg"
+	case pb.ImageFormat_PNG:
+		return "image/png"
+	case pb.ImageFormat_WEBP:
+		return "image/webp"
+	case pb.ImageFormat_GIF:
+		return "image/gif"
+	case pb.ImageFormat_TIFF:
+		return "image/tiff"
+	}
+	return "application/octet-stream"
+}
+
+// engineReadable tells whether the engine can load images in a format.
+// Others are converted to PNG before the job is queued.
+func engineReadable(format pb.ImageFormat) bool {
+	return format == pb.ImageFormat_JPG || format == pb.ImageFormat_PNG
+}
+
+// outputFormat is the format the results of a job are stored in
+func outputFormat(params *pb.JobParameters) pb.ImageFormat {
+	if params == nil || params.OutputFormat == pb.ImageFormat_UNKNOWN {
+		return pb.ImageFormat_PNG
+	}
+	return params.OutputFormat
+}
+
+// checkOutputFormat makes sure results can be stored in the format a job asks
+// for. There is no WebP encoder.
+func checkOutputFormat(params *pb.JobParameters) error {
+	switch outputFormat(params) {
+	case pb.ImageFormat_JPG, pb.ImageFormat_PNG, pb.ImageFormat_GIF, pb.ImageFormat_TIFF:
+		return nil
+	}
+	return grpc.Errorf(codes.InvalidArgument, "Results can't be stored as %s", outputFormat(params))
+}
+
+// encodeImage writes an image in the given format
+func encodeImage(img image.Image, format pb.ImageFormat) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case pb.ImageFormat_JPG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: normalizedJPEGQuality})
+	case pb.ImageFormat_PNG:
+		err = png.Encode(&buf, img)
+	case pb.ImageFormat_GIF:
+		err = gif.Encode(&buf, img, &gif.Options{NumColors: 256, Drawer: draw.FloydSteinberg})
+	case pb.ImageFormat_TIFF:
+		err = tiff.Encode(&buf, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+	default:
+		err = fmt.Errorf("Can't encode images as %s", format)
+	}
+	return buf.Bytes(), err
+}
+
+// transcode converts a result to the output format. The engine renders PNG,
+// animated jobs are put together as GIF. Results already in the output
+// format are left alone.
+func transcode(b []byte, format pb.ImageFormat) ([]byte, error) {
+	_, name, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if formatFromName(name) == format {
+		return b, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return encodeImage(img, format)
+}
+
+// imageData returns the contents of an image the client may have left out
+func imageData(img *pb.InputImage) []byte {
+	if img == nil {
+		return nil
+	}
+	return img.Image
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// sampleImage encodes a black image in a format
+func sampleImage(t *testing.T, format pb.ImageFormat, w, h int) []byte {
+	b, err := encodeImage(image.NewGray(image.Rect(0, 0, w, h)), format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// resizedPNG claims other dimensions in the header of a PNG, keeping the
+// header checksum valid
+func resizedPNG(b []byte, w, h uint32) []byte {
+	r := append([]byte{}, b...)
+	//Signature, chunk length and type come before the dimensions
+	ihdr := r[12:29]
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	binary.BigEndian.PutUint32(r[29:], crc32.ChecksumIEEE(ihdr))
+	return r
+}
+
+func TestCheckImage(t *testing.T) {
+	pngImage := sampleImage(t, pb.ImageFormat_PNG, 8, 4)
+
+	img, err := checkImage("content", pngImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != pb.ImageFormat_PNG || img.Width != 8 || img.Height != 4 {
+		t.Errorf("checked as %s %dx%d, want PNG 8x4", img.Format, img.Width, img.Height)
+	}
+	for _, format := range []pb.ImageFormat{pb.ImageFormat_JPG, pb.ImageFormat_GIF, pb.ImageFormat_TIFF} {
+		img, err = checkImage("content", sampleImage(t, format, 8, 4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Format != format {
+			t.Errorf("checked as %s, want %s", img.Format, format)
+		}
+	}
+
+	invalid := map[string][]byte{
+		"empty":           nil,
+		"not an image":    []byte("hello"),
+		"truncated":       pngImage[:len(pngImage)-20],
+		"too many pixels": resizedPNG(pngImage, 10000, 10000),
+		"no pixels":       resizedPNG(pngImage, 0, 4),
+	}
+	for name, b := range invalid {
+		if _, err := checkImage("content", b); grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", name, err)
+		}
+	}
+}
+
+func TestCreateFullJobFormats(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	//The format claimed by the client is ignored
+	_, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Format: pb.ImageFormat_PNG, Image: sampleImage(t, pb.ImageFormat_JPG, 16, 16)},
+		Content: &pb.InputImage{Title: "cat", Format: pb.ImageFormat_JPG, Image: sampleImage(t, pb.ImageFormat_PNG, 32, 16)},
+		Params:  &pb.JobParameters{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.PendingJobs) != 1 {
+		t.Fatalf("%d jobs queued, want 1", len(s.PendingJobs))
+	}
+	for _, v := range s.PendingJobs {
+		if v.StyleFormat != pb.ImageFormat_JPG || v.ContentFormat != pb.ImageFormat_PNG || v.Width != 32 || v.Height != 16 {
+			t.Errorf("queued style %s, content %s %dx%d", v.StyleFormat, v.ContentFormat, v.Width, v.Height)
+		}
+	}
+
+	_, err = s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "dog",
+		Style:   &pb.InputImage{Title: "wave", Image: sampleImage(t, pb.ImageFormat_JPG, 16, 16)},
+		Content: &pb.InputImage{Title: "dog", Image: []byte("not an image")},
+	})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("job with a bad content image: got %v, want InvalidArgument", err)
+	}
+	if _, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{Name: "dog"}); grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("job without images: got %v, want InvalidArgument", err)
+	}
+	if len(s.PendingJobs) != 1 {
+		t.Errorf("invalid jobs queued")
+	}
+}
+
+func TestTranscode(t *testing.T) {
+	result := sampleImage(t, pb.ImageFormat_PNG, 12, 6)
+
+	for _, format := range []pb.ImageFormat{pb.ImageFormat_PNG, pb.ImageFormat_JPG, pb.ImageFormat_GIF, pb.ImageFormat_TIFF} {
+		b, err := transcode(result, format)
+		if err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		img, err := checkImage("result", b)
+		if err != nil {
+			t.Errorf("%s: %v", format, err)
+			continue
+		}
+		if img.Format != format || img.Width != 12 || img.Height != 6 {
+			t.Errorf("transcoded to %s got %s %dx%d", format, img.Format, img.Width, img.Height)
+		}
+	}
+
+	if _, err := transcode(result, pb.ImageFormat_WEBP); err == nil {
+		t.Errorf("transcoded to WebP without an encoder")
+	}
+	if _, err := transcode([]byte("not a PNG"), pb.ImageFormat_JPG); err == nil {
+		t.Errorf("transcoded a broken result")
+	}
+}
+
+func TestCheckOutputFormat(t *testing.T) {
+	ok := map[pb.ImageFormat]bool{
+		pb.ImageFormat_UNKNOWN: true,
+		pb.ImageFormat_PNG:     true,
+		pb.ImageFormat_JPG:     true,
+		pb.ImageFormat_GIF:     true,
+		pb.ImageFormat_TIFF:    true,
+		pb.ImageFormat_WEBP:    false,
+	}
+	for format, want := range ok {
+		err := checkOutputFormat(&pb.JobParameters{OutputFormat: format})
+		if (err == nil) != want {
+			t.Errorf("%s: got %v, want ok %t", format, err, want)
+		}
+	}
+	if err := checkOutputFormat(nil); err != nil || outputFormat(nil) != pb.ImageFormat_PNG {
+		t.Errorf("jobs without parameters must store PNG")
+	}
+}
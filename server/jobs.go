@@ -0,0 +1,138 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"github.com/nu7hatch/gouuid"
+)
+
+func newID() (string, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(id.String(), "-", "", -1), nil
+}
+
+// status describes a job for the UI. Jobs rendered through jobs of their own
+// describe themselves unless they are in a final state.
+func (j *Job) status(status string) string {
+	if status != "" {
+		return status
+	}
+
+	switch {
+	case j.Tiling != nil:
+		return "Rendering tiles"
+	case len(j.Stages) > 0:
+		return fmt.Sprintf("Rendering stage %d of %d", len(j.Children), len(j.Stages))
+	case j.Animation != nil:
+		return "Rendering frames"
+	case j.Sweep != nil:
+		return "Rendering sweep"
+	}
+	return "In progress"
+}
+
+// isCurrentAttempt tells whether a report belongs to the latest dispatch of
+// the job. Reports without an attempt come from older workers and are
+// trusted.
+func (j *Job) isCurrentAttempt(attemptID string) bool {
+	return attemptID == "" || attemptID == j.AttemptID
+}
+
+// isDuplicate tells whether a report with the given sequence number has
+// already been processed. Workers send the reports of an attempt in order.
+func (j *Job) isDuplicate(sequence int64) bool {
+	return sequence != 0 && sequence <= j.LastSequence
+}
+
+// addPartial stores a partial result keeping them ordered by iteration. It
+// returns false if a result for that iteration was already stored.
+func (j *Job) addPartial(p PartialResult) bool {
+	i := sort.Search(len(j.PartialResults), func(i int) bool {
+		return j.PartialResults[i].Iteration >= p.Iteration
+	})
+	if i < len(j.PartialResults) && j.PartialResults[i].Iteration == p.Iteration {
+		return false
+	}
+
+	j.PartialResults = append(j.PartialResults, PartialResult{})
+	copy(j.PartialResults[i+1:], j.PartialResults[i:])
+	j.PartialResults[i] = p
+	return true
+}
+
+// latestPartial returns the partial result with the most iterations, or nil
+// if the job hasn't reported any progress yet
+func (j *Job) latestPartial() *PartialResult {
+	var latest *PartialResult
+	for i := range j.PartialResults {
+		if latest == nil || j.PartialResults[i].Iteration > latest.Iteration {
+			latest = &j.PartialResults[i]
+		}
+	}
+	return latest
+}
+
+// addLosses merges the loss samples of a report into the job time series,
+// keeping it ordered by iteration and dropping iterations already seen
+func (j *Job) addLosses(samples []*pb.LossSample) {
+	for _, l := range samples {
+		i := sort.Search(len(j.Losses), func(i int) bool {
+			return j.Losses[i].Iteration >= l.Iteration
+		})
+		if i < len(j.Losses) && j.Losses[i].Iteration == l.Iteration {
+			continue
+		}
+
+		j.Losses = append(j.Losses, LossSample{})
+		copy(j.Losses[i+1:], j.Losses[i:])
+		j.Losses[i] = LossSample{
+			Iteration: l.Iteration,
+			Content:   l.Content,
+			Style:     l.Style,
+			Total:     l.Total,
+		}
+	}
+}
+
+// Bounds on the engine output kept for each job
+const (
+	maxLogSize     = 1024 * 1024
+	maxLogAttempts = 10
+)
+
+// addLog stores the log of an attempt, replacing an earlier one for the same
+// attempt. Only the most recent attempts are kept.
+func (j *Job) addLog(l AttemptLog) {
+	if len(l.Log) > maxLogSize {
+		l.Log = l.Log[len(l.Log)-maxLogSize:]
+		l.Truncated = true
+	}
+
+	for i := range j.Logs {
+		if j.Logs[i].AttemptID == l.AttemptID {
+			j.Logs[i] = l
+			return
+		}
+	}
+
+	j.Logs = append(j.Logs, l)
+	if len(j.Logs) > maxLogAttempts {
+		j.Logs = j.Logs[len(j.Logs)-maxLogAttempts:]
+	}
+}
+
+// hasLog tells whether the log of an attempt has been stored
+func (j *Job) hasLog(attemptID string) bool {
+	for _, l := range j.Logs {
+		if l.AttemptID == attemptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+func TestAddPartial(t *testing.T) {
+	j := &Job{}
+	for _, i := range []int32{300, 100, 200, 100, 400} {
+		j.addPartial(PartialResult{Iteration: i, Image: []byte{byte(len(j.PartialResults))}})
+	}
+
+	if len(j.PartialResults) != 4 {
+		t.Fatalf("stored %d partials, want 4", len(j.PartialResults))
+	}
+	for i, want := range []int32{100, 200, 300, 400} {
+		if got := j.PartialResults[i].Iteration; got != want {
+			t.Errorf("partial %d at iteration %d, want %d", i, got, want)
+		}
+	}
+	//The first report of an iteration is kept
+	if j.PartialResults[0].Image[0] != 1 {
+		t.Errorf("repeated partial replaced the first one")
+	}
+	if p := j.latestPartial(); p == nil || p.Iteration != 400 {
+		t.Errorf("latest partial is %v, want 400", p)
+	}
+}
+
+func TestIsDuplicate(t *testing.T) {
+	j := &Job{LastSequence: 3}
+	for sequence, want := range map[int64]bool{0: false, 1: true, 3: true, 4: false} {
+		if got := j.isDuplicate(sequence); got != want {
+			t.Errorf("sequence %d after 3: duplicate %t, want %t", sequence, got, want)
+		}
+	}
+}
+
+func TestAddLosses(t *testing.T) {
+	j := &Job{}
+	j.addLosses([]*pb.LossSample{{Iteration: 2, Total: 20}, {Iteration: 3, Total: 30}})
+	//A resumed attempt repeats what the server already has
+	j.addLosses([]*pb.LossSample{{Iteration: 1, Total: 10}, {Iteration: 3, Total: 99}, {Iteration: 4, Total: 40}})
+
+	var got []float64
+	for i, l := range j.Losses {
+		if l.Iteration != int32(i+1) {
+			t.Errorf("loss %d at iteration %d", i, l.Iteration)
+		}
+		got = append(got, l.Total)
+	}
+	if fmt.Sprint(got) != "[10 20 30 40]" {
+		t.Errorf("got totals %v, want [10 20 30 40]", got)
+	}
+}
+
+func TestJobFromPath(t *testing.T) {
+	tests := map[string]bool{
+		"/api/losses/cat/1234":   true,
+		"/api/losses/cat/":       false,
+		"/api/losses/cat":        false,
+		"/api/losses/cat/1234/x": false,
+	}
+	for p, ok := range tests {
+		name, id, got := jobFromPath(p, "/api/losses/")
+		if got != ok {
+			t.Errorf("%s: ok %t, want %t", p, got, ok)
+		}
+		if ok && (name != "cat" || id != "1234") {
+			t.Errorf("%s: got %q and %q", p, name, id)
+		}
+	}
+}
+
+func TestAddLog(t *testing.T) {
+	j := &Job{}
+	for i := 0; i < maxLogAttempts+2; i++ {
+		j.addLog(AttemptLog{AttemptID: fmt.Sprint(i), Log: "tail"})
+	}
+	if len(j.Logs) != maxLogAttempts || j.Logs[0].AttemptID != "2" {
+		t.Fatalf("kept %d logs from attempt %s, want the last %d", len(j.Logs), j.Logs[0].AttemptID, maxLogAttempts)
+	}
+
+	//The full log replaces the tail sent with the failure
+	j.addLog(AttemptLog{AttemptID: "5", Log: strings.Repeat("x", maxLogSize+10)})
+	if len(j.Logs) != maxLogAttempts {
+		t.Errorf("log of a known attempt added again")
+	}
+	if !j.hasLog("5") || j.hasLog("1") {
+		t.Errorf("wrong attempts kept")
+	}
+	for _, l := range j.Logs {
+		if l.AttemptID == "5" && (len(l.Log) != maxLogSize || !l.Truncated) {
+			t.Errorf("stored %d bytes, truncated %t, want it cut to %d", len(l.Log), l.Truncated, maxLogSize)
+		}
+	}
+}
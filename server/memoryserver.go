@@ -2,36 +2,48 @@ package server
 
 import (
 	"fmt"
+	"image"
+	"image/gif"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/mgilbir/neural-style-art-project/pb"
 	_ "github.com/mgilbir/neural-style-art-project/server/statik"
-	"github.com/nu7hatch/gouuid"
+	"github.com/mgilbir/neural-style-art-project/upload"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 type memoryServer struct {
 	PendingJobs    map[jobKey]*Job
 	InProgressJobs map[jobKey]*Job
 	CompletedJobs  map[jobKey]*Job
-	Styles         map[string][]byte
+	FailedJobs     map[jobKey]*Job
+	ParentJobs     map[jobKey]*Job
+	Workers        map[string]*Worker
+	Styles         map[string]checkedImage
 	OutputDir      string
+	Normalizer     Normalizer
 	lock           sync.RWMutex
 }
 
-func NewMemoryServer(outputDir string) (Server, error) {
+func NewMemoryServer(outputDir string, normalizer Normalizer) (Server, error) {
 	return &memoryServer{
 		PendingJobs:    make(map[jobKey]*Job),
 		InProgressJobs: make(map[jobKey]*Job),
 		CompletedJobs:  make(map[jobKey]*Job),
-		Styles:         make(map[string][]byte),
+		FailedJobs:     make(map[jobKey]*Job),
+		ParentJobs:     make(map[jobKey]*Job),
+		Workers:        make(map[string]*Worker),
+		Styles:         make(map[string]checkedImage),
 		OutputDir:      outputDir,
+		Normalizer:     normalizer,
 	}, nil
 }
 
@@ -41,11 +53,16 @@ func (s *memoryServer) LoadStyle(filename string) error {
 		return err
 	}
 
-	style, err := ioutil.ReadAll(f)
+	b, err := ioutil.ReadAll(f)
 	if err != nil {
 		return err
 	}
 
+	style, err := checkImage("style", b)
+	if err != nil {
+		return fmt.Errorf("Style %q: %v", filename, err)
+	}
+
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -57,9 +74,52 @@ func (s *memoryServer) LoadStyle(filename string) error {
 }
 
 func (s *memoryServer) CreateJob(ctx context.Context, in *pb.CreateJobRequest) (*pb.CreateJobResponse, error) {
+	if err := checkOutputFormat(in.Params); err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+	if err := checkTiling(in.Params); err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+	if err := checkStages(in.Params); err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+	if err := checkSweep(in.Params); err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+	if err := checkPostProcess(in.Params.GetPostProcess()); err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+
+	checked, err := checkImage("content", imageData(in.Content))
+	if err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+
+	size := s.Normalizer.imageSize(in.Params)
+	content, err := s.prepareInput(checked, size)
+	if err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+
+	mask, err := s.prepareMask(in.Mask, size)
+	if err != nil {
+		return &pb.CreateJobResponse{}, err
+	}
+
+	s.lock.RLock()
+	styles := make(map[string]checkedImage, len(s.Styles))
+	for name, style := range s.Styles {
+		styles[name] = style
+	}
+	s.lock.RUnlock()
+
+	for styleName, checked := range styles {
+		style, err := s.prepareInput(checked, s.Normalizer.styleSize(in.Params))
+		if err != nil {
+			return &pb.CreateJobResponse{}, err
+		}
 
-	for styleName, style := range s.Styles {
-		err := s.createJob(ctx, in.Name, styleName, style, in.Content.Image)
+		err = s.createJob(ctx, in.Name, styleName, style, content, mask, in.Params)
 		if err != nil {
 			return &pb.CreateJobResponse{}, err
 		}
@@ -69,43 +129,268 @@ func (s *memoryServer) CreateJob(ctx context.Context, in *pb.CreateJobRequest) (
 }
 
 func (s *memoryServer) CreateFullJob(ctx context.Context, in *pb.CreateFullJobRequest) (*pb.CreateFullJobResponse, error) {
-	err := s.createJob(ctx, in.Name, in.Style.Title, in.Style.Image, in.Content.Image)
+	if err := checkOutputFormat(in.Params); err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+	if err := checkTiling(in.Params); err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+	if err := checkStages(in.Params); err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+	if err := checkSweep(in.Params); err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+	if err := checkPostProcess(in.Params.GetPostProcess()); err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+
+	checkedStyle, err := checkImage("style", imageData(in.Style))
+	if err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+
+	checkedContent, err := checkImage("content", imageData(in.Content))
+	if err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+
+	style, err := s.prepareInput(checkedStyle, s.Normalizer.styleSize(in.Params))
+	if err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+
+	content, err := s.prepareInput(checkedContent, s.Normalizer.imageSize(in.Params))
+	if err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+
+	mask, err := s.prepareMask(in.Mask, s.Normalizer.imageSize(in.Params))
+	if err != nil {
+		return &pb.CreateFullJobResponse{}, err
+	}
+
+	err = s.createJob(ctx, in.Name, in.Style.Title, style, content, mask, in.Params)
 	return &pb.CreateFullJobResponse{}, err
 }
 
-func (s *memoryServer) createJob(ctx context.Context, name string, styleName string, styleImage []byte, contentImage []byte) error {
+// UploadJob creates a job whose images come in chunks after the job itself
+func (s *memoryServer) UploadJob(stream pb.NeuralStyleImager_UploadJobServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	in := first.Job
+	if in == nil || in.Content == nil {
+		return grpc.Errorf(codes.InvalidArgument, "Upload must start with the job")
+	}
+
+	images, err := receiveChunks(func() (*pb.ImageChunk, error) {
+		m, err := stream.Recv()
+		return m.GetChunk(), err
+	})
+	if err != nil {
+		return err
+	}
+
+	in.Content.Image, err = receivedImage(images, pb.ImagePart_PART_CONTENT, in.Content.Digest)
+	if err != nil {
+		return err
+	}
+
+	if in.Mask != nil {
+		in.Mask.Image, err = receivedImage(images, pb.ImagePart_PART_MASK, in.Mask.Digest)
+		if err != nil {
+			return err
+		}
+	}
+
+	//Without a style the job is created for every style, like CreateJob
+	if in.Style == nil {
+		_, err = s.CreateJob(stream.Context(), &pb.CreateJobRequest{
+			Name:    in.Name,
+			Content: in.Content,
+			Params:  in.Params,
+			Mask:    in.Mask,
+		})
+	} else {
+		in.Style.Image, err = receivedImage(images, pb.ImagePart_PART_STYLE, in.Style.Digest)
+		if err != nil {
+			return err
+		}
+		_, err = s.CreateFullJob(stream.Context(), in)
+	}
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(&pb.CreateJobResponse{})
+}
+
+// receiveChunks collects the image chunks of an upload until the client is
+// done sending
+func receiveChunks(recv func() (*pb.ImageChunk, error)) (*upload.Buffer, error) {
+	images := upload.NewBuffer()
+	for {
+		c, err := recv()
+		if err == io.EOF {
+			return images, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := images.Add(c); err != nil {
+			return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
+}
+
+// receivedImage checks a streamed image against its digest. A mismatch is
+// reported as data loss so the sender can try again.
+func receivedImage(images *upload.Buffer, part pb.ImagePart, digest string) ([]byte, error) {
+	b, err := images.Image(part, digest)
+	if _, ok := err.(*upload.DigestError); ok {
+		return nil, grpc.Errorf(codes.DataLoss, "%v", err)
+	}
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return b, nil
+}
+
+// jobInput is an image of a job as submitted and as handed to the engine
+type jobInput struct {
+	original   checkedImage
+	normalized checkedImage
+}
+
+// prepareInput normalises a checked image for a job rendering at size
+func (s *memoryServer) prepareInput(img checkedImage, size int) (jobInput, error) {
+	normalized, err := s.Normalizer.normalize(img, size)
+	if grpc.Code(err) == codes.InvalidArgument {
+		return jobInput{}, err
+	}
+	if err != nil {
+		return jobInput{}, grpc.Errorf(codes.Internal, "Could not normalise image: %v", err)
+	}
+	return jobInput{original: img, normalized: normalized}, nil
+}
+
+// prepareMask normalises the mask of a job, if it has one, like its content
+// so they stay aligned
+func (s *memoryServer) prepareMask(in *pb.InputImage, size int) ([]byte, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	checked, err := checkImage("mask", imageData(in))
+	if err != nil {
+		return nil, err
+	}
+
+	mask, err := s.prepareInput(checked, size)
+	if err != nil {
+		return nil, err
+	}
+	return mask.normalized.Data, nil
+}
+
+func (s *memoryServer) createJob(ctx context.Context, name string, styleName string, style jobInput, content jobInput, mask []byte, params *pb.JobParameters) error {
+	//The engine renders at the size the inputs were scaled to
+	jobParams := pb.JobParameters{}
+	if params != nil {
+		jobParams = *params
+	}
+	jobParams.ImageSize = int32(s.Normalizer.imageSize(params))
+
+	job := newJob(name, styleName, style, content, &jobParams)
+	job.Mask = mask
+
+	//Animated GIFs are rendered a frame at a time
+	if content.original.Format == pb.ImageFormat_GIF {
+		frames, g, err := decodeFrames(content.original.Data)
+		if err != nil {
+			return grpc.Errorf(codes.InvalidArgument, "The content animation can't be read: %v", err)
+		}
+		if len(frames) > 1 {
+			if size, _ := tileSize(params); size > 0 || len(jobParams.Stages) > 0 || jobParams.Sweep != nil || postProcesses(jobParams.PostProcess) || mask != nil {
+				return grpc.Errorf(codes.InvalidArgument, "Animated jobs can't be tiled, staged, swept, post-processed or masked")
+			}
+			return s.createAnimatedJob(job, frames, g)
+		}
+	}
+
+	//Staged jobs are only rendered through their stages
+	if len(jobParams.Stages) > 0 {
+		return s.createStagedJob(job)
+	}
+
+	//Sweeps are only rendered through the jobs of their grid
+	if len(jobParams.GetSweep().GetAxes()) > 0 {
+		if mask != nil {
+			return grpc.Errorf(codes.InvalidArgument, "Sweeps can't be masked")
+		}
+		return s.createSweepJob(job)
+	}
+
+	//Tiled jobs are only rendered through their tiles
+	if size, overlap := tileSize(params); size > 0 {
+		tiling := splitTiles(job.Width, job.Height, size, overlap)
+		if len(tiling.Rects) > 1 {
+			return s.createTiledJob(job, tiling, style, content)
+		}
+	}
+
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	id, err := uuid.NewV4()
+	_, err := s.addJob(s.PendingJobs, job)
+	return err
+}
+
+// newJob builds a job for its inputs as the engine will see them
+func newJob(name string, styleName string, style jobInput, content jobInput, params *pb.JobParameters) *Job {
+	return &Job{
+		Name:            name,
+		StyleName:       styleName,
+		StyleImage:      style.normalized.Data,
+		StyleDigest:     upload.Digest(style.normalized.Data),
+		StyleFormat:     style.normalized.Format,
+		OriginalStyle:   style.original.Data,
+		ContentImage:    content.normalized.Data,
+		ContentFormat:   content.normalized.Format,
+		OriginalContent: content.original.Data,
+		Width:           content.normalized.Width,
+		Height:          content.normalized.Height,
+		Params:          params,
+		PartialResults:  make([]PartialResult, 0),
+		LastUpdated:     time.Now(),
+	}
+}
+
+// addJob stores a job under a new ID and saves its images. The lock must be
+// held.
+func (s *memoryServer) addJob(jobs map[jobKey]*Job, job *Job) (jobKey, error) {
+	idStr, err := newID()
 	if err != nil {
-		return err
+		return jobKey{}, err
 	}
 
-	idStr := strings.Replace(id.String(), "-", "", -1)
 	key := jobKey{
 		ID:        idStr,
-		Name:      name,
+		Name:      job.Name,
 		Completed: false,
 	}
 
-	job := Job{
-		Name:           name,
-		StyleName:      styleName,
-		StyleImage:     styleImage,
-		ContentImage:   contentImage,
-		PartialResults: make([][]byte, 0),
-		LastUpdated:    time.Now(),
-	}
-
-	s.PendingJobs[key] = &job
-	log.Printf("Added job with id: %q for name: %q and style: %q\n", key.ID, key.Name, styleName)
+	jobs[key] = job
+	log.Printf("Added job with id: %q for name: %q and style: %q (%dx%d)\n", key.ID, key.Name, job.StyleName, job.Width, job.Height)
 
 	//Save the data locally
 	jobDir := getDirectory(s.OutputDir, key.ID, key.Name)
 
 	//TODO: cleanup
-	styleFilename, err := prepareFilename(jobDir, job.StyleName+".jpg")
+	styleFilename, err := prepareFilename(jobDir, job.StyleName+formatExtension(job.StyleFormat))
 	if err != nil {
 		log.Println(err)
 	}
@@ -114,7 +399,7 @@ func (s *memoryServer) createJob(ctx context.Context, name string, styleName str
 		log.Println(err)
 	}
 
-	contentFilename, err := prepareFilename(jobDir, job.Name+".jpg")
+	contentFilename, err := prepareFilename(jobDir, job.Name+formatExtension(job.ContentFormat))
 	if err != nil {
 		log.Println(err)
 	}
@@ -123,6 +408,263 @@ func (s *memoryServer) createJob(ctx context.Context, name string, styleName str
 		log.Println(err)
 	}
 
+	if job.Mask != nil {
+		maskFilename, err := prepareFilename(jobDir, "mask"+formatExtension(sniffFormat(job.Mask)))
+		if err != nil {
+			log.Println(err)
+		}
+		err = ioutil.WriteFile(maskFilename, job.Mask, 0755)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	//Keep the images as submitted next to the normalised ones
+	if len(s.Normalizer.Steps) > 0 && job.OriginalContent != nil {
+		originals := map[string][]byte{
+			"original_" + job.StyleName + formatExtension(sniffFormat(job.OriginalStyle)): job.OriginalStyle,
+			"original_" + job.Name + formatExtension(sniffFormat(job.OriginalContent)):    job.OriginalContent,
+		}
+		for name, b := range originals {
+			filename, err := prepareFilename(jobDir, name)
+			if err != nil {
+				log.Println(err)
+			}
+			err = ioutil.WriteFile(filename, b, 0755)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	return key, nil
+}
+
+// createStagedJob queues the first stage of a job. The job itself waits for
+// the last one to complete.
+func (s *memoryServer) createStagedJob(parent *Job) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parent.Stages = parent.Params.Stages
+	parentKey, err := s.addJob(s.ParentJobs, parent)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Rendering id: %q - %q in %d stages", parentKey.ID, parentKey.Name, len(parent.Stages))
+	return s.addStage(parentKey, parent, nil)
+}
+
+// addStage queues the next stage of a staged job, starting from the result
+// of the previous one. The lock must be held.
+func (s *memoryServer) addStage(parentKey jobKey, parent *Job, init []byte) error {
+	i := len(parent.Children)
+	stage := parent.Stages[i]
+
+	params := *parent.Params
+	params.Stages = nil
+	params.ImageSize = stage.ImageSize
+	if stage.NumIterations > 0 {
+		params.NumIterations = stage.NumIterations
+	}
+
+	width, height := scaledSize(parent.Width, parent.Height, int(stage.ImageSize))
+	job := &Job{
+		Name:           fmt.Sprintf("%s_stage_%d", parent.Name, i),
+		StyleName:      parent.StyleName,
+		StyleImage:     parent.StyleImage,
+		StyleDigest:    parent.StyleDigest,
+		StyleFormat:    parent.StyleFormat,
+		ContentImage:   parent.ContentImage,
+		ContentFormat:  parent.ContentFormat,
+		Width:          width,
+		Height:         height,
+		Params:         &params,
+		InitImage:      init,
+		PartialResults: make([]PartialResult, 0),
+		LastUpdated:    time.Now(),
+		Parent:         &parentKey,
+	}
+
+	key, err := s.addJob(s.PendingJobs, job)
+	if err != nil {
+		return err
+	}
+	parent.Children = append(parent.Children, key)
+	return nil
+}
+
+// createAnimatedJob queues a job for each frame of the content, or only for
+// the first one if frames start from the previous result. The job itself
+// waits for them all to put the animation back together.
+func (s *memoryServer) createAnimatedJob(parent *Job, frames []image.Image, g *gif.GIF) error {
+	anim := &Animation{
+		Delays:    g.Delay,
+		LoopCount: g.LoopCount,
+	}
+	if a := parent.Params.GetAnimation(); a != nil {
+		anim.Chained = a.ChainFrames
+	}
+
+	//Normalise the frames before taking the lock, it takes a while
+	size := int(parent.Params.ImageSize)
+	for i, frame := range frames {
+		b := frame.Bounds()
+		normalized, err := s.Normalizer.normalize(checkedImage{
+			Format:  pb.ImageFormat_GIF,
+			Width:   b.Dx(),
+			Height:  b.Dy(),
+			Decoded: frame,
+		}, size)
+		if err != nil {
+			return grpc.Errorf(codes.Internal, "Could not normalise frame %d: %v", i, err)
+		}
+		anim.Frames = append(anim.Frames, normalized)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parent.Animation = anim
+	parent.Params.OutputFormat = pb.ImageFormat_GIF
+	parentKey, err := s.addJob(s.ParentJobs, parent)
+	if err != nil {
+		return err
+	}
+
+	queued := len(anim.Frames)
+	if anim.Chained {
+		queued = 1
+	}
+	for i := 0; i < queued; i++ {
+		err := s.addFrame(parentKey, parent, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Rendering id: %q - %q as %d frames", parentKey.ID, parentKey.Name, len(anim.Frames))
+	return nil
+}
+
+// addFrame queues the next frame of an animated job. The lock must be held.
+func (s *memoryServer) addFrame(parentKey jobKey, parent *Job, init []byte) error {
+	i := len(parent.Children)
+
+	params := *parent.Params
+	params.Animation = nil
+	params.OutputFormat = pb.ImageFormat_PNG
+
+	style := checkedImage{Data: parent.StyleImage, Format: parent.StyleFormat}
+	name := fmt.Sprintf("%s_frame_%d", parent.Name, i)
+	job := newJob(name, parent.StyleName, jobInput{normalized: style}, jobInput{normalized: parent.Animation.Frames[i]}, &params)
+	job.InitImage = init
+	job.Parent = &parentKey
+
+	key, err := s.addJob(s.PendingJobs, job)
+	if err != nil {
+		return err
+	}
+	parent.Children = append(parent.Children, key)
+	return nil
+}
+
+// createSweepJob queues a job for each point of the grid of a sweep. The job
+// itself waits for them all to lay their results out in a contact sheet.
+func (s *memoryServer) createSweepJob(parent *Job) error {
+	axes := parent.Params.Sweep.Axes
+	columns := sweepValues(axes[0])
+	rows := []float64{0}
+	if len(axes) > 1 {
+		rows = sweepValues(axes[1])
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parent.Sweep = &Sweep{Columns: len(columns)}
+	parentKey, err := s.addJob(s.ParentJobs, parent)
+	if err != nil {
+		return err
+	}
+
+	style := checkedImage{Data: parent.StyleImage, Format: parent.StyleFormat}
+	content := checkedImage{Data: parent.ContentImage, Format: parent.ContentFormat, Width: parent.Width, Height: parent.Height}
+	for _, row := range rows {
+		for _, column := range columns {
+			params, label := sweepParams(parent.Params, axes, []float64{column, row})
+
+			name := fmt.Sprintf("%s_sweep_%d", parent.Name, len(parent.Children))
+			job := newJob(name, parent.StyleName, jobInput{normalized: style}, jobInput{normalized: content}, params)
+			job.Parent = &parentKey
+
+			key, err := s.addJob(s.PendingJobs, job)
+			if err != nil {
+				return err
+			}
+			parent.Children = append(parent.Children, key)
+			parent.Sweep.Labels = append(parent.Sweep.Labels, label)
+		}
+	}
+
+	log.Printf("Sweeping id: %q - %q over %d jobs", parentKey.ID, parentKey.Name, len(parent.Children))
+	return nil
+}
+
+// createTiledJob queues a job for each tile of the content. The job itself
+// waits until they all complete to stitch their results together.
+func (s *memoryServer) createTiledJob(parent *Job, tiling *Tiling, style jobInput, content jobInput) error {
+	if len(tiling.Rects) > maxTiles {
+		return grpc.Errorf(codes.InvalidArgument, "The job needs %d tiles, at most %d are allowed", len(tiling.Rects), maxTiles)
+	}
+
+	//Cut the tiles before taking the lock, encoding them takes a while
+	tiles := make([]*Job, len(tiling.Rects))
+	for i, r := range tiling.Rects {
+		img := crop(content.normalized.Decoded, r)
+		data, err := encodeImage(img, pb.ImageFormat_PNG)
+		if err != nil {
+			return grpc.Errorf(codes.Internal, "Could not cut tile %d: %v", i, err)
+		}
+		tile := checkedImage{
+			Data:    data,
+			Format:  pb.ImageFormat_PNG,
+			Width:   r.Dx(),
+			Height:  r.Dy(),
+			Decoded: img,
+		}
+
+		params := *parent.Params
+		params.Tiling = nil
+		params.ImageSize = int32(r.Dx())
+		if r.Dy() > r.Dx() {
+			params.ImageSize = int32(r.Dy())
+		}
+
+		name := fmt.Sprintf("%s_tile_%d", parent.Name, i)
+		tiles[i] = newJob(name, parent.StyleName, jobInput{normalized: style.normalized}, jobInput{normalized: tile}, &params)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	parent.Tiling = tiling
+	parentKey, err := s.addJob(s.ParentJobs, parent)
+	if err != nil {
+		return err
+	}
+
+	for _, tile := range tiles {
+		tile.Parent = &parentKey
+		key, err := s.addJob(s.PendingJobs, tile)
+		if err != nil {
+			return err
+		}
+		parent.Children = append(parent.Children, key)
+	}
+
+	log.Printf("Split id: %q - %q into %d tiles", parentKey.ID, parentKey.Name, len(tiles))
 	return nil
 }
 
@@ -134,6 +676,30 @@ func (s *memoryServer) RequestJob(ctx context.Context, in *pb.JobRequest) (*pb.J
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	//Resuming doesn't keep a worker from taking work, it goes with the job
+	resume := pb.WorkerCommand_COMMAND_NONE
+
+	s.requeueOffline(time.Now())
+
+	worker, known := s.Workers[in.WorkerId]
+	if known {
+		worker.LastSeen = time.Now()
+
+		//Don't hand out work to a worker that is being told to stop
+		switch command := worker.takeCommand(); command {
+		case pb.WorkerCommand_COMMAND_NONE:
+		case pb.WorkerCommand_COMMAND_RESUME:
+			resume = command
+		default:
+			return &pb.Job{Command: command}, nil
+		}
+
+		//Nor to one that can't run it
+		if worker.Problem != "" {
+			return &pb.Job{Command: resume}, nil
+		}
+	}
+
 	var k jobKey
 	var v *Job
 
@@ -144,27 +710,72 @@ func (s *memoryServer) RequestJob(ctx context.Context, in *pb.JobRequest) (*pb.J
 
 	if v == nil {
 		//No items pending
-		return &pb.Job{}, nil
+		return &pb.Job{Command: resume}, nil
+	}
+
+	attemptID, err := newID()
+	if err != nil {
+		return &pb.Job{}, err
 	}
 
 	v.LastUpdated = time.Now()
+	v.WorkerID = in.WorkerId
+	v.WorkerSlot = in.Slot
+	v.AttemptID = attemptID
+	v.AttemptStarted = v.LastUpdated
+	v.LastSequence = 0
 	s.InProgressJobs[k] = v
 	delete(s.PendingJobs, k)
 
-	return &pb.Job{
-		Id:   k.ID,
-		Name: k.Name,
+	if known {
+		worker.assign(in.Slot, k.ID, k.Name)
+	}
+
+	job := &pb.Job{
+		Id:        k.ID,
+		Name:      k.Name,
+		AttemptId: attemptID,
 		Style: &pb.InputImage{
 			Title:  v.StyleName,
-			Format: pb.ImageFormat_JPG,
+			Format: v.StyleFormat,
 			Image:  v.StyleImage,
 		},
 		Content: &pb.InputImage{
 			Title:  v.Name,
-			Format: pb.ImageFormat_JPG,
+			Format: v.ContentFormat,
 			Image:  v.ContentImage,
 		},
-	}, nil
+		Params:  v.Params,
+		Command: resume,
+	}
+
+	//Workers that have the style cached don't need it again
+	job.Style.Digest = v.StyleDigest
+	for _, d := range in.CachedDigests {
+		if d == v.StyleDigest {
+			job.Style.Image = nil
+			break
+		}
+	}
+
+	//Continue an interrupted render where it was left
+	if partial := v.latestPartial(); partial != nil {
+		job.Init = &pb.InputImage{
+			Title:  fmt.Sprintf("%s_%d", v.Name, partial.Iteration),
+			Format: pb.ImageFormat_PNG,
+			Image:  partial.Image,
+		}
+		job.StartIteration = partial.Iteration
+		log.Printf("Resuming id: %q - %q from iteration %d", k.ID, k.Name, partial.Iteration)
+	} else if len(v.InitImage) > 0 {
+		job.Init = &pb.InputImage{
+			Title:  v.Name + "_init",
+			Format: pb.ImageFormat_PNG,
+			Image:  v.InitImage,
+		}
+	}
+
+	return job, nil
 }
 
 func (s *memoryServer) AcknowledgeJob(ctx context.Context, in *pb.JobAck) (*pb.JobAck, error) {
@@ -186,25 +797,36 @@ func (s *memoryServer) ProgressReport(ctx context.Context, in *pb.JobResult) (*p
 
 	v, ok := s.InProgressJobs[key]
 	if !ok {
-		return &pb.JobProgressResponse{}, fmt.Errorf("Key with ID %q not found\n", in.Id)
+		return &pb.JobProgressResponse{}, grpc.Errorf(codes.NotFound, "Key with ID %q not found", in.Id)
 	}
 
-	v.LastUpdated = time.Now()
-	v.PartialResults = append(v.PartialResults, in.Image)
-
-	log.Printf("Received progress on id: %q - %q: %d iterations", key.ID, key.Name, in.ProgressCount)
+	if !v.isCurrentAttempt(in.AttemptId) {
+		return &pb.JobProgressResponse{}, grpc.Errorf(codes.FailedPrecondition, "Stale attempt %q for ID %q", in.AttemptId, in.Id)
+	}
 
-	jobDir := getDirectory(s.OutputDir, key.ID, key.Name)
+	if v.isDuplicate(in.Sequence) {
+		log.Printf("Ignoring duplicate progress on id: %q - %q: %d iterations", key.ID, key.Name, in.ProgressCount)
+		return &pb.JobProgressResponse{}, nil
+	}
 
-	//Save to file
-	progressFilename, err := prepareFilename(jobDir, fmt.Sprintf("result_%d.png", in.ProgressCount))
-	if err != nil {
-		log.Println(err)
+	v.LastUpdated = time.Now()
+	if in.Sequence > v.LastSequence {
+		v.LastSequence = in.Sequence
 	}
-	err = ioutil.WriteFile(progressFilename, in.Image, 0755)
-	if err != nil {
-		log.Println(err)
+	v.addLosses(in.Losses)
+
+	added := v.addPartial(PartialResult{
+		Iteration: in.ProgressCount,
+		Image:     in.Image,
+	})
+	if !added {
+		log.Printf("Ignoring repeated progress on id: %q - %q: %d iterations", key.ID, key.Name, in.ProgressCount)
+		return &pb.JobProgressResponse{}, nil
 	}
+
+	log.Printf("Received progress on id: %q - %q: %d iterations", key.ID, key.Name, in.ProgressCount)
+
+	s.saveResult(key, v, fmt.Sprintf("result_%d", in.ProgressCount), in.Image)
 	return &pb.JobProgressResponse{}, nil
 }
 
@@ -220,31 +842,383 @@ func (s *memoryServer) CompleteJob(ctx context.Context, in *pb.JobResult) (*pb.J
 
 	v, ok := s.InProgressJobs[key]
 	if !ok {
-		return &pb.JobResultResponse{}, fmt.Errorf("Key with ID %q not found\n", in.Id)
+		//Completing twice is harmless as long as it's the same attempt
+		key.Completed = true
+		if v, ok := s.CompletedJobs[key]; ok {
+			if !v.isCurrentAttempt(in.AttemptId) {
+				return &pb.JobResultResponse{}, grpc.Errorf(codes.FailedPrecondition, "Stale attempt %q for ID %q", in.AttemptId, in.Id)
+			}
+			log.Printf("Ignoring repeated completion of id: %q - %q", key.ID, key.Name)
+			return &pb.JobResultResponse{}, nil
+		}
+		return &pb.JobResultResponse{}, grpc.Errorf(codes.NotFound, "Key with ID %q not found", in.Id)
+	}
+
+	if !v.isCurrentAttempt(in.AttemptId) {
+		return &pb.JobResultResponse{}, grpc.Errorf(codes.FailedPrecondition, "Stale attempt %q for ID %q", in.AttemptId, in.Id)
 	}
 
 	v.Result = in.Image
+	v.Annotation = in.Annotation
 	v.LastUpdated = time.Now()
+	if in.Sequence > v.LastSequence {
+		v.LastSequence = in.Sequence
+	}
+	v.addLosses(in.Losses)
+
+	if worker, ok := s.Workers[v.WorkerID]; ok {
+		worker.JobsCompleted++
+		worker.release(v.WorkerSlot)
+	}
 
 	delete(s.InProgressJobs, key)
 	key.Completed = true
 	s.CompletedJobs[key] = v
 
-	log.Printf("Completed id: %q - %q", key.ID, key.Name)
+	if in.Annotation != "" {
+		log.Printf("Completed id: %q - %q: %s", key.ID, key.Name, in.Annotation)
+	} else {
+		log.Printf("Completed id: %q - %q", key.ID, key.Name)
+	}
+
+	s.saveResult(key, v, "result", in.Image)
+
+	if v.Parent != nil {
+		s.childCompleted(*v.Parent, v)
+	} else {
+		go s.saveTimelapse(key)
+		go s.postProcessResult(key)
+	}
+
+	return &pb.JobResultResponse{}, nil
+}
 
+// saveResult writes a result of a job to its directory in the format the job
+// asked for
+func (s *memoryServer) saveResult(key jobKey, v *Job, name string, image []byte) {
 	jobDir := getDirectory(s.OutputDir, key.ID, key.Name)
 
-	//Save to file
-	progressFilename, err := prepareFilename(jobDir, "result.png")
+	format := outputFormat(v.Params)
+	filename, err := prepareFilename(jobDir, name+formatExtension(format))
+	if err != nil {
+		log.Println(err)
+	}
+	b, err := transcode(image, format)
 	if err != nil {
 		log.Println(err)
 	}
-	err = ioutil.WriteFile(progressFilename, in.Image, 0755)
+	err = ioutil.WriteFile(filename, b, 0755)
 	if err != nil {
 		log.Println(err)
 	}
+}
 
-	return &pb.JobResultResponse{}, nil
+// childCompleted moves a job rendered through jobs of its own along when one
+// of them completes. The lock must be held.
+func (s *memoryServer) childCompleted(parentKey jobKey, child *Job) {
+	parent, ok := s.ParentJobs[parentKey]
+	if !ok {
+		return
+	}
+
+	switch {
+	case parent.Tiling != nil:
+		s.partCompleted(parentKey, parent, stitchResult)
+	case parent.Sweep != nil:
+		s.partCompleted(parentKey, parent, sweepResult)
+	case len(parent.Stages) > 0:
+		s.stageCompleted(parentKey, parent, child)
+	case parent.Animation != nil:
+		s.frameCompleted(parentKey, parent, child)
+	}
+}
+
+// childFailed fails a job rendered through jobs of its own when one of them
+// can't be rendered. The lock must be held.
+func (s *memoryServer) childFailed(parentKey jobKey, child *Job, reason string) {
+	parent, ok := s.ParentJobs[parentKey]
+	if !ok {
+		return
+	}
+
+	//The rest of the parts are useless without this one
+	for _, k := range parent.Children {
+		if v, ok := s.PendingJobs[k]; ok {
+			v.FailureReason = "Another part of the job failed"
+			delete(s.PendingJobs, k)
+			s.FailedJobs[k] = v
+		}
+	}
+
+	s.failParent(parentKey, parent, fmt.Sprintf("%q failed: %s", child.Name, reason))
+}
+
+// failParent moves a job rendered through jobs of its own to the failed
+// ones. The lock must be held.
+func (s *memoryServer) failParent(key jobKey, parent *Job, reason string) {
+	parent.FailureReason = reason
+	parent.LastUpdated = time.Now()
+	delete(s.ParentJobs, key)
+	s.FailedJobs[key] = parent
+
+	log.Printf("Failed id: %q - %q: %s", key.ID, key.Name, reason)
+}
+
+// stageCompleted queues the next stage of a staged job from the result of
+// the one that completed, or completes the job after the last one. The lock
+// must be held.
+func (s *memoryServer) stageCompleted(key jobKey, parent *Job, stage *Job) {
+	if len(parent.Children) < len(parent.Stages) {
+		err := s.addStage(key, parent, stage.Result)
+		if err != nil {
+			s.failParent(key, parent, fmt.Sprintf("Could not queue stage %d: %v", len(parent.Children), err))
+		}
+		return
+	}
+
+	parent.Result = stage.Result
+	parent.Annotation = stage.Annotation
+	parent.LastUpdated = time.Now()
+	delete(s.ParentJobs, key)
+	key.Completed = true
+	s.CompletedJobs[key] = parent
+	log.Printf("Completed id: %q - %q after %d stages", key.ID, key.Name, len(parent.Stages))
+
+	s.saveResult(key, parent, "result", parent.Result)
+	go s.saveTimelapse(key)
+	go s.postProcessResult(key)
+}
+
+// frameCompleted queues the next frame of a chained animation from the
+// result of the one that completed, or starts putting the animation together
+// once all frames are done. The lock must be held.
+func (s *memoryServer) frameCompleted(key jobKey, parent *Job, frame *Job) {
+	if parent.Animation.Chained && len(parent.Children) < len(parent.Animation.Frames) {
+		err := s.addFrame(key, parent, frame.Result)
+		if err != nil {
+			s.failParent(key, parent, fmt.Sprintf("Could not queue frame %d: %v", len(parent.Children), err))
+		}
+		return
+	}
+
+	s.partCompleted(key, parent, animationResult)
+}
+
+// childrenCompleted tells whether every job a job is rendered through has
+// completed. The lock must be held.
+func (s *memoryServer) childrenCompleted(parent *Job) bool {
+	for _, k := range parent.Children {
+		k.Completed = true
+		if _, ok := s.CompletedJobs[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// partCompleted starts putting a job together once the last of the jobs it
+// is rendered through completes. The lock must be held.
+func (s *memoryServer) partCompleted(parentKey jobKey, parent *Job, build func(parent *Job, results [][]byte) ([]byte, error)) {
+	if parent.Assembling || !s.childrenCompleted(parent) {
+		return
+	}
+
+	parent.Assembling = true
+	go s.assemble(parentKey, build)
+}
+
+// assemble puts the results of the jobs a job is rendered through together
+// and completes it. That takes a while, so it runs without the lock.
+func (s *memoryServer) assemble(key jobKey, build func(parent *Job, results [][]byte) ([]byte, error)) {
+	s.lock.RLock()
+	parent, ok := s.ParentJobs[key]
+	var results [][]byte
+	if ok {
+		for _, k := range parent.Children {
+			var result []byte
+			if v, ok := s.findJob(k.ID, k.Name); ok {
+				result = v.Result
+			}
+			results = append(results, result)
+		}
+	}
+	s.lock.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	result, err := build(parent, results)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.ParentJobs[key]; !ok {
+		return
+	}
+	parent.Assembling = false
+
+	if err != nil {
+		s.failParent(key, parent, fmt.Sprintf("Could not put the result together: %v", err))
+		return
+	}
+
+	parent.LastUpdated = time.Now()
+	delete(s.ParentJobs, key)
+	parent.Result = result
+	key.Completed = true
+	s.CompletedJobs[key] = parent
+	log.Printf("Completed id: %q - %q from %d parts", key.ID, key.Name, len(results))
+
+	s.saveResult(key, parent, "result", result)
+	if parent.Animation == nil {
+		go s.saveTimelapse(key)
+	}
+	go s.postProcessResult(key)
+}
+
+// stitchResult stitches the results of the tiles of a job
+func stitchResult(parent *Job, tiles [][]byte) ([]byte, error) {
+	stitched, err := parent.Tiling.stitch(parent.Width, parent.Height, tiles)
+	if err != nil {
+		return nil, err
+	}
+	return encodeImage(stitched, pb.ImageFormat_PNG)
+}
+
+// animationResult puts the rendered frames of a job back together
+func animationResult(parent *Job, frames [][]byte) ([]byte, error) {
+	return parent.Animation.encode(frames)
+}
+
+// sweepResult lays the results of a sweep out in a contact sheet
+func sweepResult(parent *Job, results [][]byte) ([]byte, error) {
+	return parent.Sweep.contactSheet(results)
+}
+
+// tileImages returns the latest image of each tile of a job: its result, its
+// latest partial result while it renders, or else its content. The lock must
+// be held.
+func (s *memoryServer) tileImages(parent *Job) [][]byte {
+	images := make([][]byte, len(parent.Children))
+	for i, k := range parent.Children {
+		v, ok := s.findJob(k.ID, k.Name)
+		if !ok {
+			continue
+		}
+
+		switch partial := v.latestPartial(); {
+		case len(v.Result) > 0:
+			images[i] = v.Result
+		case partial != nil:
+			images[i] = partial.Image
+		default:
+			images[i] = v.ContentImage
+		}
+	}
+	return images
+}
+
+// stagePartials returns the partial results of every stage of a job in
+// order. The lock must be held.
+func (s *memoryServer) stagePartials(parent *Job) []PartialResult {
+	var partials []PartialResult
+	for _, k := range parent.Children {
+		if v, ok := s.findJob(k.ID, k.Name); ok {
+			partials = append(partials, v.PartialResults...)
+		}
+	}
+	return partials
+}
+
+// stageProgress returns the stage a job is at, counting from 1, and the
+// iterations that stage has reached. The lock must be held.
+func (s *memoryServer) stageProgress(parent *Job) (int, int32) {
+	if len(parent.Children) == 0 {
+		return 0, 0
+	}
+
+	k := parent.Children[len(parent.Children)-1]
+	v, ok := s.findJob(k.ID, k.Name)
+	if !ok {
+		return len(parent.Children), 0
+	}
+
+	var iteration int32
+	if partial := v.latestPartial(); partial != nil {
+		iteration = partial.Iteration
+	}
+	return len(parent.Children), iteration
+}
+
+// childrenProgress counts the completed parts of a job rendered through jobs
+// of its own and the iterations all of them have reached. The lock must be
+// held.
+func (s *memoryServer) childrenProgress(parent *Job) (int, int32) {
+	completed := 0
+	var iteration int32 = -1
+	for _, k := range parent.Children {
+		v, ok := s.findJob(k.ID, k.Name)
+		if !ok {
+			continue
+		}
+
+		k.Completed = true
+		if _, ok := s.CompletedJobs[k]; ok {
+			completed++
+		}
+
+		var reached int32
+		if partial := v.latestPartial(); partial != nil {
+			reached = partial.Iteration
+		}
+		if iteration < 0 || reached < iteration {
+			iteration = reached
+		}
+	}
+
+	if iteration < 0 {
+		iteration = 0
+	}
+	return completed, iteration
+}
+
+// UploadResult takes a progress report or completion whose image comes in
+// chunks after the result itself
+func (s *memoryServer) UploadResult(stream pb.NeuralStyleWorker_UploadResultServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	in := first.Result
+	if in == nil {
+		return grpc.Errorf(codes.InvalidArgument, "Upload must start with the result")
+	}
+
+	images, err := receiveChunks(func() (*pb.ImageChunk, error) {
+		m, err := stream.Recv()
+		return m.GetChunk(), err
+	})
+	if err != nil {
+		return err
+	}
+
+	in.Image, err = receivedImage(images, pb.ImagePart_PART_RESULT, in.Digest)
+	if err != nil {
+		return err
+	}
+
+	if first.Complete {
+		_, err = s.CompleteJob(stream.Context(), in)
+	} else {
+		_, err = s.ProgressReport(stream.Context(), in)
+	}
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(&pb.JobResultResponse{})
 }
 
 func (s *memoryServer) FailJob(ctx context.Context, in *pb.JobFail) (*pb.JobFail, error) {
@@ -259,59 +1233,680 @@ func (s *memoryServer) FailJob(ctx context.Context, in *pb.JobFail) (*pb.JobFail
 
 	v, ok := s.InProgressJobs[key]
 	if !ok {
-		return &pb.JobFail{}, fmt.Errorf("Key with ID %q not found\n", in.Id)
+		return &pb.JobFail{}, grpc.Errorf(codes.NotFound, "Key with ID %q not found", in.Id)
+	}
+
+	//Don't let an old attempt requeue a job that is running elsewhere
+	if !v.isCurrentAttempt(in.AttemptId) {
+		return &pb.JobFail{}, grpc.Errorf(codes.FailedPrecondition, "Stale attempt %q for ID %q", in.AttemptId, in.Id)
 	}
 
 	v.LastUpdated = time.Now()
 
-	s.PendingJobs[key] = v
+	if worker, ok := s.Workers[v.WorkerID]; ok {
+		worker.JobsFailed++
+		worker.release(v.WorkerSlot)
+	}
+	//Keep the tail until the worker uploads the whole log
+	if in.LogTail != "" && !v.hasLog(in.AttemptId) {
+		v.addLog(AttemptLog{
+			AttemptID: in.AttemptId,
+			WorkerID:  v.WorkerID,
+			Log:       in.LogTail,
+			Truncated: true,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	v.WorkerID = ""
+	v.FailureReason = in.Reason
+
+	//Stop retrying a job that keeps failing on its own account
+	permanent := in.Permanent
+	if !in.Preempted {
+		v.Failures++
+		if in.TimedOut {
+			v.Timeouts++
+		}
+		switch {
+		case permanent:
+		case v.Timeouts >= MaxTimeouts:
+			permanent = true
+			v.FailureReason = fmt.Sprintf("%s (timed out %d times)", in.Reason, v.Timeouts)
+		case v.Failures >= MaxFailures:
+			permanent = true
+			v.FailureReason = fmt.Sprintf("%s (gave up after %d attempts)", in.Reason, v.Failures)
+		}
+	}
+
 	delete(s.InProgressJobs, key)
+	if permanent {
+		s.FailedJobs[key] = v
+		if v.Parent != nil {
+			s.childFailed(*v.Parent, v, v.FailureReason)
+		}
+	} else {
+		s.PendingJobs[key] = v
+	}
 
-	log.Printf("Failed id: %q - %q", key.ID, key.Name)
+	log.Printf("Failed id: %q - %q: %s (permanent: %t)", key.ID, key.Name, v.FailureReason, permanent)
 
 	return &pb.JobFail{}, nil
 }
 
+func (s *memoryServer) RegisterWorker(ctx context.Context, in *pb.WorkerRegistration) (*pb.WorkerRegistrationResponse, error) {
+	if in.WorkerId == "" {
+		return &pb.WorkerRegistrationResponse{}, fmt.Errorf("Worker ID is required")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	w, ok := s.Workers[in.WorkerId]
+	if !ok {
+		w = &Worker{
+			ID:           in.WorkerId,
+			RegisteredAt: now,
+		}
+		s.Workers[in.WorkerId] = w
+	}
+
+	w.Hostname = in.Hostname
+	w.Version = in.Version
+	w.Capabilities = in.Capabilities
+	w.LastSeen = now
+	w.setSlots(in.Slots)
+	w.State = pb.WorkerState_WORKER_IDLE
+	w.summarise(pb.WorkerState_WORKER_IDLE, "", "")
+	w.Problem = in.Problem
+	if w.Problem != "" {
+		w.State = pb.WorkerState_WORKER_UNHEALTHY
+		log.Printf("Worker %q failed its self-test: %s", w.ID, w.Problem)
+	}
+
+	//A worker that restarted lost the jobs it was running
+	s.requeueLost(w, now, "registered again without it")
+
+	log.Printf("Registered worker %q on %q (version %q, %d slots)", w.ID, w.Hostname, w.Version, len(w.Slots))
+
+	return &pb.WorkerRegistrationResponse{
+		HeartbeatInterval: int32(HeartbeatInterval / time.Second),
+	}, nil
+}
+
+// requeueOffline hands the jobs of workers that stopped sending heartbeats
+// to others
+func (s *memoryServer) requeueOffline(now time.Time) {
+	for key, v := range s.InProgressJobs {
+		w, ok := s.Workers[v.WorkerID]
+		if ok && w.currentState(now) == pb.WorkerState_WORKER_OFFLINE {
+			s.requeue(key, v, fmt.Sprintf("worker %q went offline", w.ID))
+		}
+	}
+}
+
+// requeueLost hands the jobs a worker doesn't report running to others.
+// Attempts younger than a heartbeat interval are left alone, the worker may
+// have reported its slots before it took them.
+func (s *memoryServer) requeueLost(w *Worker, now time.Time, reason string) {
+	for key, v := range s.InProgressJobs {
+		if v.WorkerID != w.ID || w.running(key) || now.Sub(v.AttemptStarted) < HeartbeatInterval {
+			continue
+		}
+		s.requeue(key, v, fmt.Sprintf("worker %q %s", w.ID, reason))
+	}
+}
+
+// requeue puts an in-progress job back in the queue. The worker lost it, so
+// it doesn't count as a failure of the job.
+func (s *memoryServer) requeue(key jobKey, v *Job, reason string) {
+	//The slot may have moved on to another job
+	if w, ok := s.Workers[v.WorkerID]; ok && w.running(key) {
+		w.release(v.WorkerSlot)
+	}
+
+	v.LastUpdated = time.Now()
+	v.WorkerID = ""
+	delete(s.InProgressJobs, key)
+	s.PendingJobs[key] = v
+
+	log.Printf("Requeued id: %q - %q: %s", key.ID, key.Name, reason)
+}
+
+func (s *memoryServer) Heartbeat(ctx context.Context, in *pb.WorkerHeartbeat) (*pb.HeartbeatResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	w, ok := s.Workers[in.WorkerId]
+	if !ok {
+		return &pb.HeartbeatResponse{}, fmt.Errorf("Worker %q not registered", in.WorkerId)
+	}
+
+	w.LastSeen = time.Now()
+	w.State = in.State
+	w.JobID = in.JobId
+	w.JobName = in.JobName
+	w.setSlots(in.Slots)
+	w.Problem = in.Problem
+
+	s.requeueLost(w, w.LastSeen, "no longer running it")
+
+	return &pb.HeartbeatResponse{Command: w.takeCommand()}, nil
+}
+
+func (s *memoryServer) ListWorkers(ctx context.Context, in *pb.ListWorkersRequest) (*pb.ListWorkersResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	now := time.Now()
+	r := pb.ListWorkersResponse{}
+	for _, w := range s.Workers {
+		r.Workers = append(r.Workers, w.toProto(now))
+	}
+
+	return &r, nil
+}
+
+func (s *memoryServer) SendCommand(ctx context.Context, in *pb.WorkerCommandRequest) (*pb.WorkerCommandResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r := pb.WorkerCommandResponse{}
+
+	if in.WorkerId != "" {
+		w, ok := s.Workers[in.WorkerId]
+		if !ok {
+			return &r, fmt.Errorf("Worker %q not registered", in.WorkerId)
+		}
+		w.Command = in.Command
+		r.WorkerIds = append(r.WorkerIds, w.ID)
+	} else {
+		for _, w := range s.Workers {
+			w.Command = in.Command
+			r.WorkerIds = append(r.WorkerIds, w.ID)
+		}
+	}
+
+	log.Printf("Sent %s to workers %q", in.Command, r.WorkerIds)
+
+	return &r, nil
+}
+
+func (s *memoryServer) GetAllWorkers(ctx context.Context) (*AllWorkersResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	now := time.Now()
+	r := AllWorkersResponse{
+		Workers: make([]WorkerResponse, 0, len(s.Workers)),
+	}
+	for _, w := range s.Workers {
+		r.Workers = append(r.Workers, w.toResponse(now))
+	}
+
+	return &r, nil
+}
+
 func (s *memoryServer) GetAllJobs(ctx context.Context) (*AllJobsResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	r := AllJobsResponse{}
-	for k, v := range s.PendingJobs {
+	r.Jobs = s.appendJobResponses(r.Jobs, s.PendingJobs, "Pending")
+	r.Jobs = s.appendJobResponses(r.Jobs, s.InProgressJobs, "In progress")
+	r.Jobs = s.appendJobResponses(r.Jobs, s.ParentJobs, "")
+	r.Jobs = s.appendJobResponses(r.Jobs, s.CompletedJobs, "Completed")
+	r.Jobs = s.appendJobResponses(r.Jobs, s.FailedJobs, "Failed")
+
+	r.Stats = JobStats{
+		PendingJobsCount:    len(s.PendingJobs),
+		InProgressJobsCount: len(s.InProgressJobs),
+		CompletedJobsCount:  len(s.CompletedJobs),
+		FailedJobsCount:     len(s.FailedJobs),
+		ParentJobsCount:     len(s.ParentJobs),
+	}
+
+	return &r, nil
+}
+
+// appendJobResponses describes jobs for the UI. Jobs rendered through jobs
+// of their own describe their status themselves if it is empty. The lock
+// must be held.
+func (s *memoryServer) appendJobResponses(r []JobResponse, jobs map[jobKey]*Job, status string) []JobResponse {
+	for k, v := range jobs {
+		partials := v.PartialResults
+		if len(v.Stages) > 0 {
+			partials = s.stagePartials(v)
+		}
+
 		var progressUrls []string
-		for i, _ := range v.PartialResults {
-			progressUrls = append(progressUrls, fmt.Sprintf("progress/%s/%s/%d", k.Name, k.ID, i))
+		for i, _ := range partials {
+			progressUrls = append(progressUrls, fmt.Sprintf("/api/progress/%s/%s/%d", k.Name, k.ID, i))
 		}
 
-		r.Jobs = append(r.Jobs, JobResponse{
+		//Tiled jobs show their tiles stitched as they are now
+		if v.Tiling != nil {
+			progressUrls = []string{fmt.Sprintf("/api/progress/%s/%s/0", k.Name, k.ID)}
+		}
+
+		resp := JobResponse{
 			ID:                k.ID,
 			Name:              k.Name,
-			Status:            "Pending",
-			StyleImageUrl:     fmt.Sprintf("style/%s/%s", k.Name, k.ID),
-			ContentImageUrl:   fmt.Sprintf("content/%s/%s", k.Name, k.ID),
+			Status:            v.status(status),
+			StyleImageUrl:     fmt.Sprintf("/api/style/%s/%s", k.Name, k.ID),
+			ContentImageUrl:   fmt.Sprintf("/api/content/%s/%s", k.Name, k.ID),
 			ProgressImageUrls: progressUrls,
-			ResultImageUrl:    fmt.Sprintf("result/%s/%s", k.Name, k.ID),
-		})
+			ResultImageUrl:    fmt.Sprintf("/api/result/%s/%s", k.Name, k.ID),
+			LossesUrl:         fmt.Sprintf("/api/losses/%s/%s", k.Name, k.ID),
+			LogsUrl:           fmt.Sprintf("/api/logs/%s/%s", k.Name, k.ID),
+			Annotation:        v.Annotation,
+			Width:             v.Width,
+			Height:            v.Height,
+		}
+
+		if v.Parent != nil {
+			resp.ParentID = v.Parent.ID
+		}
+		if v.PostProcessed != nil {
+			resp.RawResultImageUrl = fmt.Sprintf("/api/raw/%s/%s", k.Name, k.ID)
+		}
+		if v.Animation == nil {
+			resp.TimelapseUrl = fmt.Sprintf("/api/timelapse/%s/%s", k.Name, k.ID)
+		}
+		if v.Tiling != nil {
+			resp.Tiles = len(v.Children)
+			resp.TilesCompleted, resp.Iteration = s.childrenProgress(v)
+		}
+		if v.Animation != nil {
+			resp.Frames = len(v.Animation.Frames)
+			resp.FramesCompleted, resp.Iteration = s.childrenProgress(v)
+		}
+		if v.Sweep != nil {
+			resp.SweepJobs = len(v.Children)
+			resp.SweepJobsCompleted, resp.Iteration = s.childrenProgress(v)
+		}
+		if len(v.Stages) > 0 {
+			resp.Stages = len(v.Stages)
+			resp.Stage, resp.Iteration = s.stageProgress(v)
+		}
+
+		r = append(r, resp)
 	}
+	return r
+}
 
-	r.Stats = JobStats{
-		PendingJobsCount:    len(s.PendingJobs),
-		InProgressJobsCount: len(s.InProgressJobs),
-		CompletedJobsCount:  len(s.CompletedJobs),
+// findJob looks for a job in every state
+func (s *memoryServer) findJob(jobId string, name string) (*Job, bool) {
+	key := jobKey{
+		ID:   jobId,
+		Name: name,
 	}
 
+	for _, jobs := range []map[jobKey]*Job{s.PendingJobs, s.InProgressJobs, s.ParentJobs, s.FailedJobs} {
+		if v, ok := jobs[key]; ok {
+			return v, true
+		}
+	}
+
+	key.Completed = true
+	v, ok := s.CompletedJobs[key]
+	return v, ok
+}
+
+func (s *memoryServer) GetLosses(ctx context.Context, jobId string, name string) (*LossesResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	v, ok := s.findJob(jobId, name)
+	if !ok {
+		return &LossesResponse{}, fmt.Errorf("Key with ID %q not found", jobId)
+	}
+
+	losses := make([]LossSample, len(v.Losses))
+	copy(losses, v.Losses)
+
+	return &LossesResponse{Losses: losses}, nil
+}
+
+// UploadLog stores the engine output of an attempt. Logs of stale attempts
+// are kept too as they may explain why the attempt was lost.
+func (s *memoryServer) UploadLog(ctx context.Context, in *pb.JobLogUpload) (*pb.JobLogUploadResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	v, ok := s.findJob(in.Id, in.Name)
+	if !ok {
+		return &pb.JobLogUploadResponse{}, grpc.Errorf(codes.NotFound, "Key with ID %q not found", in.Id)
+	}
+
+	v.addLog(AttemptLog{
+		AttemptID: in.AttemptId,
+		WorkerID:  in.WorkerId,
+		Log:       in.Log,
+		Truncated: in.Truncated,
+		CreatedAt: time.Now(),
+	})
+
+	return &pb.JobLogUploadResponse{}, nil
+}
+
+func (s *memoryServer) GetJobLog(ctx context.Context, in *pb.JobLogRequest) (*pb.JobLogResponse, error) {
+	logs, err := s.GetLogs(ctx, in.Id, in.Name)
+	if err != nil {
+		return &pb.JobLogResponse{}, grpc.Errorf(codes.NotFound, "%v", err)
+	}
+
+	r := pb.JobLogResponse{}
+	for _, l := range logs.Logs {
+		r.Logs = append(r.Logs, &pb.JobLog{
+			AttemptId: l.AttemptID,
+			WorkerId:  l.WorkerID,
+			Log:       l.Log,
+			Truncated: l.Truncated,
+			CreatedAt: l.CreatedAt.Unix(),
+		})
+	}
 	return &r, nil
 }
 
-func (s *memoryServer) GetStyleImage(ctx context.Context, jobId string, name string) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+func (s *memoryServer) GetLogs(ctx context.Context, jobId string, name string) (*LogsResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	v, ok := s.findJob(jobId, name)
+	if !ok {
+		return &LogsResponse{}, fmt.Errorf("Key with ID %q not found", jobId)
+	}
+
+	logs := make([]AttemptLog, len(v.Logs))
+	copy(logs, v.Logs)
+
+	return &LogsResponse{Logs: logs}, nil
+}
+
+func (s *memoryServer) GetStyleImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	v, ok := s.findJob(jobId, name)
+	if !ok {
+		return &ImageResponse{}, fmt.Errorf("Key with ID %q not found", jobId)
+	}
+
+	return &ImageResponse{Image: v.StyleImage, Format: v.StyleFormat}, nil
+}
+
+func (s *memoryServer) GetContentImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	v, ok := s.findJob(jobId, name)
+	if !ok {
+		return &ImageResponse{}, fmt.Errorf("Key with ID %q not found", jobId)
+	}
+
+	return &ImageResponse{Image: v.ContentImage, Format: v.ContentFormat}, nil
+}
+
+// GetResultImage serves the result in the output format of the job,
+// post-processed if the job asks for it
+func (s *memoryServer) GetResultImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	return s.resultImage(jobId, name, false)
+}
+
+// GetRawResultImage serves the result as the engine rendered it in the
+// output format of the job
+func (s *memoryServer) GetRawResultImage(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	return s.resultImage(jobId, name, true)
+}
+
+func (s *memoryServer) resultImage(jobId string, name string, raw bool) (*ImageResponse, error) {
+	s.lock.RLock()
+	v, ok := s.findJob(jobId, name)
+	var result []byte
+	var format pb.ImageFormat
+	if ok {
+		result = v.Result
+		if v.PostProcessed != nil && !raw {
+			result = v.PostProcessed
+		}
+		format = outputFormat(v.Params)
+	}
+	s.lock.RUnlock()
+
+	if !ok {
+		return &ImageResponse{}, fmt.Errorf("Key with ID %q not found", jobId)
+	}
+	if len(result) == 0 {
+		return &ImageResponse{}, fmt.Errorf("Job %q has no result yet", jobId)
+	}
+
+	b, err := transcode(result, format)
+	if err != nil {
+		return &ImageResponse{}, err
+	}
+	return &ImageResponse{Image: b, Format: format}, nil
+}
+
+// GetProgressImage serves a partial result in the output format of the job.
+// Tiled jobs have a single one, their tiles stitched as they are now. Staged
+// jobs have those of all their stages.
+func (s *memoryServer) GetProgressImage(ctx context.Context, jobId string, name string, index int) (*ImageResponse, error) {
+	s.lock.RLock()
+	v, ok := s.findJob(jobId, name)
+	var partial []byte
+	var tiles [][]byte
+	var format pb.ImageFormat
+	if ok && v.Tiling != nil && index == 0 {
+		tiles = s.tileImages(v)
+		format = outputFormat(v.Params)
+	} else if ok {
+		partials := v.PartialResults
+		if len(v.Stages) > 0 {
+			partials = s.stagePartials(v)
+		}
+		if index >= 0 && index < len(partials) {
+			partial = partials[index].Image
+			format = outputFormat(v.Params)
+		}
+	}
+	s.lock.RUnlock()
+
+	if !ok {
+		return &ImageResponse{}, fmt.Errorf("Key with ID %q not found", jobId)
+	}
+	if tiles != nil {
+		stitched, err := v.Tiling.stitch(v.Width, v.Height, tiles)
+		if err != nil {
+			return &ImageResponse{}, err
+		}
+		b, err := encodeImage(stitched, format)
+		if err != nil {
+			return &ImageResponse{}, err
+		}
+		return &ImageResponse{Image: b, Format: format}, nil
+	}
+	if partial == nil {
+		return &ImageResponse{}, fmt.Errorf("Job %q has no progress image %d", jobId, index)
+	}
+
+	b, err := transcode(partial, format)
+	if err != nil {
+		return &ImageResponse{}, err
+	}
+	return &ImageResponse{Image: b, Format: format}, nil
 }
 
-func (s *memoryServer) GetContentImage(ctx context.Context, jobId string, name string) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+// GetTimelapse serves a job played from its content to its latest image as
+// an animated GIF. That of a completed job is only put together once.
+func (s *memoryServer) GetTimelapse(ctx context.Context, jobId string, name string) (*ImageResponse, error) {
+	s.lock.RLock()
+	v, ok := s.findJob(jobId, name)
+	var cached, content, result []byte
+	var partials [][]byte
+	animated := false
+	if ok {
+		cached = v.Timelapse
+		content, partials, result = s.timelapseFrames(v)
+		animated = v.Animation != nil
+	}
+	s.lock.RUnlock()
+
+	if !ok {
+		return &ImageResponse{}, fmt.Errorf("Key with ID %q not found", jobId)
+	}
+	if animated {
+		return &ImageResponse{}, fmt.Errorf("Job %q is an animation, it has no time-lapse", jobId)
+	}
+	if cached != nil {
+		return &ImageResponse{Image: cached, Format: pb.ImageFormat_GIF}, nil
+	}
+
+	b, err := timelapse(content, partials, result)
+	if err != nil {
+		return &ImageResponse{}, err
+	}
+	return &ImageResponse{Image: b, Format: pb.ImageFormat_GIF}, nil
 }
 
-func (s *memoryServer) GetResultImage(ctx context.Context, jobId string, name string) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+// timelapseFrames returns the images a time-lapse of a job is made of. The
+// lock must be held.
+func (s *memoryServer) timelapseFrames(v *Job) ([]byte, [][]byte, []byte) {
+	partials := v.PartialResults
+	if len(v.Stages) > 0 {
+		partials = s.stagePartials(v)
+	}
+
+	images := make([][]byte, len(partials))
+	for i, p := range partials {
+		images[i] = p.Image
+	}
+	return v.ContentImage, images, v.Result
 }
 
-func (s *memoryServer) GetProgressImage(ctx context.Context, jobId string, name string, index int) ([]byte, error) {
-	return []byte{}, fmt.Errorf("Not implemented")
+// saveTimelapse puts the time-lapse of a completed job together and writes
+// it next to its result. It runs without the lock, encoding takes a while.
+func (s *memoryServer) saveTimelapse(key jobKey) {
+	s.lock.RLock()
+	v, ok := s.CompletedJobs[key]
+	var content, result []byte
+	var partials [][]byte
+	if ok {
+		content, partials, result = s.timelapseFrames(v)
+	}
+	s.lock.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	b, err := timelapse(content, partials, result)
+	if err != nil {
+		log.Printf("Could not put the time-lapse of id: %q - %q together. %v", key.ID, key.Name, err)
+		return
+	}
+
+	s.lock.Lock()
+	v.Timelapse = b
+	s.lock.Unlock()
+
+	jobDir := getDirectory(s.OutputDir, key.ID, key.Name)
+	filename, err := prepareFilename(jobDir, "timelapse.gif")
+	if err != nil {
+		log.Println(err)
+	}
+	err = ioutil.WriteFile(filename, b, 0755)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// PostProcessJob post-processes the result of a completed job again with
+// other settings. It waits for the new result to be ready.
+func (s *memoryServer) PostProcessJob(ctx context.Context, in *pb.PostProcessRequest) (*pb.PostProcessResponse, error) {
+	if err := checkPostProcess(in.PostProcess); err != nil {
+		return &pb.PostProcessResponse{}, err
+	}
+
+	key := jobKey{
+		ID:        in.Id,
+		Name:      in.Name,
+		Completed: true,
+	}
+
+	s.lock.Lock()
+	v, ok := s.CompletedJobs[key]
+	if !ok {
+		s.lock.Unlock()
+		return &pb.PostProcessResponse{}, grpc.Errorf(codes.NotFound, "No completed job with ID %q", in.Id)
+	}
+	if v.Animation != nil || v.Sweep != nil {
+		s.lock.Unlock()
+		return &pb.PostProcessResponse{}, grpc.Errorf(codes.InvalidArgument, "Animations and sweeps can't be post-processed")
+	}
+
+	params := *v.Params
+	params.PostProcess = in.PostProcess
+	v.Params = &params
+	v.PostProcessed = nil
+	s.lock.Unlock()
+
+	if err := s.postProcessResult(key); err != nil {
+		return &pb.PostProcessResponse{}, grpc.Errorf(codes.Internal, "%v", err)
+	}
+	return &pb.PostProcessResponse{}, nil
+}
+
+// postProcessResult post-processes the result of a completed job as it asks,
+// then composites it with its content through its mask, and writes each
+// step next to its result. It runs without the lock, adjusting the result
+// takes a while.
+func (s *memoryServer) postProcessResult(key jobKey) error {
+	s.lock.RLock()
+	v, ok := s.CompletedJobs[key]
+	var params *pb.JobParameters
+	var result, content, style, mask []byte
+	if ok {
+		params = v.Params
+		result, content, style, mask = v.Result, v.ContentImage, v.StyleImage, v.Mask
+	}
+	s.lock.RUnlock()
+
+	pp := params.GetPostProcess()
+	if !ok || (!postProcesses(pp) && mask == nil) {
+		return nil
+	}
+
+	var processed, composited []byte
+	var err error
+	if postProcesses(pp) {
+		processed, err = postProcess(pp, result, content, style)
+		result = processed
+	}
+	if err == nil && mask != nil {
+		composited, err = composite(result, content, mask, pp)
+		result = composited
+	}
+	if err != nil {
+		err = fmt.Errorf("Could not post-process id: %q - %q. %v", key.ID, key.Name, err)
+		log.Println(err)
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	//The settings changed while this one ran, the newer run stores its own
+	if v.Params != params {
+		return nil
+	}
+	v.PostProcessed = result
+	if processed != nil {
+		s.saveResult(key, v, "postprocessed", processed)
+	}
+	if composited != nil {
+		s.saveResult(key, v, "composited", composited)
+	}
+	return nil
 }
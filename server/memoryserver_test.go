@@ -0,0 +1,448 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"github.com/mgilbir/neural-style-art-project/upload"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// newTestServer returns a memory server that writes its files to a temporary
+// directory, and a function to remove it
+func newTestServer(t *testing.T) (*memoryServer, func()) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewMemoryServer(dir, Normalizer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.(*memoryServer), func() { os.RemoveAll(dir) }
+}
+
+func TestFailJob(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	retried := jobKey{ID: "retried", Name: "cat"}
+	failed := jobKey{ID: "failed", Name: "dog"}
+	for _, k := range []jobKey{retried, failed} {
+		s.InProgressJobs[k] = &Job{Name: k.Name}
+	}
+
+	if _, err := s.FailJob(ctx, &pb.JobFail{Id: "retried", Name: "cat", Reason: "preempted, retryable"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FailJob(ctx, &pb.JobFail{Id: "failed", Name: "dog", Reason: "bad input", Permanent: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.PendingJobs[retried]; !ok {
+		t.Errorf("retryable failure not requeued")
+	}
+	v, ok := s.FailedJobs[failed]
+	if !ok {
+		t.Fatalf("permanent failure not failed")
+	}
+	if v.FailureReason != "bad input" {
+		t.Errorf("failed with %q, want the reported reason", v.FailureReason)
+	}
+	if len(s.InProgressJobs) != 0 {
+		t.Errorf("%d jobs left in progress", len(s.InProgressJobs))
+	}
+
+	if _, err := s.FailJob(ctx, &pb.JobFail{Id: "failed", Name: "dog"}); err == nil {
+		t.Errorf("failed a job that isn't in progress")
+	}
+}
+
+func TestFailureCap(t *testing.T) {
+	retry := pb.JobFail{}
+	preempted := pb.JobFail{Preempted: true}
+	timedOut := pb.JobFail{TimedOut: true}
+	permanent := pb.JobFail{Permanent: true}
+
+	repeat := func(f pb.JobFail, n int) []pb.JobFail {
+		var r []pb.JobFail
+		for i := 0; i < n; i++ {
+			r = append(r, f)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		failures []pb.JobFail
+		failed   bool
+	}{
+		{"retried", []pb.JobFail{retry}, false},
+		{"permanent", []pb.JobFail{permanent}, true},
+		{"up to the cap", repeat(retry, MaxFailures-1), false},
+		{"over the cap", repeat(retry, MaxFailures), true},
+		{"preemptions don't count", append(repeat(preempted, 2*MaxFailures), retry), false},
+		{"timed out once", []pb.JobFail{timedOut}, false},
+		{"timed out again", repeat(timedOut, MaxTimeouts), true},
+		{"timeouts count as failures", append(repeat(retry, MaxFailures-1), timedOut), true},
+	}
+
+	for _, tt := range tests {
+		s, cleanup := newTestServer(t)
+		parentKey := jobKey{ID: "parent", Name: "tiled"}
+		key := jobKey{ID: "tile", Name: "tiled_0"}
+		sibling := jobKey{ID: "sibling", Name: "tiled_1"}
+		s.ParentJobs[parentKey] = &Job{Name: "tiled", Children: []jobKey{key, sibling}, Tiling: &Tiling{}}
+		s.PendingJobs[key] = &Job{Name: "tiled_0", Parent: &parentKey}
+		s.PendingJobs[sibling] = &Job{Name: "tiled_1", Parent: &parentKey}
+
+		for i, f := range tt.failures {
+			v, ok := s.PendingJobs[key]
+			if !ok {
+				t.Errorf("%s: job not requeued after failure %d", tt.name, i)
+				break
+			}
+			//Start an attempt, as RequestJob does
+			delete(s.PendingJobs, key)
+			v.AttemptID = fmt.Sprintf("attempt-%d", i)
+			s.InProgressJobs[key] = v
+
+			f.Id, f.Name, f.AttemptId = key.ID, key.Name, v.AttemptID
+			if _, err := s.FailJob(context.Background(), &f); err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				break
+			}
+		}
+
+		_, failed := s.FailedJobs[key]
+		_, parentFailed := s.FailedJobs[parentKey]
+		_, siblingFailed := s.FailedJobs[sibling]
+		if failed != tt.failed || parentFailed != tt.failed || siblingFailed != tt.failed {
+			t.Errorf("%s: failed %t, parent %t and sibling %t, want %t", tt.name, failed, parentFailed, siblingFailed, tt.failed)
+		}
+		cleanup()
+	}
+}
+
+func TestResumeFromLatestPartial(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	key := jobKey{ID: "job", Name: "cat"}
+	s.PendingJobs[key] = &Job{Name: "cat"}
+
+	job, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Init != nil || job.StartIteration != 0 {
+		t.Errorf("fresh job sent with init %v from iteration %d", job.Init, job.StartIteration)
+	}
+
+	//Partials may arrive out of order
+	for _, i := range []int32{100, 300, 200} {
+		_, err := s.ProgressReport(ctx, &pb.JobResult{Id: "job", Name: "cat", ProgressCount: i, Image: []byte{byte(i / 100)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.FailJob(ctx, &pb.JobFail{Id: "job", Name: "cat", Reason: "preempted, retryable"}); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.StartIteration != 300 {
+		t.Errorf("resumed from iteration %d, want 300", job.StartIteration)
+	}
+	if job.Init == nil || len(job.Init.Image) != 1 || job.Init.Image[0] != 3 {
+		t.Fatalf("resumed from %v, want the partial at 300", job.Init)
+	}
+	if job.Init.Format != pb.ImageFormat_PNG {
+		t.Errorf("init sent as %s, want PNG", job.Init.Format)
+	}
+}
+
+func TestReportsOfAnAttempt(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	key := jobKey{ID: "job", Name: "cat"}
+	s.PendingJobs[key] = &Job{Name: "cat"}
+	first, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := func(attempt *pb.Job, sequence int64, iteration int32) error {
+		_, err := s.ProgressReport(ctx, &pb.JobResult{Id: "job", Name: "cat", AttemptId: attempt.AttemptId, Sequence: sequence, ProgressCount: iteration})
+		return err
+	}
+
+	if err := progress(first, 1, 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FailJob(ctx, &pb.JobFail{Id: "job", Name: "cat", AttemptId: first.AttemptId}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.AttemptId == first.AttemptId {
+		t.Fatalf("attempt ID %q reused", second.AttemptId)
+	}
+
+	//The first attempt can't touch the job any more
+	if err := progress(first, 2, 200); grpc.Code(err) != codes.FailedPrecondition {
+		t.Errorf("progress of a stale attempt: got %v, want FailedPrecondition", err)
+	}
+	if _, err := s.FailJob(ctx, &pb.JobFail{Id: "job", Name: "cat", AttemptId: first.AttemptId}); grpc.Code(err) != codes.FailedPrecondition {
+		t.Errorf("failure of a stale attempt: got %v, want FailedPrecondition", err)
+	}
+
+	//Sequence numbers start over with each attempt, and repeats are dropped
+	if err := progress(second, 1, 200); err != nil {
+		t.Fatal(err)
+	}
+	if err := progress(second, 1, 300); err != nil {
+		t.Errorf("repeated report rejected: %v", err)
+	}
+	if ps := s.InProgressJobs[key].PartialResults; len(ps) != 2 || ps[1].Iteration != 200 {
+		t.Errorf("got partials %v, want 100 and 200", ps)
+	}
+
+	complete := func(attempt *pb.Job) error {
+		_, err := s.CompleteJob(ctx, &pb.JobResult{Id: "job", Name: "cat", AttemptId: attempt.AttemptId, Sequence: 2, Image: []byte("result")})
+		return err
+	}
+	if err := complete(first); grpc.Code(err) != codes.FailedPrecondition {
+		t.Errorf("completion by a stale attempt: got %v, want FailedPrecondition", err)
+	}
+	if err := complete(second); err != nil {
+		t.Fatal(err)
+	}
+	//A retried completion gets the same answer
+	if err := complete(second); err != nil {
+		t.Errorf("repeated completion rejected: %v", err)
+	}
+	if err := complete(first); grpc.Code(err) != codes.FailedPrecondition {
+		t.Errorf("completion by a stale attempt after the job completed: got %v, want FailedPrecondition", err)
+	}
+	if err := progress(second, 3, 400); grpc.Code(err) != codes.NotFound {
+		t.Errorf("progress after completion: got %v, want NotFound", err)
+	}
+	if _, ok := s.CompletedJobs[jobKey{ID: "job", Name: "cat", Completed: true}]; !ok {
+		t.Errorf("job not completed")
+	}
+}
+
+func TestLogs(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	s.PendingJobs[jobKey{ID: "job", Name: "cat"}] = &Job{Name: "cat"}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{WorkerId: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//The tail sent with a failure stands in until the whole log arrives
+	_, err = s.FailJob(ctx, &pb.JobFail{Id: "job", Name: "cat", AttemptId: job.AttemptId, LogTail: "out of memory\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.UploadLog(ctx, &pb.JobLogUpload{Id: "job", Name: "cat", AttemptId: job.AttemptId, WorkerId: "w1", Log: "Iteration 1\nout of memory\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.GetJobLog(ctx, &pb.JobLogRequest{Id: "job", Name: "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Logs) != 1 || r.Logs[0].Log != "Iteration 1\nout of memory\n" || r.Logs[0].Truncated {
+		t.Errorf("got logs %v, want the whole log of the attempt", r.Logs)
+	}
+
+	if _, err := s.GetJobLog(ctx, &pb.JobLogRequest{Id: "other", Name: "cat"}); grpc.Code(err) != codes.NotFound {
+		t.Errorf("logs of an unknown job: got %v, want NotFound", err)
+	}
+}
+
+func TestCachedStyle(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	style := []byte("style")
+	for _, id := range []string{"a", "b"} {
+		s.PendingJobs[jobKey{ID: id, Name: "cat"}] = &Job{Name: "cat", StyleImage: style, StyleDigest: upload.Digest(style)}
+	}
+
+	job, err := s.RequestJob(ctx, &pb.JobRequest{CachedDigests: []string{upload.Digest([]byte("other"))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(job.Style.Image) != "style" || job.Style.Digest != upload.Digest(style) {
+		t.Errorf("sent style %q with digest %q to a worker without it cached", job.Style.Image, job.Style.Digest)
+	}
+
+	job, err = s.RequestJob(ctx, &pb.JobRequest{CachedDigests: []string{upload.Digest(style)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Style.Image != nil || job.Style.Digest != upload.Digest(style) {
+		t.Errorf("sent style %q with digest %q to a worker with it cached", job.Style.Image, job.Style.Digest)
+	}
+}
+
+// resultStream plays an upload of a result to the server
+type resultStream struct {
+	pb.NeuralStyleWorker_UploadResultServer
+	msgs []*pb.ResultUpload
+}
+
+func (r *resultStream) Context() context.Context {
+	return context.Background()
+}
+
+func (r *resultStream) Recv() (*pb.ResultUpload, error) {
+	if len(r.msgs) == 0 {
+		return nil, io.EOF
+	}
+	m := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return m, nil
+}
+
+func (r *resultStream) SendAndClose(*pb.JobResultResponse) error {
+	return nil
+}
+
+func TestUploadResult(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	s.PendingJobs[jobKey{ID: "job", Name: "cat"}] = &Job{Name: "cat"}
+	job, err := s.RequestJob(context.Background(), &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image := bytes.Repeat([]byte("x"), 2*upload.ChunkSize+10)
+	send := func(digest string) error {
+		stream := &resultStream{msgs: []*pb.ResultUpload{{
+			Result:   &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Digest: digest},
+			Complete: true,
+		}}}
+		err := upload.Send(pb.ImagePart_PART_RESULT, image, func(c *pb.ImageChunk) error {
+			stream.msgs = append(stream.msgs, &pb.ResultUpload{Chunk: c})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.UploadResult(stream)
+	}
+
+	//A damaged upload can be sent again
+	if err := send(upload.Digest([]byte("other"))); grpc.Code(err) != codes.DataLoss {
+		t.Errorf("upload with the wrong digest: got %v, want DataLoss", err)
+	}
+	if err := send(upload.Digest(image)); err != nil {
+		t.Fatal(err)
+	}
+
+	v, ok := s.CompletedJobs[jobKey{ID: "job", Name: "cat", Completed: true}]
+	if !ok {
+		t.Fatalf("job not completed")
+	}
+	if !bytes.Equal(v.Result, image) {
+		t.Errorf("stored %d bytes, want the %d uploaded", len(v.Result), len(image))
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	//Inputs the engine can't read are converted for it
+	_, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: sampleImage(t, pb.ImageFormat_GIF, 16, 16)},
+		Content: &pb.InputImage{Title: "cat", Image: sampleImage(t, pb.ImageFormat_TIFF, 16, 16)},
+		Params:  &pb.JobParameters{OutputFormat: pb.ImageFormat_JPG},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Style.Format != pb.ImageFormat_PNG || job.Content.Format != pb.ImageFormat_PNG {
+		t.Errorf("engine sent %s style and %s content, want PNG", job.Style.Format, job.Content.Format)
+	}
+
+	//The engine always writes PNG
+	result := sampleImage(t, pb.ImageFormat_PNG, 16, 16)
+	_, err = s.ProgressReport(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1, ProgressCount: 100, Image: result})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 2, Image: result})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path.Join(getDirectory(s.OutputDir, job.Id, job.Name), "result.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img, err := checkImage("result", b); err != nil || img.Format != pb.ImageFormat_JPG {
+		t.Errorf("stored result is %s, %v", img.Format, err)
+	}
+
+	api := httptest.NewServer(NewAPIHandler(s))
+	defer api.Close()
+	for _, p := range []string{"/api/result/cat/" + job.Id, "/api/progress/cat/" + job.Id + "/0"} {
+		resp, err := http.Get(api.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/jpeg" {
+			t.Errorf("%s served %d as %q", p, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+	}
+	if _, err := s.GetProgressImage(ctx, job.Id, job.Name, 1); err == nil {
+		t.Errorf("served a progress image that doesn't exist")
+	}
+
+	_, err = s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "dog",
+		Style:   &pb.InputImage{Title: "wave", Image: result},
+		Content: &pb.InputImage{Title: "dog", Image: result},
+		Params:  &pb.JobParameters{OutputFormat: pb.ImageFormat_WEBP},
+	})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("job with WebP results: got %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+// NormalizeStep is one of the transformations applied to submitted images
+type NormalizeStep string
+
+const (
+	// StepOrient turns images upright according to their EXIF orientation
+	StepOrient NormalizeStep = "orient"
+	// StepSRGB converts images to 8 bit sRGB, applying their ICC profile or
+	// EXIF colour space. Images using a profile that can't be applied are
+	// rejected. Greyscale and CMYK profiles are ignored.
+	StepSRGB NormalizeStep = "srgb"
+	// StepResize scales images down to the size of the job output
+	StepResize NormalizeStep = "resize"
+)
+
+// DefaultImageSize is the output size of jobs that don't set one, the same
+// as the engine default
+const DefaultImageSize = 512
+
+// normalizedJPEGQuality keeps re-encoding losses out of sight
+const normalizedJPEGQuality = 95
+
+// Normalizer prepares submitted images for the engine before their job is
+// queued. Images are always re-encoded, which strips their metadata. Without
+// steps images are queued as submitted, unless the engine can't read them.
+type Normalizer struct {
+	Steps []NormalizeStep
+
+	// ImageSize is the output size of jobs that don't set one
+	ImageSize int
+}
+
+// ParseNormalizeSteps reads a comma separated list of steps. The empty string
+// disables normalisation.
+func ParseNormalizeSteps(s string) ([]NormalizeStep, error) {
+	var steps []NormalizeStep
+	for _, name := range strings.Split(s, ",") {
+		step := NormalizeStep(strings.TrimSpace(name))
+		switch step {
+		case "":
+			continue
+		case StepOrient, StepSRGB, StepResize:
+			steps = append(steps, step)
+		default:
+			return nil, fmt.Errorf("Unknown normalisation step %q. Use orient, srgb or resize", name)
+		}
+	}
+	return steps, nil
+}
+
+// imageSize is the output size of a job, that of its last stage if it has
+// any
+func (n Normalizer) imageSize(params *pb.JobParameters) int {
+	if stages := params.GetStages(); len(stages) > 0 && stages[len(stages)-1] != nil {
+		return int(stages[len(stages)-1].ImageSize)
+	}
+	if params != nil && params.ImageSize > 0 {
+		return int(params.ImageSize)
+	}
+	if n.ImageSize > 0 {
+		return n.ImageSize
+	}
+	return DefaultImageSize
+}
+
+// styleSize is the size style images are scaled to. The engine only sees one
+// tile of a tiled job at a time, so its style is scaled to the tile size.
+func (n Normalizer) styleSize(params *pb.JobParameters) int {
+	if size, _ := tileSize(params); size > 0 {
+		return size
+	}
+	return n.imageSize(params)
+}
+
+// normalize runs the steps on a checked image, scaling it down to fit in a
+// square of maxSize pixels
+func (n Normalizer) normalize(in checkedImage, maxSize int) (checkedImage, error) {
+	if len(n.Steps) == 0 && engineReadable(in.Format) {
+		return in, nil
+	}
+
+	img := in.Decoded
+	for _, step := range n.Steps {
+		switch step {
+		case StepOrient:
+			img = orient(img, exifOrientation(in.Data))
+		case StepSRGB:
+			rgba, err := toSRGB(img, in.Data, in.Format)
+			if err != nil {
+				return in, err
+			}
+			img = rgba
+		case StepResize:
+			img = fit(img, maxSize)
+		}
+	}
+
+	format := in.Format
+	if !engineReadable(format) {
+		format = pb.ImageFormat_PNG
+	}
+
+	data, err := encodeImage(img, format)
+	if err != nil {
+		return in, err
+	}
+
+	b := img.Bounds()
+	return checkedImage{
+		Data:    data,
+		Format:  format,
+		Width:   b.Dx(),
+		Height:  b.Dy(),
+		Decoded: img,
+	}, nil
+}
+
+// toRGBA converts an image to 8 bit RGB, including CMYK and greyscale ones
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// orient applies an EXIF orientation so the image is stored upright
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	//Orientations 5 to 8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			si := src.PixOffset(sx, sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// fit scales an image down, keeping its aspect ratio, so neither side is
+// larger than maxSize. Each output pixel averages the input pixels it covers.
+func fit(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if maxSize <= 0 || (w <= maxSize && h <= maxSize) {
+		return img
+	}
+
+	dw, dh := maxSize, maxSize
+	if w > h {
+		dh = h * maxSize / w
+	} else {
+		dw = w * maxSize / h
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	src := toRGBA(img)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[i+c])
+					}
+					i += 4
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			di := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[di+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
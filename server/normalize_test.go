@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+)
+
+func TestParseNormalizeSteps(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []NormalizeStep
+		ok   bool
+	}{
+		{"", nil, true},
+		{"orient,srgb,resize", []NormalizeStep{StepOrient, StepSRGB, StepResize}, true},
+		{" resize , orient ", []NormalizeStep{StepResize, StepOrient}, true},
+		{"orient,,srgb", []NormalizeStep{StepOrient, StepSRGB}, true},
+		{"cmyk", nil, false},
+		{"orient,sharpen", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseNormalizeSteps(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("%q: got error %v, want ok %t", tt.in, err, tt.ok)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.in, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q: got %v, want %v", tt.in, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	//A 3×2 image with a different grey level in each pixel
+	//	0 1 2
+	//	3 4 5
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.Set(i%3, i/3, color.RGBA{uint8(i), uint8(i), uint8(i), 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{0, 1, 2}, {3, 4, 5}}},
+		{2, [][]uint8{{2, 1, 0}, {5, 4, 3}}},
+		{3, [][]uint8{{5, 4, 3}, {2, 1, 0}}},
+		{4, [][]uint8{{3, 4, 5}, {0, 1, 2}}},
+		{5, [][]uint8{{0, 3}, {1, 4}, {2, 5}}},
+		{6, [][]uint8{{3, 0}, {4, 1}, {5, 2}}},
+		{7, [][]uint8{{5, 2}, {4, 1}, {3, 0}}},
+		{8, [][]uint8{{2, 5}, {1, 4}, {0, 3}}},
+	}
+
+	for _, tt := range tests {
+		got := toRGBA(orient(src, tt.orientation))
+		b := got.Bounds()
+		if b.Dy() != len(tt.want) || b.Dx() != len(tt.want[0]) {
+			t.Errorf("orientation %d: got size %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), len(tt.want[0]), len(tt.want))
+			continue
+		}
+		for y, row := range tt.want {
+			for x, v := range row {
+				if p := got.Pix[got.PixOffset(x, y)]; p != v {
+					t.Errorf("orientation %d: pixel %d,%d is %d, want %d", tt.orientation, x, y, p, v)
+				}
+			}
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, maxSize int
+		wantW, wantH  int
+	}{
+		{100, 50, 0, 100, 50},
+		{100, 50, 200, 100, 50},
+		{100, 50, 100, 100, 50},
+		{100, 50, 40, 40, 20},
+		{50, 100, 40, 20, 40},
+		{1000, 1, 10, 10, 1},
+	}
+
+	for _, tt := range tests {
+		b := fit(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.maxSize).Bounds()
+		if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("fit %dx%d in %d: got %dx%d, want %dx%d", tt.w, tt.h, tt.maxSize, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+
+	//Pixels are averaged, not sampled
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.RGBA{200, 0, 0, 255})
+	src.Set(1, 1, color.RGBA{0, 0, 100, 255})
+	got := toRGBA(fit(src, 1))
+	if c := got.RGBAAt(0, 0); c != (color.RGBA{50, 0, 25, 127}) {
+		t.Errorf("averaged to %v", c)
+	}
+}
+
+func TestNormalizeSubmittedImages(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	s.Normalizer = Normalizer{Steps: []NormalizeStep{StepOrient, StepSRGB, StepResize}, ImageSize: 64}
+
+	content := sampleImage(t, pb.ImageFormat_PNG, 200, 100)
+	_, err := s.CreateFullJob(context.Background(), &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: sampleImage(t, pb.ImageFormat_JPG, 32, 32)},
+		Content: &pb.InputImage{Title: "cat", Image: content},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range s.PendingJobs {
+		if v.Width != 64 || v.Height != 32 || v.Params.ImageSize != 64 {
+			t.Errorf("queued %dx%d rendering at %d, want 64x32 at 64", v.Width, v.Height, v.Params.ImageSize)
+		}
+		if !bytes.Equal(v.OriginalContent, content) {
+			t.Errorf("original content not kept")
+		}
+		img, err := png.Decode(bytes.NewReader(v.ContentImage))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 32 {
+			t.Errorf("content sent as %v", b)
+		}
+	}
+}
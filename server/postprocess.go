@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// checkPostProcess makes sure a result can be post-processed as asked
+func checkPostProcess(pp *pb.PostProcess) error {
+	if pp == nil {
+		return nil
+	}
+
+	if _, ok := pb.ColorTransfer_name[int32(pp.ColorTransfer)]; !ok {
+		return grpc.Errorf(codes.InvalidArgument, "Unknown colour transfer %d", pp.ColorTransfer)
+	}
+	if pp.Strength < 0 || pp.Strength > 1 {
+		return grpc.Errorf(codes.InvalidArgument, "Post-processing strength must be between 0 and 1")
+	}
+	if pp.MaskFeather < 0 {
+		return grpc.Errorf(codes.InvalidArgument, "Mask feathering can't be negative")
+	}
+	return nil
+}
+
+// postProcesses tells whether post-processing changes a result at all
+func postProcesses(pp *pb.PostProcess) bool {
+	if pp == nil {
+		return false
+	}
+	return pp.ColorTransfer != pb.ColorTransfer_KEEP_COLORS || (pp.Strength > 0 && pp.Strength < 1)
+}
+
+// postProcess adjusts a rendered result with the content and style of its
+// job, first its colours and then how much of it is kept. The content is
+// scaled to the size of the result. The adjusted result is encoded as PNG.
+func postProcess(pp *pb.PostProcess, result []byte, content []byte, style []byte) ([]byte, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(result))
+	if err != nil {
+		return nil, fmt.Errorf("Result: %v", err)
+	}
+	img := cloneRGBA(toRGBA(decoded))
+	b := img.Bounds()
+
+	decoded, _, err = image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("Content: %v", err)
+	}
+	original := toRGBA(scaleTo(decoded, b.Dx(), b.Dy()))
+
+	switch pp.ColorTransfer {
+	case pb.ColorTransfer_LUMINANCE:
+		transferLuminance(img, original)
+	case pb.ColorTransfer_MATCH_CONTENT:
+		matchHistogram(img, original)
+	case pb.ColorTransfer_MATCH_STYLE:
+		decoded, _, err := image.Decode(bytes.NewReader(style))
+		if err != nil {
+			return nil, fmt.Errorf("Style: %v", err)
+		}
+		matchHistogram(img, toRGBA(decoded))
+	}
+
+	if pp.Strength > 0 && pp.Strength < 1 {
+		blend(img, original, pp.Strength)
+	}
+
+	return encodeImage(img, pb.ImageFormat_PNG)
+}
+
+// transferLuminance keeps the luminance of each pixel of an image and takes
+// its colour from the same pixel of another one of the same size
+func transferLuminance(img *image.RGBA, colors *image.RGBA) {
+	for i := 0; i+3 < len(img.Pix); i += 4 {
+		y, _, _ := color.RGBToYCbCr(img.Pix[i], img.Pix[i+1], img.Pix[i+2])
+		_, cb, cr := color.RGBToYCbCr(colors.Pix[i], colors.Pix[i+1], colors.Pix[i+2])
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2] = color.YCbCrToRGB(y, cb, cr)
+	}
+}
+
+// matchHistogram remaps each colour channel of an image so its histogram
+// follows that of a reference image, which may be of any size
+func matchHistogram(img *image.RGBA, ref *image.RGBA) {
+	for c := 0; c < 3; c++ {
+		src := cumulativeHistogram(img.Pix, c)
+		dst := cumulativeHistogram(ref.Pix, c)
+
+		var lut [256]uint8
+		j := 0
+		for v := range lut {
+			for j < 255 && dst[j] < src[v] {
+				j++
+			}
+			lut[v] = uint8(j)
+		}
+
+		for i := c; i < len(img.Pix); i += 4 {
+			img.Pix[i] = lut[img.Pix[i]]
+		}
+	}
+}
+
+// cumulativeHistogram returns the fraction of pixels with each value or less
+// in a channel of RGBA pixels
+func cumulativeHistogram(pix []uint8, channel int) [256]float64 {
+	var counts [256]int
+	n := 0
+	for i := channel; i < len(pix); i += 4 {
+		counts[pix[i]]++
+		n++
+	}
+
+	var cdf [256]float64
+	sum := 0
+	for v, count := range counts {
+		sum += count
+		if n > 0 {
+			cdf[v] = float64(sum) / float64(n)
+		}
+	}
+	return cdf
+}
+
+// blend keeps strength of an image and mixes the rest of another one of the
+// same size in
+func blend(img *image.RGBA, other *image.RGBA, strength float64) {
+	for i := range img.Pix {
+		img.Pix[i] = uint8(float64(img.Pix[i])*strength + float64(other.Pix[i])*(1-strength) + 0.5)
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// greyRGBA builds a 1 pixel high image with the given grey levels
+func greyRGBA(levels ...uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(levels), 1))
+	for i, v := range levels {
+		img.Pix[i*4], img.Pix[i*4+1], img.Pix[i*4+2], img.Pix[i*4+3] = v, v, v, 255
+	}
+	return img
+}
+
+// levels returns the red channel of a 1 pixel high image
+func levels(img *image.RGBA) []uint8 {
+	var r []uint8
+	for i := 0; i < len(img.Pix); i += 4 {
+		r = append(r, img.Pix[i])
+	}
+	return r
+}
+
+func TestMatchHistogram(t *testing.T) {
+	tests := []struct {
+		name string
+		img  []uint8
+		ref  []uint8
+		want []uint8
+	}{
+		{"same histogram", []uint8{0, 100, 200}, []uint8{200, 0, 100}, []uint8{0, 100, 200}},
+		{"shifted", []uint8{10, 20, 30, 40}, []uint8{110, 120, 130, 140}, []uint8{110, 120, 130, 140}},
+		{"stretched", []uint8{100, 101, 102, 103}, []uint8{0, 85, 170, 255}, []uint8{0, 85, 170, 255}},
+		{"order kept", []uint8{40, 30, 20, 10}, []uint8{1, 2, 3, 4}, []uint8{4, 3, 2, 1}},
+		{"reference of another size", []uint8{0, 255}, []uint8{50, 50, 50, 60, 60, 60}, []uint8{50, 60}},
+		{"flat reference", []uint8{0, 128, 255}, []uint8{77, 77}, []uint8{77, 77, 77}},
+	}
+
+	for _, tt := range tests {
+		img := greyRGBA(tt.img...)
+		matchHistogram(img, greyRGBA(tt.ref...))
+		got := levels(img)
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		for i := 3; i < len(img.Pix); i += 4 {
+			if img.Pix[i] != 255 {
+				t.Errorf("%s: alpha changed", tt.name)
+				break
+			}
+		}
+	}
+}
+
+func TestTransferLuminance(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	copy(img.Pix, []uint8{128, 128, 128, 255, 30, 30, 30, 255})
+	colors := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	copy(colors.Pix, []uint8{200, 40, 40, 255, 128, 128, 128, 255})
+
+	transferLuminance(img, colors)
+
+	//The first pixel turns red, the second one stays grey
+	if r, g, b := img.Pix[0], img.Pix[1], img.Pix[2]; r <= g || r <= b || g != b {
+		t.Errorf("first pixel is %d,%d,%d, want a red", r, g, b)
+	}
+	if r, g, b := img.Pix[4], img.Pix[5], img.Pix[6]; r != 30 || g != 30 || b != 30 {
+		t.Errorf("second pixel is %d,%d,%d, want 30,30,30", r, g, b)
+	}
+}
+
+func TestBlend(t *testing.T) {
+	tests := []struct {
+		strength float64
+		want     uint8
+	}{
+		{1, 200},
+		{0, 100},
+		{0.5, 150},
+		{0.25, 125},
+	}
+
+	for _, tt := range tests {
+		img := greyRGBA(200)
+		blend(img, greyRGBA(100), tt.strength)
+		if got := img.Pix[0]; got != tt.want {
+			t.Errorf("strength %v: got %d, want %d", tt.strength, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPostProcess(t *testing.T) {
+	tests := []struct {
+		name string
+		pp   *pb.PostProcess
+		ok   bool
+		does bool
+	}{
+		{"none", nil, true, false},
+		{"defaults", &pb.PostProcess{}, true, false},
+		{"luminance", &pb.PostProcess{ColorTransfer: pb.ColorTransfer_LUMINANCE}, true, true},
+		{"half strength", &pb.PostProcess{Strength: 0.5}, true, true},
+		{"full strength", &pb.PostProcess{Strength: 1}, true, false},
+		{"unknown transfer", &pb.PostProcess{ColorTransfer: pb.ColorTransfer(42)}, false, true},
+		{"negative strength", &pb.PostProcess{Strength: -0.1}, false, false},
+		{"too strong", &pb.PostProcess{Strength: 1.5}, false, false},
+		{"negative feather", &pb.PostProcess{MaskFeather: -1}, false, false},
+	}
+
+	for _, tt := range tests {
+		if err := checkPostProcess(tt.pp); (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v, want ok %t", tt.name, err, tt.ok)
+		}
+		if got := postProcesses(tt.pp); got != tt.does {
+			t.Errorf("%s: post-processes %t, want %t", tt.name, got, tt.does)
+		}
+	}
+}
+
+func TestPostProcess(t *testing.T) {
+	red := color.RGBA{200, 0, 0, 255}
+	blue := color.RGBA{0, 0, 200, 255}
+	result := uniformPNG(t, 40, 20, red)
+	content := uniformPNG(t, 80, 40, blue)
+
+	tests := []struct {
+		name string
+		pp   *pb.PostProcess
+		want color.RGBA
+	}{
+		{"keep colours", &pb.PostProcess{}, red},
+		{"match content", &pb.PostProcess{ColorTransfer: pb.ColorTransfer_MATCH_CONTENT}, blue},
+		{"match style", &pb.PostProcess{ColorTransfer: pb.ColorTransfer_MATCH_STYLE}, red},
+		{"half strength", &pb.PostProcess{Strength: 0.5}, color.RGBA{100, 0, 100, 255}},
+	}
+
+	for _, tt := range tests {
+		b, err := postProcess(tt.pp, result, content, result)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		img, err := png.Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if b := img.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+			t.Errorf("%s: got size %v, want that of the result", tt.name, b)
+		}
+		if got := toRGBA(img).RGBAAt(10, 10); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := postProcess(&pb.PostProcess{}, result, []byte("not an image"), result); err == nil {
+		t.Errorf("post-processed with an unreadable content image")
+	}
+}
+
+func TestPostProcessJob(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	red := color.RGBA{200, 0, 0, 255}
+	blue := color.RGBA{0, 0, 200, 255}
+	_, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 8, 8, red)},
+		Content: &pb.InputImage{Title: "cat", Image: uniformPNG(t, 8, 8, blue)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1, Image: uniformPNG(t, 8, 8, red)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Post-processing can be changed after the job completed
+	_, err = s.PostProcessJob(ctx, &pb.PostProcessRequest{Id: job.Id, Name: job.Name,
+		PostProcess: &pb.PostProcess{ColorTransfer: pb.ColorTransfer_MATCH_CONTENT}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pixel := func(img *ImageResponse, err error) color.RGBA {
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := png.Decode(bytes.NewReader(img.Image))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return toRGBA(decoded).RGBAAt(0, 0)
+	}
+	if got := pixel(s.GetResultImage(ctx, job.Id, job.Name)); got != blue {
+		t.Errorf("result is %v, want it in the colours of the content", got)
+	}
+	if got := pixel(s.GetRawResultImage(ctx, job.Id, job.Name)); got != red {
+		t.Errorf("raw result is %v, want it as rendered", got)
+	}
+
+	_, err = s.PostProcessJob(ctx, &pb.PostProcessRequest{Id: "other", Name: "cat", PostProcess: &pb.PostProcess{}})
+	if grpc.Code(err) != codes.NotFound {
+		t.Errorf("post-processing an unknown job: got %v, want NotFound", err)
+	}
+	_, err = s.PostProcessJob(ctx, &pb.PostProcessRequest{Id: job.Id, Name: job.Name, PostProcess: &pb.PostProcess{Strength: 2}})
+	if grpc.Code(err) != codes.InvalidArgument {
+		t.Errorf("post-processing with an invalid strength: got %v, want InvalidArgument", err)
+	}
+}
@@ -8,14 +8,101 @@ import (
 	"golang.org/x/net/context"
 )
 
+var (
+	// MaxFailures is how many attempts at a job can fail before it is given
+	// up on
+	MaxFailures = 5
+
+	// MaxTimeouts is how many attempts at a job can time out before it is
+	// given up on. A job that hangs once tends to hang every time.
+	MaxTimeouts = 2
+)
+
 type Job struct {
-	Name           string
-	StyleName      string
-	StyleImage     []byte
-	ContentImage   []byte
-	PartialResults [][]byte
-	Result         []byte
-	LastUpdated    time.Time
+	Name            string
+	StyleName       string
+	StyleImage      []byte
+	StyleDigest     string
+	StyleFormat     pb.ImageFormat
+	ContentImage    []byte
+	ContentFormat   pb.ImageFormat
+	OriginalStyle   []byte
+	OriginalContent []byte
+	Mask            []byte
+	Width           int
+	Height          int
+	Params          *pb.JobParameters
+	PartialResults  []PartialResult
+	Losses          []LossSample
+	Result          []byte
+	Annotation      string
+	WorkerID        string
+	WorkerSlot      int32
+	AttemptID       string
+	AttemptStarted  time.Time
+	LastSequence    int64
+	FailureReason   string
+	Failures        int
+	Timeouts        int
+	Logs            []AttemptLog
+	LastUpdated     time.Time
+	Parent          *jobKey
+	Children        []jobKey
+	Tiling          *Tiling
+	Assembling      bool
+	Stages          []*pb.Stage
+	InitImage       []byte
+	Animation       *Animation
+	Sweep           *Sweep
+	Timelapse       []byte
+	PostProcessed   []byte
+}
+
+type Worker struct {
+	ID            string
+	Hostname      string
+	Version       string
+	Capabilities  []string
+	State         pb.WorkerState
+	JobID         string
+	JobName       string
+	RegisteredAt  time.Time
+	LastSeen      time.Time
+	JobsCompleted int
+	JobsFailed    int
+	Command       pb.WorkerCommand
+	Slots         []Slot
+	Problem       string
+}
+
+// Slot is a device on a worker that runs one job at a time
+type Slot struct {
+	Index   int32
+	Device  string
+	State   pb.WorkerState
+	JobID   string
+	JobName string
+}
+
+type LossSample struct {
+	Iteration int32   `json:"iteration"`
+	Content   float64 `json:"content"`
+	Style     float64 `json:"style"`
+	Total     float64 `json:"total"`
+}
+
+// AttemptLog is the engine output of one attempt at a job
+type AttemptLog struct {
+	AttemptID string    `json:"attemptId"`
+	WorkerID  string    `json:"workerId"`
+	Log       string    `json:"log"`
+	Truncated bool      `json:"truncated"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type PartialResult struct {
+	Iteration int32
+	Image     []byte
 }
 
 type jobKey struct {
@@ -28,6 +115,7 @@ type Server interface {
 	StyleLoader
 	ImagerServer
 	JobServer
+	AdminServer
 	UIServer
 	Closer
 }
@@ -39,6 +127,9 @@ type StyleLoader interface {
 type ImagerServer interface {
 	CreateJob(ctx context.Context, in *pb.CreateJobRequest) (*pb.CreateJobResponse, error)
 	CreateFullJob(ctx context.Context, in *pb.CreateFullJobRequest) (*pb.CreateFullJobResponse, error)
+	GetJobLog(ctx context.Context, in *pb.JobLogRequest) (*pb.JobLogResponse, error)
+	UploadJob(stream pb.NeuralStyleImager_UploadJobServer) error
+	PostProcessJob(ctx context.Context, in *pb.PostProcessRequest) (*pb.PostProcessResponse, error)
 }
 
 type JobServer interface {
@@ -47,22 +138,50 @@ type JobServer interface {
 	ProgressReport(ctx context.Context, in *pb.JobResult) (*pb.JobProgressResponse, error)
 	CompleteJob(ctx context.Context, in *pb.JobResult) (*pb.JobResultResponse, error)
 	FailJob(ctx context.Context, in *pb.JobFail) (*pb.JobFail, error)
+	RegisterWorker(ctx context.Context, in *pb.WorkerRegistration) (*pb.WorkerRegistrationResponse, error)
+	Heartbeat(ctx context.Context, in *pb.WorkerHeartbeat) (*pb.HeartbeatResponse, error)
+	UploadLog(ctx context.Context, in *pb.JobLogUpload) (*pb.JobLogUploadResponse, error)
+	UploadResult(stream pb.NeuralStyleWorker_UploadResultServer) error
+}
+
+type AdminServer interface {
+	ListWorkers(ctx context.Context, in *pb.ListWorkersRequest) (*pb.ListWorkersResponse, error)
+	SendCommand(ctx context.Context, in *pb.WorkerCommandRequest) (*pb.WorkerCommandResponse, error)
 }
 
 type JobResponse struct {
-	ID                string   `json:"id"`
-	Name              string   `json:"name"`
-	Status            string   `json:"status"`
-	StyleImageUrl     string   `json:"styleUrl"`
-	ContentImageUrl   string   `json:"contentUrl"`
-	ProgressImageUrls []string `json:"progressUrls,omitempty"`
-	ResultImageUrl    string   `json:"resultUrl,omitempty"`
+	ID                 string   `json:"id"`
+	Name               string   `json:"name"`
+	Status             string   `json:"status"`
+	StyleImageUrl      string   `json:"styleUrl"`
+	ContentImageUrl    string   `json:"contentUrl"`
+	ProgressImageUrls  []string `json:"progressUrls,omitempty"`
+	ResultImageUrl     string   `json:"resultUrl,omitempty"`
+	RawResultImageUrl  string   `json:"rawResultUrl,omitempty"`
+	LossesUrl          string   `json:"lossesUrl"`
+	LogsUrl            string   `json:"logsUrl"`
+	TimelapseUrl       string   `json:"timelapseUrl,omitempty"`
+	Annotation         string   `json:"annotation,omitempty"`
+	Width              int      `json:"width"`
+	Height             int      `json:"height"`
+	ParentID           string   `json:"parentId,omitempty"`
+	Tiles              int      `json:"tiles,omitempty"`
+	TilesCompleted     int      `json:"tilesCompleted,omitempty"`
+	Stages             int      `json:"stages,omitempty"`
+	Stage              int      `json:"stage,omitempty"`
+	Frames             int      `json:"frames,omitempty"`
+	FramesCompleted    int      `json:"framesCompleted,omitempty"`
+	SweepJobs          int      `json:"sweepJobs,omitempty"`
+	SweepJobsCompleted int      `json:"sweepJobsCompleted,omitempty"`
+	Iteration          int32    `json:"iteration,omitempty"`
 }
 
 type JobStats struct {
 	PendingJobsCount    int `json:"pending"`
 	InProgressJobsCount int `json:"inprogress"`
 	CompletedJobsCount  int `json:"completed"`
+	FailedJobsCount     int `json:"failed"`
+	ParentJobsCount     int `json:"parents"`
 }
 
 type AllJobsResponse struct {
@@ -70,12 +189,59 @@ type AllJobsResponse struct {
 	Stats JobStats      `json:"stats"`
 }
 
+type WorkerResponse struct {
+	ID            string         `json:"id"`
+	Hostname      string         `json:"hostname"`
+	Version       string         `json:"version"`
+	Capabilities  []string       `json:"capabilities,omitempty"`
+	Status        string         `json:"status"`
+	JobID         string         `json:"jobId,omitempty"`
+	JobName       string         `json:"jobName,omitempty"`
+	LastSeen      time.Time      `json:"lastSeen"`
+	JobsCompleted int            `json:"completed"`
+	JobsFailed    int            `json:"failed"`
+	Command       string         `json:"command,omitempty"`
+	Slots         []SlotResponse `json:"slots,omitempty"`
+	Problem       string         `json:"problem,omitempty"`
+}
+
+type SlotResponse struct {
+	Index   int32  `json:"index"`
+	Device  string `json:"device"`
+	Status  string `json:"status"`
+	JobID   string `json:"jobId,omitempty"`
+	JobName string `json:"jobName,omitempty"`
+}
+
+type AllWorkersResponse struct {
+	Workers []WorkerResponse `json:"workers"`
+}
+
+type LossesResponse struct {
+	Losses []LossSample `json:"losses"`
+}
+
+type LogsResponse struct {
+	Logs []AttemptLog `json:"logs"`
+}
+
+// ImageResponse is an image along with the format it is encoded in
+type ImageResponse struct {
+	Image  []byte
+	Format pb.ImageFormat
+}
+
 type UIServer interface {
 	GetAllJobs(ctx context.Context) (*AllJobsResponse, error)
-	GetStyleImage(ctx context.Context, jobId string, name string) ([]byte, error)
-	GetContentImage(ctx context.Context, jobId string, name string) ([]byte, error)
-	GetResultImage(ctx context.Context, jobId string, name string) ([]byte, error)
-	GetProgressImage(ctx context.Context, jobId string, name string, index int) ([]byte, error)
+	GetAllWorkers(ctx context.Context) (*AllWorkersResponse, error)
+	GetStyleImage(ctx context.Context, jobId string, name string) (*ImageResponse, error)
+	GetContentImage(ctx context.Context, jobId string, name string) (*ImageResponse, error)
+	GetResultImage(ctx context.Context, jobId string, name string) (*ImageResponse, error)
+	GetRawResultImage(ctx context.Context, jobId string, name string) (*ImageResponse, error)
+	GetProgressImage(ctx context.Context, jobId string, name string, index int) (*ImageResponse, error)
+	GetTimelapse(ctx context.Context, jobId string, name string) (*ImageResponse, error)
+	GetLosses(ctx context.Context, jobId string, name string) (*LossesResponse, error)
+	GetLogs(ctx context.Context, jobId string, name string) (*LogsResponse, error)
 }
 
 type Closer interface {
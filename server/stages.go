@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Bounds on the renders a job may ask for
+const (
+	maxStages        = 8
+	minStageSize     = 64
+	maxNumIterations = 10000
+)
+
+// checkStages makes sure the stages and iteration counts of a job make sense
+func checkStages(params *pb.JobParameters) error {
+	if params == nil {
+		return nil
+	}
+
+	if params.NumIterations < 0 || params.NumIterations > maxNumIterations {
+		return grpc.Errorf(codes.InvalidArgument, "Jobs can run between 1 and %d iterations", maxNumIterations)
+	}
+
+	if len(params.Stages) == 0 {
+		return nil
+	}
+	if len(params.Stages) > maxStages {
+		return grpc.Errorf(codes.InvalidArgument, "Jobs can have at most %d stages", maxStages)
+	}
+	if t := params.GetTiling(); t != nil && t.TileSize > 0 {
+		return grpc.Errorf(codes.InvalidArgument, "Staged jobs can't be tiled")
+	}
+
+	for i, stage := range params.Stages {
+		if stage == nil || stage.ImageSize < minStageSize {
+			return grpc.Errorf(codes.InvalidArgument, "Stage %d must be at least %d pixels", i, minStageSize)
+		}
+		if stage.NumIterations < 0 || stage.NumIterations > maxNumIterations {
+			return grpc.Errorf(codes.InvalidArgument, "Stage %d can run between 1 and %d iterations", i, maxNumIterations)
+		}
+	}
+	return nil
+}
+
+// scaledSize is the size of a w×h image once the engine scales its largest
+// side to size
+func scaledSize(w, h, size int) (int, int) {
+	if w <= 0 || h <= 0 {
+		return size, size
+	}
+	if w > h {
+		return size, h * size / w
+	}
+	return w * size / h, size
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCheckStages(t *testing.T) {
+	stages := func(sizes ...int32) []*pb.Stage {
+		var r []*pb.Stage
+		for _, size := range sizes {
+			r = append(r, &pb.Stage{ImageSize: size})
+		}
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		params *pb.JobParameters
+		ok     bool
+	}{
+		{"no parameters", nil, true},
+		{"no stages", &pb.JobParameters{NumIterations: 500}, true},
+		{"stages", &pb.JobParameters{Stages: stages(256, 512, 1024)}, true},
+		{"stage iterations", &pb.JobParameters{Stages: []*pb.Stage{{ImageSize: 256, NumIterations: maxNumIterations}}}, true},
+		{"negative iterations", &pb.JobParameters{NumIterations: -1}, false},
+		{"too many iterations", &pb.JobParameters{NumIterations: maxNumIterations + 1}, false},
+		{"too many stages", &pb.JobParameters{Stages: stages(64, 64, 64, 64, 64, 64, 64, 64, 64)}, false},
+		{"small stage", &pb.JobParameters{Stages: stages(256, 32)}, false},
+		{"missing stage", &pb.JobParameters{Stages: []*pb.Stage{{ImageSize: 256}, nil}}, false},
+		{"stage with too many iterations", &pb.JobParameters{Stages: []*pb.Stage{{ImageSize: 256, NumIterations: maxNumIterations + 1}}}, false},
+		{"tiled", &pb.JobParameters{Stages: stages(256), Tiling: &pb.Tiling{TileSize: 128}}, false},
+	}
+
+	for _, tt := range tests {
+		err := checkStages(tt.params)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", tt.name, err)
+		}
+	}
+}
+
+func TestScaledSize(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{400, 200, 100, 100, 50},
+		{200, 400, 100, 50, 100},
+		{300, 300, 512, 512, 512},
+		{1000, 3, 100, 100, 0},
+		{0, 0, 256, 256, 256},
+	}
+
+	for _, tt := range tests {
+		w, h := scaledSize(tt.w, tt.h, tt.size)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("scaledSize(%d, %d, %d) = %dx%d, want %dx%d", tt.w, tt.h, tt.size, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestStagedJob(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 64, 64, color.RGBA{0, 0, 200, 255})},
+		Content: &pb.InputImage{Title: "cat", Image: uniformPNG(t, 256, 128, color.RGBA{200, 0, 0, 255})},
+		Params: &pb.JobParameters{
+			NumIterations: 300,
+			Stages:        []*pb.Stage{{ImageSize: 64, NumIterations: 500}, {ImageSize: 128}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := [][]byte{uniformPNG(t, 64, 32, color.RGBA{1, 0, 0, 255}), uniformPNG(t, 128, 64, color.RGBA{2, 0, 0, 255})}
+	for i, want := range []struct {
+		size, iterations int32
+		init             []byte
+	}{{64, 500, nil}, {128, 300, results[0]}} {
+		if len(s.ParentJobs) != 1 || len(s.PendingJobs) != 1 {
+			t.Fatalf("stage %d: %d parent and %d pending jobs, want the next stage queued", i, len(s.ParentJobs), len(s.PendingJobs))
+		}
+		job, err := s.RequestJob(ctx, &pb.JobRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Params.ImageSize != want.size || job.Params.NumIterations != want.iterations || len(job.Params.Stages) != 0 {
+			t.Errorf("stage %d rendered at %d for %d iterations in %d stages", i, job.Params.ImageSize, job.Params.NumIterations, len(job.Params.Stages))
+		}
+		if want.init == nil && job.Init != nil {
+			t.Errorf("stage %d started from an image", i)
+		}
+		if want.init != nil && (job.Init == nil || !bytes.Equal(job.Init.Image, want.init) || job.StartIteration != 0) {
+			t.Errorf("stage %d didn't start from the previous result", i)
+		}
+
+		_, err = s.ProgressReport(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1, ProgressCount: 100, Image: results[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 2, Image: results[i]})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var parent *Job
+	var parentKey jobKey
+	for k, v := range s.CompletedJobs {
+		if k.Name == "cat" {
+			parent, parentKey = v, k
+		}
+	}
+	if parent == nil {
+		t.Fatalf("staged job not completed")
+	}
+	if !bytes.Equal(parent.Result, results[1]) {
+		t.Errorf("completed with another result than that of the last stage")
+	}
+
+	//Progress images of every stage are kept in order
+	for i, want := range results {
+		img, err := s.GetProgressImage(ctx, parentKey.ID, parentKey.Name, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(img.Image, want) {
+			t.Errorf("progress image %d is not that of stage %d", i, i)
+		}
+	}
+}
+
+func TestStagedJobStatus(t *testing.T) {
+	j := &Job{Stages: []*pb.Stage{{}, {}, {}}, Children: []jobKey{{}, {}}}
+	if got := j.status(""); got != "Rendering stage 2 of 3" {
+		t.Errorf("got %q", got)
+	}
+	if got := j.status("Failed"); got != "Failed" {
+		t.Errorf("final state shown as %q", got)
+	}
+	if got := (&Job{Tiling: &Tiling{}}).status(""); got != "Rendering tiles" {
+		t.Errorf("tiled job shown as %q", got)
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Bounds on the grid a sweep may ask for
+const (
+	maxSweepAxes  = 2
+	maxSweepSteps = 10
+	maxSweepJobs  = 64
+)
+
+// Layout of contact sheets
+const (
+	sheetCellSize   = 384
+	sheetPadding    = 8
+	sheetLineHeight = 13
+)
+
+// Sweep is a grid of jobs trying values of one or two parameters. Jobs are
+// stored row by row.
+type Sweep struct {
+	Labels  []string
+	Columns int
+}
+
+// checkSweep makes sure the grid of a sweep can be rendered
+func checkSweep(params *pb.JobParameters) error {
+	axes := params.GetSweep().GetAxes()
+	if len(axes) == 0 {
+		return nil
+	}
+
+	if len(axes) > maxSweepAxes {
+		return grpc.Errorf(codes.InvalidArgument, "Sweeps can vary at most %d parameters", maxSweepAxes)
+	}
+	if t := params.GetTiling(); (t != nil && t.TileSize > 0) || len(params.Stages) > 0 || postProcesses(params.PostProcess) {
+		return grpc.Errorf(codes.InvalidArgument, "Sweeps can't be tiled, staged or post-processed")
+	}
+
+	jobs := 1
+	for i, a := range axes {
+		if a == nil || a.Parameter == pb.SweepParameter_SWEEP_UNKNOWN {
+			return grpc.Errorf(codes.InvalidArgument, "Sweep axis %d has no parameter", i)
+		}
+		if _, ok := pb.SweepParameter_name[int32(a.Parameter)]; !ok {
+			return grpc.Errorf(codes.InvalidArgument, "Sweep axis %d has unknown parameter %d", i, a.Parameter)
+		}
+		if i > 0 && a.Parameter == axes[0].Parameter {
+			return grpc.Errorf(codes.InvalidArgument, "Sweep axes vary the same parameter")
+		}
+		if a.Steps < 1 || a.Steps > maxSweepSteps {
+			return grpc.Errorf(codes.InvalidArgument, "Sweep axis %d must have between 1 and %d steps", i, maxSweepSteps)
+		}
+		//The engine takes a weight of 0 as unset and uses its default
+		if a.From <= 0 || a.To <= 0 {
+			return grpc.Errorf(codes.InvalidArgument, "Sweep axis %d must only try values above 0", i)
+		}
+		jobs *= int(a.Steps)
+	}
+
+	if jobs > maxSweepJobs {
+		return grpc.Errorf(codes.InvalidArgument, "The sweep needs %d jobs, at most %d are allowed", jobs, maxSweepJobs)
+	}
+	return nil
+}
+
+// sweepValues lists the values tried along an axis
+func sweepValues(a *pb.SweepAxis) []float64 {
+	n := int(a.Steps)
+	if n <= 1 {
+		return []float64{a.From}
+	}
+
+	values := make([]float64, n)
+	for i := range values {
+		t := float64(i) / float64(n-1)
+		if a.Logarithmic {
+			values[i] = a.From * math.Pow(a.To/a.From, t)
+		} else {
+			values[i] = a.From + (a.To-a.From)*t
+		}
+	}
+	return values
+}
+
+// sweepParams returns the parameters of the job at a point of the grid and
+// the label it gets on the contact sheet
+func sweepParams(params *pb.JobParameters, axes []*pb.SweepAxis, values []float64) (*pb.JobParameters, string) {
+	p := *params
+	p.Sweep = nil
+
+	var labels []string
+	for i, a := range axes {
+		switch a.Parameter {
+		case pb.SweepParameter_CONTENT_WEIGHT:
+			p.ContentWeight = values[i]
+		case pb.SweepParameter_STYLE_WEIGHT:
+			p.StyleWeight = values[i]
+		case pb.SweepParameter_STYLE_SCALE:
+			p.StyleScale = values[i]
+		case pb.SweepParameter_TV_WEIGHT:
+			p.TvWeight = values[i]
+		}
+		labels = append(labels, fmt.Sprintf("%s=%.3g", strings.ToLower(a.Parameter.String()), values[i]))
+	}
+	return &p, strings.Join(labels, "\n")
+}
+
+// contactSheet lays the results of a sweep out in its grid, each one scaled
+// down and labelled with the values it was rendered with
+func (sw *Sweep) contactSheet(results [][]byte) ([]byte, error) {
+	thumbs := make([]image.Image, len(results))
+	cellW, cellH := 0, 0
+	for i, b := range results {
+		img, _, err := image.Decode(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("Result %d: %v", i, err)
+		}
+		thumbs[i] = fit(img, sheetCellSize)
+
+		tb := thumbs[i].Bounds()
+		if tb.Dx() > cellW {
+			cellW = tb.Dx()
+		}
+		if tb.Dy() > cellH {
+			cellH = tb.Dy()
+		}
+	}
+
+	lines := 0
+	for _, l := range sw.Labels {
+		if n := strings.Count(l, "\n") + 1; n > lines {
+			lines = n
+		}
+	}
+	labelH := lines*sheetLineHeight + sheetPadding
+
+	rows := (len(thumbs) + sw.Columns - 1) / sw.Columns
+	sheet := image.NewRGBA(image.Rect(0, 0,
+		sw.Columns*(cellW+sheetPadding)+sheetPadding,
+		rows*(cellH+labelH+sheetPadding)+sheetPadding))
+	draw.Draw(sheet, sheet.Bounds(), image.White, image.ZP, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  sheet,
+		Src:  image.Black,
+		Face: basicfont.Face7x13,
+	}
+
+	for i, thumb := range thumbs {
+		x := sheetPadding + (i%sw.Columns)*(cellW+sheetPadding)
+		y := sheetPadding + (i/sw.Columns)*(cellH+labelH+sheetPadding)
+
+		tb := thumb.Bounds()
+		draw.Draw(sheet, image.Rect(x, y, x+tb.Dx(), y+tb.Dy()), thumb, tb.Min, draw.Src)
+
+		for n, line := range strings.Split(sw.Labels[i], "\n") {
+			d.Dot = fixed.P(x, y+cellH+(n+1)*sheetLineHeight)
+			d.DrawString(line)
+		}
+	}
+
+	return encodeImage(sheet, pb.ImageFormat_PNG)
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+)
+
+func TestSweepValues(t *testing.T) {
+	tests := []struct {
+		name string
+		axis *pb.SweepAxis
+		want []float64
+	}{
+		{"single step", &pb.SweepAxis{From: 5, To: 50, Steps: 1}, []float64{5}},
+		{"linear", &pb.SweepAxis{From: 0.5, To: 2, Steps: 4}, []float64{0.5, 1, 1.5, 2}},
+		{"logarithmic", &pb.SweepAxis{From: 10, To: 1000, Steps: 3, Logarithmic: true}, []float64{10, 100, 1000}},
+		{"descending", &pb.SweepAxis{From: 3, To: 1, Steps: 3}, []float64{3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		got := sweepValues(tt.axis)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCheckSweep(t *testing.T) {
+	axis := func(p pb.SweepParameter, from, to float64, steps int32, log bool) *pb.SweepAxis {
+		return &pb.SweepAxis{Parameter: p, From: from, To: to, Steps: steps, Logarithmic: log}
+	}
+	sweep := func(axes ...*pb.SweepAxis) *pb.JobParameters {
+		return &pb.JobParameters{Sweep: &pb.Sweep{Axes: axes}}
+	}
+
+	tests := []struct {
+		name   string
+		params *pb.JobParameters
+		ok     bool
+	}{
+		{"no sweep", &pb.JobParameters{}, true},
+		{"one axis", sweep(axis(pb.SweepParameter_STYLE_WEIGHT, 10, 1000, 5, true)), true},
+		{"two axes", sweep(
+			axis(pb.SweepParameter_STYLE_WEIGHT, 10, 1000, 5, true),
+			axis(pb.SweepParameter_STYLE_SCALE, 0.5, 2, 4, false)), true},
+		{"three axes", sweep(
+			axis(pb.SweepParameter_STYLE_WEIGHT, 10, 1000, 2, true),
+			axis(pb.SweepParameter_STYLE_SCALE, 0.5, 2, 2, false),
+			axis(pb.SweepParameter_TV_WEIGHT, 0.001, 0.01, 2, false)), false},
+		{"no parameter", sweep(axis(pb.SweepParameter_SWEEP_UNKNOWN, 1, 2, 2, false)), false},
+		{"unknown parameter", sweep(axis(pb.SweepParameter(42), 1, 2, 2, false)), false},
+		{"same parameter twice", sweep(
+			axis(pb.SweepParameter_TV_WEIGHT, 0.001, 0.01, 2, false),
+			axis(pb.SweepParameter_TV_WEIGHT, 0.001, 0.01, 2, false)), false},
+		{"no steps", sweep(axis(pb.SweepParameter_STYLE_WEIGHT, 10, 1000, 0, false)), false},
+		{"too many steps", sweep(axis(pb.SweepParameter_STYLE_WEIGHT, 10, 1000, maxSweepSteps+1, false)), false},
+		{"too many jobs", sweep(
+			axis(pb.SweepParameter_STYLE_WEIGHT, 10, 1000, maxSweepSteps, true),
+			axis(pb.SweepParameter_STYLE_SCALE, 0.5, 2, maxSweepSteps, false)), false},
+		{"negative", sweep(axis(pb.SweepParameter_CONTENT_WEIGHT, -1, 5, 3, false)), false},
+		{"zero log", sweep(axis(pb.SweepParameter_STYLE_WEIGHT, 0, 1000, 3, true)), false},
+		//The engine would take these as unset and render with its default
+		{"zero weight", sweep(axis(pb.SweepParameter_TV_WEIGHT, 0, 0.01, 3, false)), false},
+		{"zero end", sweep(axis(pb.SweepParameter_CONTENT_WEIGHT, 10, 0, 3, false)), false},
+		{"tiled", &pb.JobParameters{
+			Sweep:  &pb.Sweep{Axes: []*pb.SweepAxis{axis(pb.SweepParameter_STYLE_WEIGHT, 10, 1000, 5, true)}},
+			Tiling: &pb.Tiling{TileSize: 512},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		err := checkSweep(tt.params)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v, want ok %t", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestSweepParams(t *testing.T) {
+	axes := []*pb.SweepAxis{
+		{Parameter: pb.SweepParameter_STYLE_WEIGHT},
+		{Parameter: pb.SweepParameter_TV_WEIGHT},
+	}
+	params := &pb.JobParameters{
+		ContentWeight: 5,
+		Sweep:         &pb.Sweep{Axes: axes},
+	}
+
+	p, label := sweepParams(params, axes, []float64{100, 0.001})
+	if p.StyleWeight != 100 || p.TvWeight != 0.001 || p.ContentWeight != 5 {
+		t.Errorf("got weights %v, %v, %v", p.StyleWeight, p.TvWeight, p.ContentWeight)
+	}
+	if p.Sweep != nil {
+		t.Errorf("sweep kept in the parameters of a job of the grid")
+	}
+	if params.StyleWeight != 0 {
+		t.Errorf("parameters of the sweep changed")
+	}
+	if want := "style_weight=100\ntv_weight=0.001"; label != want {
+		t.Errorf("got label %q, want %q", label, want)
+	}
+}
+
+func TestContactSheet(t *testing.T) {
+	sw := &Sweep{Columns: 2, Labels: []string{"a=1", "a=2", "a=1\nb=2", "a=2\nb=2"}}
+	results := [][]byte{
+		uniformPNG(t, 100, 50, color.RGBA{200, 0, 0, 255}),
+		uniformPNG(t, 100, 50, color.RGBA{0, 200, 0, 255}),
+		uniformPNG(t, 100, 50, color.RGBA{0, 0, 200, 255}),
+		uniformPNG(t, 800, 400, color.RGBA{200, 200, 0, 255}),
+	}
+
+	b, err := sw.contactSheet(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//Large results are scaled down to the cell size
+	cellW, cellH := sheetCellSize, sheetCellSize/2
+	labelH := 2*sheetLineHeight + sheetPadding
+	wantW := 2*(cellW+sheetPadding) + sheetPadding
+	wantH := 2*(cellH+labelH+sheetPadding) + sheetPadding
+	if b := sheet.Bounds(); b.Dx() != wantW || b.Dy() != wantH {
+		t.Errorf("sheet is %dx%d, want %dx%d", b.Dx(), b.Dy(), wantW, wantH)
+	}
+
+	//Results are laid out row by row
+	cells := map[image.Point]color.RGBA{
+		{sheetPadding, sheetPadding}:                                        {200, 0, 0, 255},
+		{2*sheetPadding + cellW, sheetPadding}:                              {0, 200, 0, 255},
+		{sheetPadding, 2*sheetPadding + cellH + labelH}:                     {0, 0, 200, 255},
+		{2*sheetPadding + cellW + 10, 2*sheetPadding + cellH + labelH + 10}: {200, 200, 0, 255},
+		{0, 0}: {255, 255, 255, 255},
+	}
+	for p, want := range cells {
+		if got := color.RGBAModel.Convert(sheet.At(p.X, p.Y)); got != want {
+			t.Errorf("pixel %v is %v, want %v", p, got, want)
+		}
+	}
+
+	//Labels are drawn under each result
+	dark := 0
+	for y := sheetPadding + cellH; y < sheetPadding+cellH+labelH; y++ {
+		for x := sheetPadding; x < sheetPadding+cellW; x++ {
+			if r, _, _, _ := sheet.At(x, y).RGBA(); r < 0x8000 {
+				dark++
+			}
+		}
+	}
+	if dark == 0 {
+		t.Errorf("no label drawn")
+	}
+
+	if _, err := sw.contactSheet([][]byte{results[0], nil}); err == nil {
+		t.Errorf("laid out a missing result")
+	}
+}
+
+func TestSweepJob(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 8, 8, color.RGBA{0, 0, 200, 255})},
+		Content: &pb.InputImage{Title: "cat", Image: uniformPNG(t, 8, 8, color.RGBA{200, 0, 0, 255})},
+		Params: &pb.JobParameters{Sweep: &pb.Sweep{Axes: []*pb.SweepAxis{
+			{Parameter: pb.SweepParameter_STYLE_WEIGHT, From: 10, To: 1000, Steps: 3, Logarithmic: true},
+			{Parameter: pb.SweepParameter_TV_WEIGHT, From: 0.001, To: 0.002, Steps: 2},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.ParentJobs) != 1 || len(s.PendingJobs) != 6 {
+		t.Fatalf("got %d parent and %d pending jobs, want a job for each of the 6 points", len(s.ParentJobs), len(s.PendingJobs))
+	}
+
+	seen := make(map[[2]float64]bool)
+	for i := 0; i < 6; i++ {
+		job, err := s.RequestJob(ctx, &pb.JobRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[[2]float64{job.Params.StyleWeight, job.Params.TvWeight}] = true
+		_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1,
+			Image: uniformPNG(t, 8, 8, color.RGBA{0, 200, 0, 255})})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(seen) != 6 {
+		t.Errorf("rendered %d different points, want 6", len(seen))
+	}
+
+	var parent *Job
+	for i := 0; i < 100 && parent == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		s.lock.RLock()
+		for k, v := range s.CompletedJobs {
+			if k.Name == "cat" {
+				parent = v
+			}
+		}
+		s.lock.RUnlock()
+	}
+	if parent == nil {
+		t.Fatalf("sweep not completed")
+	}
+	if _, err := png.Decode(bytes.NewReader(parent.Result)); err != nil {
+		t.Errorf("contact sheet unreadable: %v", err)
+	}
+}
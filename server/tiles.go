@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Bounds on the tiling a job may ask for
+const (
+	minTileSize = 64
+	maxTiles    = 256
+)
+
+// Tiling is how the content of a tiled job was split. Tiles are stored row
+// by row.
+type Tiling struct {
+	Rects   []image.Rectangle
+	Columns int
+}
+
+// tileSize returns the tile size and overlap a job asks for. A zero size
+// means the job isn't tiled.
+func tileSize(params *pb.JobParameters) (int, int) {
+	t := params.GetTiling()
+	if t == nil || t.TileSize <= 0 {
+		return 0, 0
+	}
+
+	overlap := int(t.Overlap)
+	if overlap == 0 {
+		overlap = int(t.TileSize) / 8
+	}
+	return int(t.TileSize), overlap
+}
+
+// checkTiling makes sure the tiles of a job can be stitched together
+func checkTiling(params *pb.JobParameters) error {
+	t := params.GetTiling()
+	if t == nil || t.TileSize == 0 {
+		return nil
+	}
+
+	if t.TileSize < minTileSize {
+		return grpc.Errorf(codes.InvalidArgument, "Tiles must be at least %d pixels", minTileSize)
+	}
+	if t.Overlap < 0 || t.Overlap >= t.TileSize/2 {
+		return grpc.Errorf(codes.InvalidArgument, "Tile overlap must be between 0 and %d pixels", t.TileSize/2-1)
+	}
+	return nil
+}
+
+// splitTiles covers a w×h image with tiles of at most size pixels a side,
+// overlapping their neighbours by at least overlap pixels
+func splitTiles(w, h, size, overlap int) *Tiling {
+	xs := tileOffsets(w, size, overlap)
+	ys := tileOffsets(h, size, overlap)
+
+	t := &Tiling{Columns: len(xs)}
+	for _, y := range ys {
+		for _, x := range xs {
+			t.Rects = append(t.Rects, image.Rect(x, y, minInt(x+size, w), minInt(y+size, h)))
+		}
+	}
+	return t
+}
+
+// tileOffsets spreads tiles evenly along a side so the last one ends at its
+// edge
+func tileOffsets(length, size, overlap int) []int {
+	if length <= size {
+		return []int{0}
+	}
+
+	step := size - overlap
+	n := (length - overlap + step - 1) / step
+	offsets := make([]int, n)
+	for i := range offsets {
+		offsets[i] = i * (length - size) / (n - 1)
+	}
+	return offsets
+}
+
+// crop returns the part of an image a tile covers
+func crop(img image.Image, r image.Rectangle) image.Image {
+	return toRGBA(img).SubImage(r)
+}
+
+// stitch blends the images of the tiles into a w×h image. Tiles are laid
+// over their left and top neighbours, fading in across the pixels they
+// share so no seams show. Tiles that came back in a different size are
+// scaled to fit, missing ones are left blank.
+func (t *Tiling) stitch(w, h int, tiles [][]byte) (*image.RGBA, error) {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for i, r := range t.Rects {
+		if tiles[i] == nil {
+			continue
+		}
+		decoded, _, err := image.Decode(bytes.NewReader(tiles[i]))
+		if err != nil {
+			return nil, err
+		}
+		src := toRGBA(decoded)
+		sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+		var left, top int
+		if i%t.Columns != 0 {
+			left = t.Rects[i-1].Max.X - r.Min.X
+		}
+		if i >= t.Columns {
+			top = t.Rects[i-t.Columns].Max.Y - r.Min.Y
+		}
+
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			sy := (y - r.Min.Y) * sh / r.Dy()
+			ay := fade(y-r.Min.Y, top)
+			for x := r.Min.X; x < r.Max.X; x++ {
+				sx := (x - r.Min.X) * sw / r.Dx()
+				a := ay * fade(x-r.Min.X, left)
+
+				si := src.PixOffset(src.Bounds().Min.X+sx, src.Bounds().Min.Y+sy)
+				di := dst.PixOffset(x, y)
+				for c := 0; c < 4; c++ {
+					dst.Pix[di+c] = uint8(float64(dst.Pix[di+c])*(1-a) + float64(src.Pix[si+c])*a + 0.5)
+				}
+			}
+		}
+	}
+	return dst, nil
+}
+
+// fade is the opacity of a tile d pixels into an overlap of the given width
+func fade(d, overlap int) float64 {
+	if d >= overlap {
+		return 1
+	}
+	return float64(d+1) / float64(overlap+1)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
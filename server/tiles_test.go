@@ -0,0 +1,264 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestTileOffsets(t *testing.T) {
+	tests := []struct {
+		length, size, overlap int
+		want                  []int
+	}{
+		{100, 512, 64, []int{0}},
+		{512, 512, 64, []int{0}},
+		{513, 512, 64, []int{0, 1}},
+		{1000, 512, 64, []int{0, 244, 488}},
+		{1024, 512, 64, []int{0, 256, 512}},
+		{2000, 512, 0, []int{0, 496, 992, 1488}},
+	}
+
+	for _, tt := range tests {
+		got := tileOffsets(tt.length, tt.size, tt.overlap)
+		if len(got) != len(tt.want) {
+			t.Errorf("tileOffsets(%d, %d, %d) = %v, want %v", tt.length, tt.size, tt.overlap, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("tileOffsets(%d, %d, %d) = %v, want %v", tt.length, tt.size, tt.overlap, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSplitTiles(t *testing.T) {
+	tests := []struct {
+		w, h, size, overlap int
+		columns, tiles      int
+	}{
+		{300, 200, 512, 64, 1, 1},
+		{1000, 400, 512, 64, 3, 3},
+		{1024, 1024, 512, 64, 3, 9},
+		{1920, 1080, 256, 32, 9, 45},
+	}
+
+	for _, tt := range tests {
+		tiling := splitTiles(tt.w, tt.h, tt.size, tt.overlap)
+		if tiling.Columns != tt.columns || len(tiling.Rects) != tt.tiles {
+			t.Errorf("%dx%d in %d tiles: got %d tiles in %d columns, want %d in %d",
+				tt.w, tt.h, tt.size, len(tiling.Rects), tiling.Columns, tt.tiles, tt.columns)
+			continue
+		}
+
+		covered := make([]bool, tt.w*tt.h)
+		for i, r := range tiling.Rects {
+			if r.Dx() > tt.size || r.Dy() > tt.size {
+				t.Errorf("%dx%d: tile %d is %v, larger than %d", tt.w, tt.h, i, r, tt.size)
+			}
+			if i%tiling.Columns != 0 && tiling.Rects[i-1].Max.X-r.Min.X < tt.overlap {
+				t.Errorf("%dx%d: tile %d overlaps its left neighbour by less than %d", tt.w, tt.h, i, tt.overlap)
+			}
+			if i >= tiling.Columns && tiling.Rects[i-tiling.Columns].Max.Y-r.Min.Y < tt.overlap {
+				t.Errorf("%dx%d: tile %d overlaps its top neighbour by less than %d", tt.w, tt.h, i, tt.overlap)
+			}
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					covered[y*tt.w+x] = true
+				}
+			}
+		}
+		for i, c := range covered {
+			if !c {
+				t.Errorf("%dx%d: pixel %d,%d not covered", tt.w, tt.h, i%tt.w, i/tt.w)
+				break
+			}
+		}
+	}
+}
+
+// uniformPNG encodes a w×h image of a single colour
+func uniformPNG(t *testing.T, w, h int, c color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestStitch(t *testing.T) {
+	red := color.RGBA{200, 0, 0, 255}
+	blue := color.RGBA{0, 0, 200, 255}
+	tiling := splitTiles(100, 40, 60, 20)
+
+	tests := []struct {
+		name  string
+		tiles [][]byte
+		//Expected colour at x, y = 20
+		want map[int]color.RGBA
+	}{
+		{
+			name:  "same colour",
+			tiles: [][]byte{uniformPNG(t, 60, 40, red), uniformPNG(t, 60, 40, red)},
+			want:  map[int]color.RGBA{0: red, 50: red, 99: red},
+		},
+		{
+			name:  "scaled tile",
+			tiles: [][]byte{uniformPNG(t, 60, 40, red), uniformPNG(t, 30, 20, red)},
+			want:  map[int]color.RGBA{0: red, 50: red, 99: red},
+		},
+		{
+			name:  "blended overlap",
+			tiles: [][]byte{uniformPNG(t, 60, 40, red), uniformPNG(t, 60, 40, blue)},
+			want:  map[int]color.RGBA{0: red, 39: red, 49: {105, 0, 95, 255}, 60: blue, 99: blue},
+		},
+		{
+			name:  "missing tile",
+			tiles: [][]byte{nil, uniformPNG(t, 60, 40, blue)},
+			want:  map[int]color.RGBA{0: {}, 39: {}, 60: blue},
+		},
+	}
+
+	for _, tt := range tests {
+		img, err := tiling.stitch(100, 40, tt.tiles)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 40 {
+			t.Errorf("%s: got size %v", tt.name, b)
+			continue
+		}
+		for x, want := range tt.want {
+			if got := img.RGBAAt(x, 20); got != want {
+				t.Errorf("%s: pixel %d is %v, want %v", tt.name, x, got, want)
+			}
+		}
+	}
+
+	if _, err := tiling.stitch(100, 40, [][]byte{[]byte("not an image"), nil}); err == nil {
+		t.Errorf("stitched an unreadable tile")
+	}
+}
+
+// createTiled submits a 200×100 job rendered in tiles of 128 pixels
+func createTiled(t *testing.T, s *memoryServer) {
+	_, err := s.CreateFullJob(context.Background(), &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 64, 64, color.RGBA{0, 0, 200, 255})},
+		Content: &pb.InputImage{Title: "cat", Image: uniformPNG(t, 200, 100, color.RGBA{200, 0, 0, 255})},
+		Params: &pb.JobParameters{
+			ImageSize: 200,
+			Tiling:    &pb.Tiling{TileSize: 128, Overlap: 16},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTiledJob(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	createTiled(t, s)
+	if len(s.ParentJobs) != 1 || len(s.PendingJobs) != 2 {
+		t.Fatalf("got %d tiled jobs and %d pending, want 1 split in 2 tiles", len(s.ParentJobs), len(s.PendingJobs))
+	}
+
+	for i := 0; i < 2; i++ {
+		job, err := s.RequestJob(ctx, &pb.JobRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Params.Tiling != nil || job.Params.ImageSize > 128 {
+			t.Errorf("tile %q sent with tiling %v at size %d", job.Name, job.Params.Tiling, job.Params.ImageSize)
+		}
+		img, err := checkImage("tile", job.Content.Image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1,
+			Image: uniformPNG(t, img.Width, img.Height, color.RGBA{0, 200, 0, 255})})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//Stitching runs in the background
+	var parent *Job
+	for i := 0; i < 100 && parent == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		s.lock.RLock()
+		for k, v := range s.CompletedJobs {
+			if k.Name == "cat" {
+				parent = v
+			}
+		}
+		s.lock.RUnlock()
+	}
+	if parent == nil {
+		t.Fatalf("tiled job not completed")
+	}
+	img, err := png.Decode(bytes.NewReader(parent.Result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+		t.Errorf("stitched to %v, want 200x100", b)
+	}
+	if c := color.RGBAModel.Convert(img.At(100, 50)).(color.RGBA); c != (color.RGBA{0, 200, 0, 255}) {
+		t.Errorf("stitched pixel is %v, want the colour of the tiles", c)
+	}
+}
+
+func TestTileFailed(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	createTiled(t, s)
+	job, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Reason: "bad input", Permanent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.ParentJobs) != 0 || len(s.PendingJobs) != 0 || len(s.FailedJobs) != 3 {
+		t.Errorf("got %d tiled, %d pending and %d failed jobs, want the job and both tiles failed",
+			len(s.ParentJobs), len(s.PendingJobs), len(s.FailedJobs))
+	}
+}
+
+func TestCheckTiling(t *testing.T) {
+	valid := []*pb.Tiling{nil, {}, {TileSize: 64}, {TileSize: 512, Overlap: 255}}
+	for _, tiling := range valid {
+		if err := checkTiling(&pb.JobParameters{Tiling: tiling}); err != nil {
+			t.Errorf("%v: %v", tiling, err)
+		}
+	}
+	invalid := []*pb.Tiling{{TileSize: 32}, {TileSize: 512, Overlap: 256}, {TileSize: 512, Overlap: -1}}
+	for _, tiling := range invalid {
+		if err := checkTiling(&pb.JobParameters{Tiling: tiling}); grpc.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: got %v, want InvalidArgument", tiling, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+)
+
+// How long each frame of a time-lapse stays up, in hundredths of a second
+const (
+	timelapseContentDelay = 100
+	timelapsePartialDelay = 30
+	timelapseResultDelay  = 200
+)
+
+// maxTimelapseFrames bounds the partial results shown in a time-lapse. Jobs
+// with more have some skipped.
+const maxTimelapseFrames = 60
+
+// timelapse plays the content of a job, its partial results in order and its
+// result, if it has one yet, as an animated GIF. Every frame is scaled to the
+// size of the last one.
+func timelapse(content []byte, partials [][]byte, result []byte) ([]byte, error) {
+	if len(partials) > maxTimelapseFrames {
+		picked := make([][]byte, maxTimelapseFrames)
+		for i := range picked {
+			picked[i] = partials[i*len(partials)/maxTimelapseFrames]
+		}
+		partials = picked
+	}
+
+	frames := [][]byte{content}
+	delays := []int{timelapseContentDelay}
+	for _, p := range partials {
+		frames = append(frames, p)
+		delays = append(delays, timelapsePartialDelay)
+	}
+	if len(result) > 0 {
+		frames = append(frames, result)
+		delays = append(delays, timelapseResultDelay)
+	}
+	if len(frames) < 2 {
+		return nil, fmt.Errorf("Nothing has been rendered yet")
+	}
+
+	decoded := make([]image.Image, len(frames))
+	for i, b := range frames {
+		img, _, err := image.Decode(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("Frame %d: %v", i, err)
+		}
+		decoded[i] = img
+	}
+
+	last := decoded[len(decoded)-1].Bounds()
+	bounds := image.Rect(0, 0, last.Dx(), last.Dy())
+
+	g := &gif.GIF{}
+	for i, img := range decoded {
+		p := image.NewPaletted(bounds, palette.Plan9)
+		draw.FloydSteinberg.Draw(p, bounds, scaleTo(img, bounds.Dx(), bounds.Dy()), image.ZP)
+		g.Image = append(g.Image, p)
+		g.Delay = append(g.Delay, delays[i])
+	}
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, g)
+	return buf.Bytes(), err
+}
+
+// scaleTo resizes an image to w×h picking the nearest pixel. Time-lapse
+// frames only differ in size when a job is rendered in stages.
+func scaleTo(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	if b.Dx() == w && b.Dy() == h && b.Min == image.ZP {
+		return img
+	}
+
+	src := toRGBA(img)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := y * src.Bounds().Dy() / h
+		for x := 0; x < w; x++ {
+			sx := x * src.Bounds().Dx() / w
+			si := src.PixOffset(src.Bounds().Min.X+sx, src.Bounds().Min.Y+sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
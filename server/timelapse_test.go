@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+)
+
+func TestTimelapse(t *testing.T) {
+	content := uniformPNG(t, 32, 16, color.RGBA{200, 0, 0, 255})
+	partial := uniformPNG(t, 16, 8, color.RGBA{0, 200, 0, 255})
+	result := uniformPNG(t, 64, 32, color.RGBA{0, 0, 200, 255})
+
+	b, err := timelapse(content, [][]byte{partial, partial}, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDelays := []int{timelapseContentDelay, timelapsePartialDelay, timelapsePartialDelay, timelapseResultDelay}
+	if len(g.Image) != len(wantDelays) {
+		t.Fatalf("got %d frames, want %d", len(g.Image), len(wantDelays))
+	}
+	for i, frame := range g.Image {
+		if g.Delay[i] != wantDelays[i] {
+			t.Errorf("frame %d shown for %d, want %d", i, g.Delay[i], wantDelays[i])
+		}
+		if b := frame.Bounds(); b.Dx() != 64 || b.Dy() != 32 {
+			t.Errorf("frame %d is %v, want the size of the result", i, b)
+		}
+	}
+
+	//Without a result the latest partial sets the size
+	b, err = timelapse(content, [][]byte{partial}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err = gif.DecodeAll(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 2 || g.Image[0].Bounds().Dx() != 16 {
+		t.Errorf("got %d frames of %v", len(g.Image), g.Image[0].Bounds())
+	}
+}
+
+func TestTimelapseFrames(t *testing.T) {
+	content := uniformPNG(t, 4, 4, color.RGBA{200, 0, 0, 255})
+	partial := uniformPNG(t, 4, 4, color.RGBA{0, 200, 0, 255})
+
+	if _, err := timelapse(content, nil, nil); err == nil {
+		t.Errorf("put together a time-lapse of the content alone")
+	}
+	if _, err := timelapse(content, [][]byte{[]byte("broken")}, nil); err == nil {
+		t.Errorf("put together a time-lapse with a broken frame")
+	}
+
+	//Long renders have partials skipped
+	partials := make([][]byte, 3*maxTimelapseFrames)
+	for i := range partials {
+		partials[i] = partial
+	}
+	b, err := timelapse(content, partials, partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != maxTimelapseFrames+2 {
+		t.Errorf("got %d frames, want %d", len(g.Image), maxTimelapseFrames+2)
+	}
+}
+
+func TestScaleTo(t *testing.T) {
+	src := image.NewRGBA(image.Rect(10, 10, 12, 11))
+	src.Set(10, 10, color.RGBA{255, 0, 0, 255})
+	src.Set(11, 10, color.RGBA{0, 0, 255, 255})
+
+	got := toRGBA(scaleTo(src, 4, 2))
+	for x := 0; x < 4; x++ {
+		want := color.RGBA{255, 0, 0, 255}
+		if x >= 2 {
+			want = color.RGBA{0, 0, 255, 255}
+		}
+		for y := 0; y < 2; y++ {
+			if c := got.RGBAAt(x, y); c != want {
+				t.Errorf("pixel %d,%d is %v, want %v", x, y, c, want)
+			}
+		}
+	}
+
+	same := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	if scaleTo(same, 4, 2) != image.Image(same) {
+		t.Errorf("copied an image of the right size")
+	}
+}
+
+func TestGetTimelapse(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := s.CreateFullJob(ctx, &pb.CreateFullJobRequest{
+		Name:    "cat",
+		Style:   &pb.InputImage{Title: "wave", Image: uniformPNG(t, 8, 8, color.RGBA{0, 0, 200, 255})},
+		Content: &pb.InputImage{Title: "cat", Image: uniformPNG(t, 8, 8, color.RGBA{200, 0, 0, 255})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetTimelapse(ctx, job.Id, job.Name); err == nil {
+		t.Errorf("served a time-lapse before anything was rendered")
+	}
+
+	rendered := uniformPNG(t, 8, 8, color.RGBA{0, 200, 0, 255})
+	_, err = s.ProgressReport(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 1, ProgressCount: 100, Image: rendered})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := httptest.NewServer(NewAPIHandler(s))
+	defer api.Close()
+	resp, err := http.Get(api.URL + "/api/timelapse/cat/" + job.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/gif" {
+		t.Errorf("served %d as %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="cat_timelapse.gif"` {
+		t.Errorf("served as %q", got)
+	}
+
+	//The time-lapse of a completed job is saved with its results
+	_, err = s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name, AttemptId: job.AttemptId, Sequence: 2, Image: rendered})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := path.Join(getDirectory(s.OutputDir, job.Id, job.Name), "timelapse.gif")
+	var g *gif.GIF
+	for i := 0; i < 100 && g == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		saved, err := ioutil.ReadFile(filename)
+		if err == nil {
+			g, _ = gif.DecodeAll(bytes.NewReader(saved))
+		}
+	}
+	if g == nil {
+		t.Fatalf("time-lapse not saved")
+	}
+	if len(g.Image) != 3 {
+		t.Errorf("saved %d frames, want content, partial and result", len(g.Image))
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+var (
+	// HeartbeatInterval is how often registered workers are asked to check in
+	HeartbeatInterval = 15 * time.Second
+
+	// WorkerTimeout is how long a worker can go without being heard from
+	// before it is shown as offline and its jobs are handed to others
+	WorkerTimeout = 3 * HeartbeatInterval
+)
+
+func (w *Worker) currentState(now time.Time) pb.WorkerState {
+	if now.Sub(w.LastSeen) > WorkerTimeout {
+		return pb.WorkerState_WORKER_OFFLINE
+	}
+	return w.State
+}
+
+func (w *Worker) assign(slot int32, id string, name string) {
+	if s := w.slot(slot); s != nil {
+		s.State = pb.WorkerState_WORKER_BUSY
+		s.JobID = id
+		s.JobName = name
+	}
+	w.summarise(pb.WorkerState_WORKER_BUSY, id, name)
+}
+
+func (w *Worker) release(slot int32) {
+	if s := w.slot(slot); s != nil {
+		s.State = pb.WorkerState_WORKER_IDLE
+		s.JobID = ""
+		s.JobName = ""
+	}
+	w.summarise(pb.WorkerState_WORKER_IDLE, "", "")
+}
+
+// running tells whether the worker reports a slot running the job
+func (w *Worker) running(key jobKey) bool {
+	for _, s := range w.Slots {
+		if s.JobID == key.ID && s.JobName == key.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Worker) slot(index int32) *Slot {
+	for i := range w.Slots {
+		if w.Slots[i].Index == index {
+			return &w.Slots[i]
+		}
+	}
+	return nil
+}
+
+// summarise updates the worker state after a slot changed. Workers without
+// slots report a single job, which is given as the fallback.
+func (w *Worker) summarise(state pb.WorkerState, id string, name string) {
+	if len(w.Slots) == 0 {
+		w.State = state
+		w.JobID = id
+		w.JobName = name
+		return
+	}
+
+	for _, s := range w.Slots {
+		if s.State == pb.WorkerState_WORKER_BUSY {
+			w.State = pb.WorkerState_WORKER_BUSY
+			w.JobID = s.JobID
+			w.JobName = s.JobName
+			return
+		}
+	}
+
+	//Keep paused, drained and unhealthy workers as they are
+	if w.State == pb.WorkerState_WORKER_BUSY {
+		w.State = pb.WorkerState_WORKER_IDLE
+	}
+	w.JobID = ""
+	w.JobName = ""
+}
+
+// setSlots replaces the slots with those reported by the worker
+func (w *Worker) setSlots(slots []*pb.SlotInfo) {
+	w.Slots = make([]Slot, 0, len(slots))
+	for _, s := range slots {
+		w.Slots = append(w.Slots, Slot{
+			Index:   s.Index,
+			Device:  s.Device,
+			State:   s.State,
+			JobID:   s.JobId,
+			JobName: s.JobName,
+		})
+	}
+}
+
+// takeCommand returns the pending command, if any, and clears it so it is
+// only delivered once
+func (w *Worker) takeCommand() pb.WorkerCommand {
+	c := w.Command
+	w.Command = pb.WorkerCommand_COMMAND_NONE
+	return c
+}
+
+func (w *Worker) toProto(now time.Time) *pb.WorkerInfo {
+	return &pb.WorkerInfo{
+		Id:             w.ID,
+		Hostname:       w.Hostname,
+		Version:        w.Version,
+		Capabilities:   w.Capabilities,
+		State:          w.currentState(now),
+		JobId:          w.JobID,
+		JobName:        w.JobName,
+		RegisteredAt:   w.RegisteredAt.Unix(),
+		LastSeen:       w.LastSeen.Unix(),
+		JobsCompleted:  int32(w.JobsCompleted),
+		JobsFailed:     int32(w.JobsFailed),
+		PendingCommand: w.Command,
+		Slots:          w.slotsToProto(now),
+		Problem:        w.Problem,
+	}
+}
+
+func (w *Worker) slotsToProto(now time.Time) []*pb.SlotInfo {
+	offline := w.currentState(now) == pb.WorkerState_WORKER_OFFLINE
+
+	var r []*pb.SlotInfo
+	for _, s := range w.Slots {
+		state := s.State
+		if offline {
+			state = pb.WorkerState_WORKER_OFFLINE
+		}
+		r = append(r, &pb.SlotInfo{
+			Index:   s.Index,
+			Device:  s.Device,
+			State:   state,
+			JobId:   s.JobID,
+			JobName: s.JobName,
+		})
+	}
+	return r
+}
+
+func stateStatus(state pb.WorkerState) string {
+	switch state {
+	case pb.WorkerState_WORKER_IDLE:
+		return "Idle"
+	case pb.WorkerState_WORKER_BUSY:
+		return "Busy"
+	case pb.WorkerState_WORKER_OFFLINE:
+		return "Offline"
+	case pb.WorkerState_WORKER_PAUSED:
+		return "Paused"
+	case pb.WorkerState_WORKER_DRAINED:
+		return "Drained"
+	case pb.WorkerState_WORKER_UNHEALTHY:
+		return "Unhealthy"
+	}
+	return "Unknown"
+}
+
+func (w *Worker) toResponse(now time.Time) WorkerResponse {
+	status := stateStatus(w.currentState(now))
+
+	command := ""
+	if w.Command != pb.WorkerCommand_COMMAND_NONE {
+		command = w.Command.String()
+	}
+
+	var slots []SlotResponse
+	for _, s := range w.slotsToProto(now) {
+		slots = append(slots, SlotResponse{
+			Index:   s.Index,
+			Device:  s.Device,
+			Status:  stateStatus(s.State),
+			JobID:   s.JobId,
+			JobName: s.JobName,
+		})
+	}
+
+	return WorkerResponse{
+		ID:            w.ID,
+		Hostname:      w.Hostname,
+		Version:       w.Version,
+		Capabilities:  w.Capabilities,
+		Status:        status,
+		JobID:         w.JobID,
+		JobName:       w.JobName,
+		LastSeen:      w.LastSeen,
+		JobsCompleted: w.JobsCompleted,
+		JobsFailed:    w.JobsFailed,
+		Command:       command,
+		Slots:         slots,
+		Problem:       w.Problem,
+	}
+}
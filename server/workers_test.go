@@ -0,0 +1,320 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+)
+
+func TestWorkerRegistry(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := s.RegisterWorker(ctx, &pb.WorkerRegistration{}); err == nil {
+		t.Errorf("registered a worker without an ID")
+	}
+	if _, err := s.Heartbeat(ctx, &pb.WorkerHeartbeat{WorkerId: "w1"}); err == nil {
+		t.Errorf("heartbeat of an unregistered worker accepted")
+	}
+
+	r, err := s.RegisterWorker(ctx, &pb.WorkerRegistration{WorkerId: "w1", Hostname: "gpu-box", Version: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.HeartbeatInterval != int32(HeartbeatInterval/time.Second) {
+		t.Errorf("asked for heartbeats every %ds, want %s", r.HeartbeatInterval, HeartbeatInterval)
+	}
+
+	key := jobKey{ID: "job", Name: "cat"}
+	s.PendingJobs[key] = &Job{Name: "cat"}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{WorkerId: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := s.Workers["w1"]
+	if w.State != pb.WorkerState_WORKER_BUSY || w.JobID != job.Id {
+		t.Errorf("worker is %s on %q after taking %q", w.State, w.JobID, job.Id)
+	}
+
+	if _, err := s.CompleteJob(ctx, &pb.JobResult{Id: job.Id, Name: job.Name}); err != nil {
+		t.Fatal(err)
+	}
+	if w.State != pb.WorkerState_WORKER_IDLE || w.JobID != "" || w.JobsCompleted != 1 {
+		t.Errorf("worker is %s on %q with %d completed after completing its job", w.State, w.JobID, w.JobsCompleted)
+	}
+
+	//Heartbeats report what the worker is doing
+	_, err = s.Heartbeat(ctx, &pb.WorkerHeartbeat{WorkerId: "w1", State: pb.WorkerState_WORKER_BUSY, JobId: "other", JobName: "dog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.ListWorkers(ctx, &pb.ListWorkersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Workers) != 1 {
+		t.Fatalf("listed %d workers, want 1", len(list.Workers))
+	}
+	if info := list.Workers[0]; info.Hostname != "gpu-box" || info.State != pb.WorkerState_WORKER_BUSY || info.JobName != "dog" {
+		t.Errorf("listed %+v", info)
+	}
+}
+
+func TestWorkerOffline(t *testing.T) {
+	now := time.Now()
+	w := &Worker{State: pb.WorkerState_WORKER_BUSY, LastSeen: now}
+
+	if got := w.currentState(now.Add(WorkerTimeout)); got != pb.WorkerState_WORKER_BUSY {
+		t.Errorf("state %s at the timeout, want it busy", got)
+	}
+	if got := w.currentState(now.Add(WorkerTimeout + time.Second)); got != pb.WorkerState_WORKER_OFFLINE {
+		t.Errorf("state %s after the timeout, want it offline", got)
+	}
+	if got := w.toResponse(now.Add(time.Hour)).Status; got != "Offline" {
+		t.Errorf("shown as %q, want Offline", got)
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	for _, id := range []string{"w1", "w2"} {
+		if _, err := s.RegisterWorker(ctx, &pb.WorkerRegistration{WorkerId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.SendCommand(ctx, &pb.WorkerCommandRequest{WorkerId: "w3", Command: pb.WorkerCommand_COMMAND_DRAIN}); err == nil {
+		t.Errorf("sent a command to an unregistered worker")
+	}
+
+	r, err := s.SendCommand(ctx, &pb.WorkerCommandRequest{Command: pb.WorkerCommand_COMMAND_PAUSE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.WorkerIds) != 2 {
+		t.Errorf("sent to %q, want every worker", r.WorkerIds)
+	}
+	if _, err := s.SendCommand(ctx, &pb.WorkerCommandRequest{WorkerId: "w2", Command: pb.WorkerCommand_COMMAND_DRAIN}); err != nil {
+		t.Fatal(err)
+	}
+
+	//The command is delivered with the next heartbeat, once
+	for _, want := range []pb.WorkerCommand{pb.WorkerCommand_COMMAND_PAUSE, pb.WorkerCommand_COMMAND_NONE} {
+		hb, err := s.Heartbeat(ctx, &pb.WorkerHeartbeat{WorkerId: "w1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hb.Command != want {
+			t.Errorf("heartbeat delivered %s, want %s", hb.Command, want)
+		}
+	}
+
+	//A worker asking for a job gets the command instead
+	s.PendingJobs[jobKey{ID: "job", Name: "cat"}] = &Job{Name: "cat"}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{WorkerId: "w2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Command != pb.WorkerCommand_COMMAND_DRAIN || job.Id != "" {
+		t.Errorf("got job %q with %s, want no job and a drain", job.Id, job.Command)
+	}
+	if len(s.PendingJobs) != 1 {
+		t.Errorf("job taken off the queue")
+	}
+
+	//Resuming doesn't hold back the job, it goes along with it
+	if _, err := s.SendCommand(ctx, &pb.WorkerCommandRequest{WorkerId: "w2", Command: pb.WorkerCommand_COMMAND_RESUME}); err != nil {
+		t.Fatal(err)
+	}
+	job, err = s.RequestJob(ctx, &pb.JobRequest{WorkerId: "w2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Command != pb.WorkerCommand_COMMAND_RESUME || job.Id == "" {
+		t.Errorf("got job %q with %s, want the job and a resume", job.Id, job.Command)
+	}
+}
+
+func TestWorkerSlots(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	idle := []*pb.SlotInfo{
+		{Index: 0, Device: "gpu:0", State: pb.WorkerState_WORKER_IDLE},
+		{Index: 1, Device: "gpu:1", State: pb.WorkerState_WORKER_IDLE},
+	}
+	if _, err := s.RegisterWorker(ctx, &pb.WorkerRegistration{WorkerId: "w1", Slots: idle}); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []jobKey{{ID: "a", Name: "cat"}, {ID: "b", Name: "dog"}} {
+		s.PendingJobs[k] = &Job{Name: k.Name}
+	}
+
+	var jobs []*pb.Job
+	for slot := int32(0); slot < 2; slot++ {
+		job, err := s.RequestJob(ctx, &pb.JobRequest{WorkerId: "w1", Slot: slot})
+		if err != nil {
+			t.Fatal(err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	w := s.Workers["w1"]
+	for i, job := range jobs {
+		if w.Slots[i].State != pb.WorkerState_WORKER_BUSY || w.Slots[i].JobID != job.Id {
+			t.Errorf("slot %d is %s on %q, want busy on %q", i, w.Slots[i].State, w.Slots[i].JobID, job.Id)
+		}
+	}
+
+	//The worker stays busy until every slot is done
+	if _, err := s.CompleteJob(ctx, &pb.JobResult{Id: jobs[0].Id, Name: jobs[0].Name, AttemptId: jobs[0].AttemptId}); err != nil {
+		t.Fatal(err)
+	}
+	if w.Slots[0].State != pb.WorkerState_WORKER_IDLE || w.State != pb.WorkerState_WORKER_BUSY || w.JobID != jobs[1].Id {
+		t.Errorf("after the first job: slot 0 %s, worker %s on %q", w.Slots[0].State, w.State, w.JobID)
+	}
+	if _, err := s.FailJob(ctx, &pb.JobFail{Id: jobs[1].Id, Name: jobs[1].Name, AttemptId: jobs[1].AttemptId}); err != nil {
+		t.Fatal(err)
+	}
+	if w.State != pb.WorkerState_WORKER_IDLE || w.JobID != "" {
+		t.Errorf("after both jobs: worker %s on %q, want idle", w.State, w.JobID)
+	}
+
+	//Slots of an offline worker are shown offline too
+	w.LastSeen = time.Now().Add(-2 * WorkerTimeout)
+	r := w.toResponse(time.Now())
+	if len(r.Slots) != 2 || r.Slots[1].Status != "Offline" || r.Slots[1].Device != "gpu:1" {
+		t.Errorf("got slots %+v", r.Slots)
+	}
+}
+
+func TestUnhealthyWorker(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := s.RegisterWorker(ctx, &pb.WorkerRegistration{WorkerId: "w1", Problem: "engine binary: not found"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := s.Workers["w1"]
+	if w.State != pb.WorkerState_WORKER_UNHEALTHY {
+		t.Errorf("worker failing its self-test is %s", w.State)
+	}
+	if r := w.toResponse(time.Now()); r.Status != "Unhealthy" || r.Problem != "engine binary: not found" {
+		t.Errorf("shown as %q with problem %q", r.Status, r.Problem)
+	}
+
+	s.PendingJobs[jobKey{ID: "job", Name: "cat"}] = &Job{Name: "cat"}
+	job, err := s.RequestJob(ctx, &pb.JobRequest{WorkerId: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Id != "" || len(s.PendingJobs) != 1 {
+		t.Errorf("unhealthy worker got job %q", job.Id)
+	}
+
+	//Once the self-test passes the heartbeat clears the problem
+	_, err = s.Heartbeat(ctx, &pb.WorkerHeartbeat{WorkerId: "w1", State: pb.WorkerState_WORKER_IDLE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err = s.RequestJob(ctx, &pb.JobRequest{WorkerId: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Id == "" {
+		t.Errorf("healthy worker got no job")
+	}
+}
+
+func TestRequeueLostJobs(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	slots := func(jobs ...*pb.Job) []*pb.SlotInfo {
+		r := []*pb.SlotInfo{}
+		for i, job := range jobs {
+			r = append(r, &pb.SlotInfo{Index: int32(i), Device: "gpu", State: pb.WorkerState_WORKER_BUSY, JobId: job.Id, JobName: job.Name})
+		}
+		return append(r, &pb.SlotInfo{Index: int32(len(jobs)), Device: "gpu", State: pb.WorkerState_WORKER_IDLE})
+	}
+	for _, id := range []string{"w1", "w2"} {
+		if _, err := s.RegisterWorker(ctx, &pb.WorkerRegistration{WorkerId: id, Slots: slots()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	take := func(workerID string, slot int32, name string) *pb.Job {
+		s.PendingJobs[jobKey{ID: name, Name: name}] = &Job{Name: name}
+		job, err := s.RequestJob(ctx, &pb.JobRequest{WorkerId: workerID, Slot: slot})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+	kept := take("w1", 0, "kept")
+	lost := take("w1", 1, "lost")
+	other := take("w2", 0, "other")
+	young := take("w1", 2, "young")
+
+	//Only the attempt that just started could have been missed by the report
+	for _, job := range []*pb.Job{kept, lost, other} {
+		s.InProgressJobs[jobKey{ID: job.Id, Name: job.Name}].AttemptStarted = time.Now().Add(-2 * HeartbeatInterval)
+	}
+
+	//w1 comes back still running one of its jobs
+	if _, err := s.RegisterWorker(ctx, &pb.WorkerRegistration{WorkerId: "w1", Slots: slots(kept)}); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := func(job *pb.Job) bool {
+		_, ok := s.PendingJobs[jobKey{ID: job.Id, Name: job.Name}]
+		return ok
+	}
+	tests := []struct {
+		job     *pb.Job
+		pending bool
+	}{
+		{kept, false},
+		{lost, true},
+		{other, false},
+		{young, false},
+	}
+	for _, tt := range tests {
+		if got := pending(tt.job); got != tt.pending {
+			t.Errorf("%s: pending %t, want %t", tt.job.Name, got, tt.pending)
+		}
+	}
+	w := s.Workers["w1"]
+	if len(w.Slots) != 2 || w.Slots[0].JobID != kept.Id || w.State != pb.WorkerState_WORKER_BUSY {
+		t.Errorf("reported slots not kept: %+v, %s", w.Slots, w.State)
+	}
+
+	//Heartbeats go on leaving out the young attempt once it is old enough
+	s.InProgressJobs[jobKey{ID: young.Id, Name: young.Name}].AttemptStarted = time.Now().Add(-2 * HeartbeatInterval)
+	if _, err := s.Heartbeat(ctx, &pb.WorkerHeartbeat{WorkerId: "w1", State: pb.WorkerState_WORKER_BUSY, Slots: slots(kept)}); err != nil {
+		t.Fatal(err)
+	}
+	if !pending(young) || pending(kept) {
+		t.Errorf("after a heartbeat: young pending %t, kept pending %t", pending(young), pending(kept))
+	}
+
+	//w2 stops sending heartbeats
+	s.Workers["w2"].LastSeen = time.Now().Add(-2 * WorkerTimeout)
+	s.requeueOffline(time.Now())
+	if !pending(other) {
+		t.Errorf("job of an offline worker not requeued")
+	}
+	if pending(kept) {
+		t.Errorf("job of an online worker requeued")
+	}
+}
@@ -0,0 +1,121 @@
+// Package upload sends images too large for a single gRPC message as a
+// stream of chunks and puts them back together on the other side
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+const (
+	// DefaultMaxMsgSize is the largest message gRPC accepts unless told
+	// otherwise
+	DefaultMaxMsgSize = 4 * 1024 * 1024
+
+	// ChunkSize is how much image data goes in each streamed message
+	ChunkSize = 1024 * 1024
+
+	// MaxImageSize bounds what a streamed upload may add up to
+	MaxImageSize = 256 * 1024 * 1024
+
+	// headroom is left in a message for everything but the image
+	headroom = 64 * 1024
+)
+
+// Digest identifies the contents of an image
+func Digest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Fits tells whether size bytes of images can be sent in a single message to
+// a peer that accepts messages of up to maxMsgSize bytes
+func Fits(size int, maxMsgSize int) bool {
+	if maxMsgSize <= 0 {
+		maxMsgSize = DefaultMaxMsgSize
+	}
+	return size+headroom <= maxMsgSize
+}
+
+// Send splits an image into chunks and passes them to send in order
+func Send(part pb.ImagePart, image []byte, send func(*pb.ImageChunk) error) error {
+	for len(image) > 0 {
+		n := ChunkSize
+		if n > len(image) {
+			n = len(image)
+		}
+
+		err := send(&pb.ImageChunk{Part: part, Data: image[:n]})
+		if err != nil {
+			return err
+		}
+		image = image[n:]
+	}
+	return nil
+}
+
+// Buffer collects the chunks of the images of an upload
+type Buffer struct {
+	parts map[pb.ImagePart]*bytes.Buffer
+	size  int
+}
+
+func NewBuffer() *Buffer {
+	return &Buffer{parts: make(map[pb.ImagePart]*bytes.Buffer)}
+}
+
+// Add appends a chunk to the image it belongs to
+func (b *Buffer) Add(c *pb.ImageChunk) error {
+	if c == nil {
+		return fmt.Errorf("Missing image chunk")
+	}
+	if c.Part == pb.ImagePart_PART_UNKNOWN {
+		return fmt.Errorf("Image chunk without a part")
+	}
+
+	b.size += len(c.Data)
+	if b.size > MaxImageSize {
+		return fmt.Errorf("Upload larger than %d bytes", MaxImageSize)
+	}
+
+	buf, ok := b.parts[c.Part]
+	if !ok {
+		buf = &bytes.Buffer{}
+		b.parts[c.Part] = buf
+	}
+	buf.Write(c.Data)
+	return nil
+}
+
+// Image returns the contents of an image once they match its digest
+func (b *Buffer) Image(part pb.ImagePart, digest string) ([]byte, error) {
+	if digest == "" {
+		return nil, fmt.Errorf("Missing digest for %s", part)
+	}
+
+	var image []byte
+	if buf, ok := b.parts[part]; ok {
+		image = buf.Bytes()
+	}
+
+	if got := Digest(image); got != digest {
+		return nil, &DigestError{Part: part, Expected: digest, Got: got}
+	}
+	return image, nil
+}
+
+// DigestError is returned when an image arrives different from how it was
+// sent
+type DigestError struct {
+	Part     pb.ImagePart
+	Expected string
+	Got      string
+}
+
+func (e *DigestError) Error() string {
+	return fmt.Sprintf("Digest mismatch for %s: expected %s, got %s", e.Part, e.Expected, e.Got)
+}
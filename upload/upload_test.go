@@ -0,0 +1,160 @@
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+func TestFits(t *testing.T) {
+	tests := []struct {
+		size, maxMsgSize int
+		want             bool
+	}{
+		{0, 0, true},
+		{DefaultMaxMsgSize - headroom, 0, true},
+		{DefaultMaxMsgSize - headroom + 1, 0, false},
+		{DefaultMaxMsgSize, 0, false},
+		{10 * 1024 * 1024, 16 * 1024 * 1024, true},
+		{16 * 1024 * 1024, 16 * 1024 * 1024, false},
+	}
+
+	for _, tt := range tests {
+		if got := Fits(tt.size, tt.maxMsgSize); got != tt.want {
+			t.Errorf("Fits(%d, %d) = %t, want %t", tt.size, tt.maxMsgSize, got, tt.want)
+		}
+	}
+}
+
+func TestSendAndBuffer(t *testing.T) {
+	content := bytes.Repeat([]byte("content"), ChunkSize/3)
+	style := bytes.Repeat([]byte("s"), ChunkSize)
+
+	tests := []struct {
+		name   string
+		image  []byte
+		chunks int
+	}{
+		{"empty", nil, 0},
+		{"small", []byte("small image"), 1},
+		{"exact chunk", style, 1},
+		{"several chunks", content, 3},
+	}
+
+	for _, tt := range tests {
+		b := NewBuffer()
+		chunks := 0
+		err := Send(pb.ImagePart_PART_CONTENT, tt.image, func(c *pb.ImageChunk) error {
+			chunks++
+			if len(c.Data) > ChunkSize {
+				t.Errorf("%s: chunk of %d bytes", tt.name, len(c.Data))
+			}
+			return b.Add(c)
+		})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if chunks != tt.chunks {
+			t.Errorf("%s: sent %d chunks, want %d", tt.name, chunks, tt.chunks)
+		}
+
+		got, err := b.Image(pb.ImagePart_PART_CONTENT, Digest(tt.image))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.image) {
+			t.Errorf("%s: got %d bytes back, want %d", tt.name, len(got), len(tt.image))
+		}
+	}
+}
+
+func TestBufferParts(t *testing.T) {
+	b := NewBuffer()
+	for _, c := range []*pb.ImageChunk{
+		{Part: pb.ImagePart_PART_STYLE, Data: []byte("sty")},
+		{Part: pb.ImagePart_PART_CONTENT, Data: []byte("con")},
+		{Part: pb.ImagePart_PART_STYLE, Data: []byte("le")},
+		{Part: pb.ImagePart_PART_CONTENT, Data: []byte("tent")},
+	} {
+		if err := b.Add(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		part   pb.ImagePart
+		digest string
+		want   string
+		err    bool
+	}{
+		{"style", pb.ImagePart_PART_STYLE, Digest([]byte("style")), "style", false},
+		{"content", pb.ImagePart_PART_CONTENT, Digest([]byte("content")), "content", false},
+		{"no digest", pb.ImagePart_PART_STYLE, "", "", true},
+		{"wrong digest", pb.ImagePart_PART_STYLE, Digest([]byte("content")), "", true},
+		{"part not sent", pb.ImagePart_PART_RESULT, Digest([]byte("result")), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := b.Image(tt.part, tt.digest)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: got error %v, want error %t", tt.name, err, tt.err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	_, err := b.Image(pb.ImagePart_PART_STYLE, Digest([]byte("content")))
+	if _, ok := err.(*DigestError); !ok {
+		t.Errorf("mismatch reported as %T, want *DigestError", err)
+	}
+}
+
+func TestBufferRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []*pb.ImageChunk
+	}{
+		{"nil chunk", []*pb.ImageChunk{nil}},
+		{"no part", []*pb.ImageChunk{{Data: []byte("x")}}},
+		{"too large", func() []*pb.ImageChunk {
+			var chunks []*pb.ImageChunk
+			data := make([]byte, ChunkSize)
+			for i := 0; i <= MaxImageSize/ChunkSize; i++ {
+				chunks = append(chunks, &pb.ImageChunk{Part: pb.ImagePart_PART_RESULT, Data: data})
+			}
+			return chunks
+		}()},
+	}
+
+	for _, tt := range tests {
+		b := NewBuffer()
+		var err error
+		for _, c := range tt.chunks {
+			if err = b.Add(c); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+}
+
+func ExampleSend() {
+	err := Send(pb.ImagePart_PART_RESULT, make([]byte, ChunkSize+1), func(c *pb.ImageChunk) error {
+		fmt.Println(c.Part, len(c.Data))
+		return nil
+	})
+	fmt.Println(err)
+	// Output:
+	// PART_RESULT 1048576
+	// PART_RESULT 1
+	// <nil>
+}
@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/upload"
+)
+
+// Cache keeps style images on disk, keyed by digest, so the server doesn't
+// need to send them with every job. The least recently used images are
+// evicted once the cache grows over its size limit.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	lock    sync.Mutex
+	entries map[string]*cacheEntry
+	size    int64
+}
+
+type cacheEntry struct {
+	digest string
+	size   int64
+	used   time.Time
+}
+
+// OpenCache uses dir for the cache, picking up the images already in it
+func OpenCache(dir string, maxSize int64) (*Cache, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[string]*cacheEntry),
+	}
+	for _, f := range files {
+		if f.IsDir() || len(f.Name()) != sha256.Size*2 {
+			continue
+		}
+		c.entries[f.Name()] = &cacheEntry{
+			digest: f.Name(),
+			size:   f.Size(),
+			used:   f.ModTime(),
+		}
+		c.size += f.Size()
+	}
+
+	c.lock.Lock()
+	c.evict()
+	c.lock.Unlock()
+
+	log.Printf("Cache %q has %d images, %d bytes", dir, len(c.entries), c.size)
+	return c, nil
+}
+
+// Get returns a cached image
+func (c *Cache) Get(d string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.entries[d]
+	if !ok {
+		return nil, false
+	}
+
+	b, err := ioutil.ReadFile(c.filename(d))
+	if err != nil || upload.Digest(b) != d {
+		log.Printf("Dropping unreadable cache entry %q. %v", d, err)
+		c.remove(e)
+		return nil, false
+	}
+
+	c.touch(e)
+	return b, true
+}
+
+// Put adds an image to the cache, evicting older ones if needed
+func (c *Cache) Put(d string, b []byte) error {
+	if upload.Digest(b) != d {
+		return fmt.Errorf("Digest mismatch for %q", d)
+	}
+	if int64(len(b)) > c.maxSize {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[d]; ok {
+		c.touch(e)
+		return nil
+	}
+
+	//Write to a temporary file so a crash never leaves half an image
+	tmp, err := ioutil.TempFile(c.dir, "tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.filename(d))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	e := &cacheEntry{digest: d, size: int64(len(b))}
+	c.entries[d] = e
+	c.size += e.size
+	c.touch(e)
+	c.evict()
+	return nil
+}
+
+// Digests lists the cached images
+func (c *Cache) Digests() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	r := make([]string, 0, len(c.entries))
+	for d := range c.entries {
+		r = append(r, d)
+	}
+	sort.Strings(r)
+	return r
+}
+
+func (c *Cache) filename(d string) string {
+	return path.Join(c.dir, d)
+}
+
+// touch records a use. The file modification time keeps the order across
+// restarts.
+func (c *Cache) touch(e *cacheEntry) {
+	e.used = time.Now()
+	os.Chtimes(c.filename(e.digest), e.used, e.used)
+}
+
+func (c *Cache) remove(e *cacheEntry) {
+	err := os.Remove(c.filename(e.digest))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Could not remove cache entry %q. %v", e.digest, err)
+	}
+	delete(c.entries, e.digest)
+	c.size -= e.size
+}
+
+// evict drops the least recently used images until the cache fits
+func (c *Cache) evict() {
+	if c.size <= c.maxSize {
+		return
+	}
+
+	entries := make([]*cacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	sort.Sort(byUse(entries))
+
+	for _, e := range entries {
+		if c.size <= c.maxSize {
+			return
+		}
+		c.remove(e)
+	}
+}
+
+type byUse []*cacheEntry
+
+func (e byUse) Len() int           { return len(e) }
+func (e byUse) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byUse) Less(i, j int) bool { return e[i].used.Before(e[j].used) }
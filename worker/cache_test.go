@@ -0,0 +1,190 @@
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"github.com/mgilbir/neural-style-art-project/upload"
+	"golang.org/x/net/context"
+)
+
+func TestCacheEviction(t *testing.T) {
+	images := map[string][]byte{
+		"a":   []byte(strings.Repeat("a", 40)),
+		"b":   []byte(strings.Repeat("b", 40)),
+		"c":   []byte(strings.Repeat("c", 40)),
+		"big": []byte(strings.Repeat("x", 200)),
+	}
+
+	//Operations are "put <image>" or "get <image>"
+	tests := []struct {
+		name    string
+		maxSize int64
+		ops     []string
+		want    []string
+	}{
+		{"fits", 100, []string{"put a", "put b"}, []string{"a", "b"}},
+		{"oldest evicted", 100, []string{"put a", "put b", "put c"}, []string{"b", "c"}},
+		{"get keeps it", 100, []string{"put a", "put b", "get a", "put c"}, []string{"a", "c"}},
+		{"put again keeps it", 100, []string{"put a", "put b", "put a", "put c"}, []string{"a", "c"}},
+		{"too large to cache", 100, []string{"put a", "put big"}, []string{"a"}},
+		{"one at a time", 40, []string{"put a", "put b", "put c"}, []string{"c"}},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "cache")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		c, err := OpenCache(dir, tt.maxSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, op := range tt.ops {
+			f := strings.Fields(op)
+			b := images[f[1]]
+			switch f[0] {
+			case "put":
+				err = c.Put(upload.Digest(b), b)
+				if err != nil {
+					t.Errorf("%s: %s: %v", tt.name, op, err)
+				}
+			case "get":
+				if _, ok := c.Get(upload.Digest(b)); !ok {
+					t.Errorf("%s: %s: not cached", tt.name, op)
+				}
+			}
+			//Keep uses apart so the order doesn't depend on the clock
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		var want []string
+		for _, name := range tt.want {
+			want = append(want, upload.Digest(images[name]))
+		}
+		sort.Strings(want)
+		got := c.Digests()
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: cached %v, want %v", tt.name, got, tt.want)
+		}
+		for _, d := range got {
+			if b, ok := c.Get(d); !ok || upload.Digest(b) != d {
+				t.Errorf("%s: %s not readable", tt.name, d)
+			}
+		}
+	}
+}
+
+func TestCacheReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := OpenCache(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, recent := []byte(strings.Repeat("o", 40)), []byte(strings.Repeat("r", 40))
+	for _, b := range [][]byte{old, recent} {
+		if err := c.Put(upload.Digest(b), b); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//Files that aren't images are left alone
+	if err := ioutil.WriteFile(path.Join(dir, "notes"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	//A smaller cache keeps the most recently used image
+	c, err = OpenCache(dir, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Digests(); len(got) != 1 || got[0] != upload.Digest(recent) {
+		t.Errorf("reopened with %v, want %s", got, upload.Digest(recent))
+	}
+
+	//Corrupt entries are dropped when read
+	if err := ioutil.WriteFile(path.Join(dir, upload.Digest(recent)), []byte("corrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get(upload.Digest(recent)); ok {
+		t.Errorf("got a corrupt entry")
+	}
+	if got := c.Digests(); len(got) != 0 {
+		t.Errorf("corrupt entry kept: %v", got)
+	}
+
+	if err := c.Put(upload.Digest(old), recent); err == nil {
+		t.Errorf("put an image under the wrong digest")
+	}
+}
+
+func TestResolveStyle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := OpenCache(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Worker{config: Config{Cache: c}}
+	style := []byte("style")
+
+	//An image sent with the job is cached for the next one
+	img := &pb.InputImage{Title: "wave", Image: style, Digest: upload.Digest(style)}
+	if err := w.resolveStyle(img); err != nil {
+		t.Fatal(err)
+	}
+	img = &pb.InputImage{Title: "wave", Digest: upload.Digest(style)}
+	if err := w.resolveStyle(img); err != nil {
+		t.Fatal(err)
+	}
+	if string(img.Image) != "style" {
+		t.Errorf("resolved to %q, want the cached image", img.Image)
+	}
+
+	if err := w.resolveStyle(&pb.InputImage{Title: "scream", Digest: upload.Digest([]byte("other"))}); err == nil {
+		t.Errorf("resolved an image that isn't cached")
+	}
+	if err := (&Worker{}).resolveStyle(&pb.InputImage{Title: "wave", Digest: upload.Digest(style)}); err == nil {
+		t.Errorf("resolved an image without a cache")
+	}
+}
+
+func TestRunJobStyleNotCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := OpenCache(path.Join(dir, "cache"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeClient{}
+	w := &Worker{client: client, config: Config{WorkDir: dir, Cache: c}}
+	job := &pb.Job{Id: "job", Name: "cat", AttemptId: "a1", Style: &pb.InputImage{Title: "wave", Digest: upload.Digest([]byte("style"))}}
+	w.runJob(context.Background(), &slot{}, job)
+
+	//The job isn't at fault, it mustn't count towards giving up on it
+	if len(client.fails) != 1 || !client.fails[0].Preempted || client.fails[0].Permanent {
+		t.Errorf("failed %v, want the job handed back", client.fails)
+	}
+}
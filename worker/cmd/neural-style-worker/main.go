@@ -2,30 +2,129 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"golang.org/x/net/context"
 
+	"github.com/mgilbir/neural-style-art-project/upload"
 	"github.com/mgilbir/neural-style-art-project/worker"
 	"google.golang.org/grpc"
 )
 
 var (
 	grpcConnStr = flag.String("grpc", ":8081", "The gRPC connection string")
+	workerID    = flag.String("id", "", "The worker ID reported to the server. Defaults to hostname-pid")
+	gracePeriod = flag.Duration("grace", 30*time.Second, "How long to wait for an interrupted job to be handed back to the server")
+	slots       = flag.String("slots", "gpu:0", "Comma separated devices to render on, one job at a time each: gpu:N or cpu")
+	workDir     = flag.String("workdir", "work", "The directory where each job gets its own scratch directory")
+	keep        = flag.String("keep", "failed", "Which job directories to keep when done: none, failed or all")
+	maxDuration = flag.Duration("timeout", 2*time.Hour, "How long a render may take unless the job sets its own limit. 0 to disable")
+	stall       = flag.Duration("stall", 10*time.Minute, "How long the engine may go without progress unless the job sets its own limit. 0 to disable")
+	cacheDir    = flag.String("cache", "cache", "The directory where style images are cached between jobs")
+	cacheSize   = flag.Int64("cachesize", 1<<30, "How many bytes of style images to cache. 0 to disable")
+	probe       = flag.Bool("probe", true, "Run the self-test at startup and refuse jobs until it passes")
+	healthAddr  = flag.String("health", "localhost:8091", "Where to serve the worker health status over HTTP. Empty to disable")
+	maxLogSize  = flag.Int("logsize", worker.DefaultMaxLogSize, "How many bytes of engine output to upload for each job attempt")
+	maxMsgSize  = flag.Int("msgsize", 64*1024*1024, "The largest gRPC message accepted from the server, in bytes")
+	serverMsg   = flag.Int("servermsgsize", upload.DefaultMaxMsgSize, "The largest gRPC message the server accepts, in bytes. Larger results are streamed")
 )
 
 func main() {
 	flag.Parse()
+	rand.Seed(time.Now().UnixNano())
+
+	devices, err := worker.ParseSlots(*slots)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	//neural-style-worker [flags] selftest checks the machine and exits
+	if flag.Arg(0) == "selftest" {
+		r := worker.SelfTest(devices, *workDir)
+		fmt.Print(r)
+		if !r.OK() {
+			os.Exit(1)
+		}
+		return
+	}
 
 	//TODO: Fix the insecure thingie
-	conn, err := grpc.Dial(*grpcConnStr, grpc.WithInsecure())
+	conn, err := grpc.Dial(*grpcConnStr, grpc.WithInsecure(), grpc.WithMaxMsgSize(*maxMsgSize))
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer conn.Close()
 
-	w := worker.New(conn)
+	retention, err := worker.ParseRetention(*keep)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var cache *worker.Cache
+	if *cacheSize > 0 {
+		cache, err = worker.OpenCache(*cacheDir, *cacheSize)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	id := *workerID
+	if id == "" {
+		id = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
 
-	ctx := context.Background()
-	w.Run(ctx)
+	w := worker.New(conn, worker.Config{
+		ID:           id,
+		Hostname:     hostname,
+		Capabilities: worker.Capabilities(devices),
+		Devices:      devices,
+		GracePeriod:  *gracePeriod,
+		WorkDir:      *workDir,
+		Retention:    retention,
+		Probe:        *probe,
+		Cache:        cache,
+		MaxLogSize:   *maxLogSize,
+		MaxDuration:  *maxDuration,
+		StallTimeout: *stall,
+
+		ServerMaxMsgSize: *serverMsg,
+	})
+
+	if *healthAddr != "" {
+		go func() {
+			err := http.ListenAndServe(*healthAddr, w.HealthHandler())
+			log.Printf("Health status not available. %v", err)
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-sigC
+		log.Printf("Received %s, handing back the current job", sig)
+		cancel()
+
+		//Don't wait forever if handing back gets stuck
+		time.Sleep(*gracePeriod + 5*time.Second)
+		log.Fatal("Grace period exceeded")
+	}()
+
+	if err := w.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,158 @@
+package worker
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// callTimeout bounds a single call to the server. grpc waits for the
+// connection to come back otherwise, so a down server would block forever.
+var callTimeout = 30 * time.Second
+
+// backoff spaces out retries exponentially, with jitter so a fleet of workers
+// doesn't hit a restarted server at once
+type backoff struct {
+	min      time.Duration
+	max      time.Duration
+	failures uint
+}
+
+func newBackoff() *backoff {
+	return &backoff{min: time.Second, max: time.Minute}
+}
+
+// next returns how long to wait before the next retry
+func (b *backoff) next() time.Duration {
+	d := b.max
+	if b.failures < 16 && b.min<<b.failures < b.max {
+		d = b.min << b.failures
+	}
+	b.failures++
+
+	//Somewhere between half and the full delay
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (b *backoff) reset() {
+	b.failures = 0
+}
+
+// permanent tells whether retrying a call that failed with err is pointless.
+// The server rejects reports it doesn't want with these codes, anything else
+// may be the connection.
+func permanent(err error) bool {
+	switch grpc.Code(err) {
+	case codes.NotFound, codes.FailedPrecondition, codes.InvalidArgument,
+		codes.AlreadyExists, codes.PermissionDenied, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// health keeps track of whether the server can be reached
+type health struct {
+	lock        sync.Mutex
+	connected   bool
+	lastContact time.Time
+	lastError   string
+	since       time.Time
+}
+
+func (h *health) record(err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+
+	//Rejections still mean the server is there
+	if err == nil || permanent(err) {
+		if !h.connected {
+			h.since = now
+		}
+		h.connected = true
+		h.lastContact = now
+		return
+	}
+
+	if h.connected || h.since.IsZero() {
+		h.since = now
+	}
+	h.connected = false
+	h.lastError = err.Error()
+}
+
+// call makes a single attempt at a call to the server
+func (w *Worker) call(ctx context.Context, f func(context.Context) error) error {
+	cctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	err := f(cctx)
+	if ctx.Err() == nil {
+		w.health.record(err)
+	}
+	return err
+}
+
+// deliver retries a call until it succeeds, the server rejects it or ctx is
+// done, so results are not lost while the server is away
+func (w *Worker) deliver(ctx context.Context, what string, f func(context.Context) error) error {
+	b := newBackoff()
+	for {
+		err := w.call(ctx, f)
+		if err == nil || permanent(err) {
+			return err
+		}
+
+		d := b.next()
+		log.Printf("Could not send %s, retrying in %s. %v", what, d, err)
+		w.wait(ctx, d)
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// HealthResponse is served by the health handler
+type HealthResponse struct {
+	ID          string         `json:"id"`
+	Connected   bool           `json:"connected"`
+	Since       time.Time      `json:"since"`
+	LastContact time.Time      `json:"lastContact"`
+	LastError   string         `json:"lastError,omitempty"`
+	Slots       []*pb.SlotInfo `json:"slots"`
+}
+
+// HealthHandler reports whether the worker can reach the server and what
+// each slot is doing. It answers 503 while the server is unreachable.
+func (w *Worker) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w.health.lock.Lock()
+		resp := HealthResponse{
+			ID:          w.config.ID,
+			Connected:   w.health.connected,
+			Since:       w.health.since,
+			LastContact: w.health.lastContact,
+			LastError:   w.health.lastError,
+		}
+		w.health.lock.Unlock()
+		resp.Slots = w.slotInfo()
+
+		rw.Header().Set("Content-Type", "application/json")
+		if !resp.Connected {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(rw).Encode(resp); err != nil {
+			log.Println(err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestBackoff(t *testing.T) {
+	b := &backoff{min: time.Second, max: 10 * time.Second}
+	for i, full := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		full *= time.Second
+		if d := b.next(); d < full/2 || d > full {
+			t.Errorf("retry %d after %s, want between %s and %s", i, d, full/2, full)
+		}
+	}
+
+	b.reset()
+	if d := b.next(); d > time.Second {
+		t.Errorf("waited %s after a reset, want at most a second", d)
+	}
+
+	//Many failures in a row don't overflow
+	b.failures = 100
+	if d := b.next(); d < 5*time.Second || d > 10*time.Second {
+		t.Errorf("waited %s after 100 failures", d)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	w := &Worker{}
+
+	calls := 0
+	rejected := func(ctx context.Context) error {
+		calls++
+		return grpc.Errorf(codes.FailedPrecondition, "stale attempt")
+	}
+	if err := w.deliver(context.Background(), "a rejected report", rejected); grpc.Code(err) != codes.FailedPrecondition || calls != 1 {
+		t.Errorf("rejected report: got %v after %d calls, want it returned at once", err, calls)
+	}
+
+	//Connection errors are retried until the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	unavailable := func(ctx context.Context) error {
+		calls++
+		if calls == 2 {
+			cancel()
+		}
+		return grpc.Errorf(codes.Unavailable, "connection refused")
+	}
+	if err := w.deliver(ctx, "an undeliverable report", unavailable); grpc.Code(err) != codes.Unavailable || calls != 2 {
+		t.Errorf("undeliverable report: got %v after %d calls", err, calls)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	w := &Worker{config: Config{ID: "w1"}}
+	handler := w.HealthHandler()
+	get := func() (int, HealthResponse) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		var r HealthResponse
+		if err := json.NewDecoder(rec.Body).Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, r
+	}
+
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("got %d before reaching the server, want 503", code)
+	}
+
+	w.health.record(nil)
+	code, r := get()
+	if code != http.StatusOK || !r.Connected || r.ID != "w1" {
+		t.Errorf("got %d with %+v once connected", code, r)
+	}
+	since := r.Since
+
+	//A rejection still means the server is up
+	w.health.record(grpc.Errorf(codes.NotFound, "no such job"))
+	if _, r := get(); !r.Connected || !r.Since.Equal(since) {
+		t.Errorf("rejection changed the connection: %+v", r)
+	}
+
+	w.health.record(errors.New("connection refused"))
+	code, r = get()
+	if code != http.StatusServiceUnavailable || r.Connected || r.LastError != "connection refused" {
+		t.Errorf("got %d with %+v after losing the server", code, r)
+	}
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxLogSize bounds the engine output kept and uploaded per attempt
+const DefaultMaxLogSize = 256 * 1024
+
+// Number of lines attached to a failure report
+const failureTailLines = 50
+
+// logBuffer keeps the last max bytes written to it
+type logBuffer struct {
+	max int
+
+	lock      sync.Mutex
+	buf       []byte
+	truncated bool
+}
+
+func newLogBuffer(max int) *logBuffer {
+	if max <= 0 {
+		max = DefaultMaxLogSize
+	}
+	return &logBuffer{max: max}
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.buf = append(b.buf, p...)
+
+	//Trim in bulk so long logs are not copied on every line
+	if len(b.buf) > 2*b.max {
+		b.buf = append([]byte(nil), b.buf[len(b.buf)-b.max:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *logBuffer) writeLine(prefix string, line string) {
+	b.Write([]byte(prefix + line + "\n"))
+}
+
+// contents returns the kept output, starting at a line boundary
+func (b *logBuffer) contents() (string, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	buf := b.buf
+	truncated := b.truncated
+	if len(buf) > b.max {
+		buf = buf[len(buf)-b.max:]
+		truncated = true
+	}
+	if truncated {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		}
+	}
+	return string(buf), truncated
+}
+
+// tail returns the last n lines of output
+func (b *logBuffer) tail(n int) string {
+	s, _ := b.contents()
+	lines := strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "")
+}
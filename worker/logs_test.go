@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+)
+
+func TestLogBuffer(t *testing.T) {
+	b := newLogBuffer(20)
+	for i := 0; i < 4; i++ {
+		b.writeLine("", fmt.Sprintf("line %d", i))
+	}
+
+	//The kept output starts at a line boundary once trimmed
+	content, truncated := b.contents()
+	if content != "line 2\nline 3\n" || !truncated {
+		t.Errorf("got %q, truncated %t", content, truncated)
+	}
+	if tail := b.tail(1); tail != "line 3" {
+		t.Errorf("tail is %q, want the last line", tail)
+	}
+	if tail := b.tail(10); tail != "line 2\nline 3" {
+		t.Errorf("tail is %q, want all of %q", tail, content)
+	}
+
+	//Trimming in bulk doesn't lose what has to be kept
+	b = newLogBuffer(1000)
+	for i := 0; i < 1000; i++ {
+		b.writeLine("ERRORS | ", fmt.Sprintf("%03d", i))
+	}
+	content, _ = b.contents()
+	if len(content) > 1000 || !strings.HasSuffix(content, "ERRORS | 999\n") || !strings.HasPrefix(content, "ERRORS | ") {
+		t.Errorf("kept %d bytes ending in %q", len(content), content[len(content)-20:])
+	}
+
+	if b := newLogBuffer(0); b.max != DefaultMaxLogSize {
+		t.Errorf("unbounded buffer kept %d bytes, want the default", b.max)
+	}
+}
+
+func TestUploadLog(t *testing.T) {
+	c := &fakeClient{}
+	w := &Worker{client: c, config: Config{ID: "w1", GracePeriod: time.Second}}
+	job := &pb.Job{Id: "job", Name: "cat", AttemptId: "a1"}
+
+	//Nothing to send before the engine printed anything
+	w.uploadLog(context.Background(), job, newLogBuffer(100))
+	if len(c.logs) != 0 {
+		t.Errorf("uploaded an empty log")
+	}
+
+	logs := newLogBuffer(100)
+	logs.writeLine("", "Iteration 1 / 500")
+	w.uploadLog(context.Background(), job, logs)
+	if len(c.logs) != 1 {
+		t.Fatalf("uploaded %d logs, want 1", len(c.logs))
+	}
+	if l := c.logs[0]; l.AttemptId != "a1" || l.WorkerId != "w1" || l.Log != "Iteration 1 / 500\n" || l.Truncated {
+		t.Errorf("uploaded %+v", l)
+	}
+
+	//A stopped attempt still uploads its log within the grace period
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gctx, gcancel := w.graceContext(ctx)
+	defer gcancel()
+	w.uploadLog(gctx, job, logs)
+	if len(c.logs) != 2 {
+		t.Errorf("log of a stopped attempt not uploaded")
+	}
+}
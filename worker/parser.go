@@ -0,0 +1,182 @@
+package worker
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+// neural_style.lua prints, for every print_iter iterations:
+//
+//	Iteration 100 / 500
+//	  Content 1 loss: 2091178.593750
+//	  Style 1 loss: 30021.292114
+//	  ...
+//	  Total loss: 2881570.054321
+var (
+	iterationRe = regexp.MustCompile(`^Iteration (\d+)`)
+	layerLossRe = regexp.MustCompile(`^(Content|Style) \d+ loss: (\S+)$`)
+	totalLossRe = regexp.MustCompile(`^Total loss: (\S+)$`)
+)
+
+// progressParser follows the engine output, keeping track of the last
+// iteration and the losses not yet sent to the server
+type progressParser struct {
+	// Added to the iterations printed by the engine when resuming
+	offset int32
+
+	lock        sync.Mutex
+	iteration   int32
+	updated     time.Time
+	current     *pb.LossSample
+	pending     []*pb.LossSample
+	totals      map[int32]float64
+	convergedAt int32
+}
+
+// parseLine follows a line of engine output. When the line completes the
+// losses of an iteration, it returns that iteration counted from the start
+// of the job.
+func (p *progressParser) parseLine(line string) (int32, bool) {
+	line = strings.TrimSpace(line)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if m := iterationRe.FindStringSubmatch(line); m != nil {
+		i, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, false
+		}
+		p.iteration = int32(i)
+		p.updated = time.Now()
+		p.current = &pb.LossSample{Iteration: p.offset + int32(i)}
+		return 0, false
+	}
+
+	if p.current == nil {
+		return 0, false
+	}
+
+	if m := layerLossRe.FindStringSubmatch(line); m != nil {
+		loss, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			return 0, false
+		}
+		if m[1] == "Content" {
+			p.current.Content += loss
+		} else {
+			p.current.Style += loss
+		}
+		return 0, false
+	}
+
+	if m := totalLossRe.FindStringSubmatch(line); m != nil {
+		loss, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		iteration := p.current.Iteration
+		p.current.Total = loss
+		p.pending = append(p.pending, p.current)
+		if p.totals == nil {
+			p.totals = make(map[int32]float64)
+		}
+		p.totals[iteration] = loss
+		p.current = nil
+		return iteration, true
+	}
+	return 0, false
+}
+
+// lastIteration is the last iteration printed by the engine in this run
+func (p *progressParser) lastIteration() int32 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.iteration
+}
+
+// lastUpdate is when the engine last printed an iteration, or when the
+// parser was started if it hasn't yet
+func (p *progressParser) lastUpdate() time.Time {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.updated
+}
+
+// suspended moves the last update forward by the time the engine was
+// suspended, so it doesn't count as a stall
+func (p *progressParser) suspended(d time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.updated = p.updated.Add(d)
+}
+
+// takeLosses returns the losses parsed since the last call
+func (p *progressParser) takeLosses() []*pb.LossSample {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	losses := p.pending
+	p.pending = nil
+	return losses
+}
+
+// returnLosses puts back losses that could not be sent so they go with the
+// next report
+func (p *progressParser) returnLosses(losses []*pb.LossSample) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.pending = append(losses, p.pending...)
+}
+
+// converged tells whether the total loss at iteration improved by less than
+// the threshold relative to its value window iterations before
+func (p *progressParser) converged(c *pb.Convergence, iteration int32) bool {
+	if c == nil || c.Window <= 0 || c.Threshold <= 0 {
+		return false
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now, ok := p.totals[iteration]
+	if !ok {
+		return false
+	}
+	before, ok := p.totals[iteration-c.Window]
+	if !ok || before == 0 {
+		return false
+	}
+
+	return (before-now)/before < c.Threshold
+}
+
+// converge records that the render converged at iteration. It returns false
+// if it had already.
+func (p *progressParser) converge(iteration int32) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.convergedAt > 0 {
+		return false
+	}
+	p.convergedAt = iteration
+	return true
+}
+
+// convergence is the iteration the render converged at, or 0
+func (p *progressParser) convergence() int32 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.convergedAt
+}
@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+// engineOutput prints an iteration the way neural_style.lua does
+func engineOutput(iteration int, total string) []string {
+	return []string{
+		fmt.Sprintf("Iteration %d / 1000", iteration),
+		"  Content 1 loss: 100.5",
+		"  Style 1 loss: 20.25",
+		"  Style 2 loss: 4.25",
+		"  Total loss: " + total,
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		iteration int32
+		losses    []pb.LossSample
+	}{
+		{
+			name:      "one iteration",
+			lines:     engineOutput(1, "125"),
+			iteration: 1,
+			losses:    []pb.LossSample{{Iteration: 1, Content: 100.5, Style: 24.5, Total: 125}},
+		},
+		{
+			name:      "unfinished iteration",
+			lines:     append(engineOutput(1, "125"), "Iteration 2 / 1000", "  Content 1 loss: 99"),
+			iteration: 2,
+			losses:    []pb.LossSample{{Iteration: 1, Content: 100.5, Style: 24.5, Total: 125}},
+		},
+		{
+			name:      "losses before any iteration",
+			lines:     []string{"  Content 1 loss: 5", "  Total loss: 5", "Setting up style layer"},
+			iteration: 0,
+		},
+		{
+			name:      "unreadable loss",
+			lines:     []string{"Iteration 1 / 1000", "  Total loss: nan(ind)"},
+			iteration: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		p := &progressParser{}
+		for _, line := range tt.lines {
+			p.parseLine(line)
+		}
+
+		if got := p.lastIteration(); got != tt.iteration {
+			t.Errorf("%s: last iteration %d, want %d", tt.name, got, tt.iteration)
+		}
+
+		losses := p.takeLosses()
+		if len(losses) != len(tt.losses) {
+			t.Errorf("%s: got %d losses, want %d", tt.name, len(losses), len(tt.losses))
+			continue
+		}
+		for i, l := range losses {
+			if *l != tt.losses[i] {
+				t.Errorf("%s: loss %d is %+v, want %+v", tt.name, i, *l, tt.losses[i])
+			}
+		}
+		if len(p.takeLosses()) != 0 {
+			t.Errorf("%s: losses taken twice", tt.name)
+		}
+	}
+}
+
+func TestParseResumed(t *testing.T) {
+	//The engine counts from 1 again when resuming from iteration 300
+	p := &progressParser{offset: 300}
+	var completed []int32
+	for i, total := range []string{"125", "120"} {
+		for _, line := range engineOutput(i+1, total) {
+			if i, ok := p.parseLine(line); ok {
+				completed = append(completed, i)
+			}
+		}
+	}
+
+	//The engine iteration decides when the run is over...
+	if got := p.lastIteration(); got != 2 {
+		t.Errorf("last iteration %d, want the engine's 2", got)
+	}
+	//...but the losses go to the server on the job's scale
+	losses := p.takeLosses()
+	if len(losses) != 2 || losses[0].Iteration != 301 || losses[1].Iteration != 302 {
+		t.Errorf("got losses %v, want them at 301 and 302", losses)
+	}
+	//And so are the iterations it completes
+	if len(completed) != 2 || completed[0] != 301 || completed[1] != 302 {
+		t.Errorf("completed %v, want 301 and 302", completed)
+	}
+}
+
+func TestSuspended(t *testing.T) {
+	p := &progressParser{}
+	for _, line := range engineOutput(1, "125") {
+		p.parseLine(line)
+	}
+	updated := p.lastUpdate()
+
+	p.suspended(5 * time.Minute)
+	if got := p.lastUpdate().Sub(updated); got != 5*time.Minute {
+		t.Errorf("last update moved by %s, want the time suspended", got)
+	}
+}
+
+func TestConverged(t *testing.T) {
+	p := &progressParser{}
+	totals := []string{"1000", "800", "700", "695", "694"}
+	for i, total := range totals {
+		for _, line := range engineOutput((i+1)*10, total) {
+			p.parseLine(line)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		convergence *pb.Convergence
+		iteration   int32
+		want        bool
+	}{
+		{"disabled", nil, 50, false},
+		{"no window", &pb.Convergence{Threshold: 0.5}, 50, false},
+		{"no threshold", &pb.Convergence{Window: 10}, 50, false},
+		{"still improving", &pb.Convergence{Threshold: 0.01, Window: 10}, 30, false},
+		{"flattened", &pb.Convergence{Threshold: 0.01, Window: 10}, 40, true},
+		{"wide window", &pb.Convergence{Threshold: 0.01, Window: 30}, 50, false},
+		{"window before the start", &pb.Convergence{Threshold: 0.5, Window: 100}, 50, false},
+		{"iteration not printed", &pb.Convergence{Threshold: 0.5, Window: 10}, 45, false},
+	}
+
+	for _, tt := range tests {
+		if got := p.converged(tt.convergence, tt.iteration); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConverge(t *testing.T) {
+	p := &progressParser{}
+	if got := p.convergence(); got != 0 {
+		t.Errorf("converged at %d before converging", got)
+	}
+	if !p.converge(150) {
+		t.Errorf("first convergence not recorded")
+	}
+	//Only the first one counts, the engine is already being stopped
+	if p.converge(151) {
+		t.Errorf("converged twice")
+	}
+	if got := p.convergence(); got != 150 {
+		t.Errorf("converged at %d, want 150", got)
+	}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"log"
+	"os/exec"
+	"syscall"
+)
+
+// startGroup starts cmd in its own process group so killGroup can stop the
+// engine together with anything it spawned
+func startGroup(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd.Start()
+}
+
+// signalGroup sends a signal to the engine and anything it spawned
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+
+	err := syscall.Kill(-cmd.Process.Pid, sig)
+	if err != nil {
+		log.Printf("Could not send %s to process group %d. %v", sig, cmd.Process.Pid, err)
+	}
+}
+
+func killGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if err != nil {
+		log.Printf("Could not kill process group %d. %v", cmd.Process.Pid, err)
+		cmd.Process.Kill()
+	}
+}
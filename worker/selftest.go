@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Files the engine loads from its working directory with the default options
+var engineFiles = []string{
+	"neural_style.lua",
+	"models/VGG_ILSVRC_19_layers_deploy.prototxt",
+	"models/VGG_ILSVRC_19_layers.caffemodel",
+}
+
+// How long each engine run of the self-test may take
+var selfTestTimeout = 5 * time.Minute
+
+// Check is the outcome of one self-test step
+type Check struct {
+	Name string
+	Err  error
+}
+
+// SelfTestReport lists the steps of a self-test in the order they ran
+type SelfTestReport struct {
+	Checks []Check
+}
+
+// OK tells whether every check passed
+func (r *SelfTestReport) OK() bool {
+	return r.Problem() == ""
+}
+
+// Problem describes the first failed check, or is empty if all passed
+func (r *SelfTestReport) Problem() string {
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			return fmt.Sprintf("%s: %v", c.Name, c.Err)
+		}
+	}
+	return ""
+}
+
+func (r *SelfTestReport) String() string {
+	var b bytes.Buffer
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			fmt.Fprintf(&b, "FAIL %s: %v\n", c.Name, c.Err)
+		} else {
+			fmt.Fprintf(&b, "ok   %s\n", c.Name)
+		}
+	}
+	return b.String()
+}
+
+func (r *SelfTestReport) add(name string, err error) bool {
+	r.Checks = append(r.Checks, Check{Name: name, Err: err})
+	return err == nil
+}
+
+// SelfTest checks that the engine can run on each device: the th binary,
+// the engine and model files, the backend and finally a tiny render. Checks
+// that depend on a failed one are skipped.
+func SelfTest(devices []string, workDir string) *SelfTestReport {
+	r := &SelfTestReport{}
+
+	_, err := exec.LookPath("th")
+	if !r.add("engine binary", err) {
+		return r
+	}
+
+	ok := true
+	for _, f := range engineFiles {
+		_, err := os.Stat(f)
+		ok = r.add("file "+f, err) && ok
+	}
+	if !ok {
+		return r
+	}
+
+	dir := path.Join(workDir, "selftest")
+	err = writeTestImages(dir)
+	if !r.add("test images", err) {
+		return r
+	}
+	defer os.RemoveAll(dir)
+
+	for i, d := range devices {
+		s := &slot{index: int32(i), device: d}
+		if !r.add("backend on "+d, checkBackend(s)) {
+			continue
+		}
+		r.add("test render on "+d, testRender(s, dir))
+	}
+
+	return r
+}
+
+// checkBackend loads the torch packages the device needs
+func checkBackend(s *slot) error {
+	script := "require 'nn'"
+	if i, err := gpuIndex(s.device); err == nil {
+		script = fmt.Sprintf("require 'cutorch'; require 'cudnn'; assert(cutorch.getDeviceCount() > %d, 'no such GPU')", i)
+	}
+	return runEngine("-e", script)
+}
+
+// testRender runs a couple of iterations on tiny images
+func testRender(s *slot, dir string) error {
+	output := path.Join(dir, "out_"+strconv.Itoa(int(s.index))+".png")
+	args := []string{"neural_style.lua",
+		"-style_image", path.Join(dir, "style.png"),
+		"-content_image", path.Join(dir, "content.png"),
+		"-output_image", output,
+		"-image_size", "64",
+		"-num_iterations", "2",
+		"-print_iter", "1",
+		"-save_iter", "0",
+	}
+	args = append(args, s.engineArgs()...)
+
+	err := runEngine(args...)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(output)
+	if err != nil {
+		return fmt.Errorf("No output image. %v", err)
+	}
+	return nil
+}
+
+// runEngine runs th, killing it if it takes longer than the self-test allows.
+// Errors carry the end of the output, which usually says what is wrong.
+func runEngine(args ...string) error {
+	logs := newLogBuffer(0)
+	cmd := exec.Command("th", args...)
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+
+	err := startGroup(cmd)
+	if err != nil {
+		return err
+	}
+
+	timer := time.AfterFunc(selfTestTimeout, func() { killGroup(cmd) })
+	err = cmd.Wait()
+	timedOut := !timer.Stop()
+
+	switch {
+	case timedOut:
+		return fmt.Errorf("timed out after %s", selfTestTimeout)
+	case err != nil:
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(logs.tail(5)))
+	}
+	return nil
+}
+
+func writeTestImages(dir string) error {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	content := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	style := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			content.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 128, 255})
+			if (x/8+y/8)%2 == 0 {
+				style.Set(x, y, color.RGBA{200, 40, 40, 255})
+			} else {
+				style.Set(x, y, color.RGBA{40, 40, 200, 255})
+			}
+		}
+	}
+
+	for name, img := range map[string]image.Image{"content.png": content, "style.png": style} {
+		var b bytes.Buffer
+		err := png.Encode(&b, img)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(path.Join(dir, name), b.Bytes(), 0600)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"errors"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfTestReport(t *testing.T) {
+	r := &SelfTestReport{}
+	r.add("engine binary", nil)
+	if !r.OK() || r.Problem() != "" {
+		t.Errorf("report with passing checks not ok: %q", r.Problem())
+	}
+
+	r.add("file neural_style.lua", errors.New("missing"))
+	r.add("file models/vgg", errors.New("also missing"))
+	if r.OK() {
+		t.Errorf("report with failed checks ok")
+	}
+	if got := r.Problem(); got != "file neural_style.lua: missing" {
+		t.Errorf("problem %q, want the first failed check", got)
+	}
+	want := "ok   engine binary\nFAIL file neural_style.lua: missing\nFAIL file models/vgg: also missing\n"
+	if got := r.String(); got != want {
+		t.Errorf("report printed as\n%s\nwant\n%s", got, want)
+	}
+}
+
+// fakeEngine puts a th script with the given body first in the PATH
+func fakeEngine(t *testing.T, body string) func() {
+	dir, err := ioutil.TempDir("", "engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "th"), []byte("#!/bin/sh\n"+body+"\n"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir+":"+oldPath)
+	return func() {
+		os.Setenv("PATH", oldPath)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSelfTestMissingEngine(t *testing.T) {
+	oldPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", oldPath)
+	os.Setenv("PATH", "")
+
+	r := SelfTest([]string{"cpu"}, os.TempDir())
+	if len(r.Checks) != 1 || r.OK() {
+		t.Errorf("got %v, want it to stop after the engine binary", r.Checks)
+	}
+}
+
+func TestRunEngine(t *testing.T) {
+	restore := fakeEngine(t, `echo "loading"; echo "cuda runtime error" >&2; exit 1`)
+	err := runEngine()
+	restore()
+	if err == nil || !strings.Contains(err.Error(), "cuda runtime error") {
+		t.Errorf("got %v, want the engine output in the error", err)
+	}
+
+	restore = fakeEngine(t, "exit 0")
+	err = runEngine()
+	restore()
+	if err != nil {
+		t.Errorf("successful run failed: %v", err)
+	}
+
+	oldTimeout := selfTestTimeout
+	defer func() { selfTestTimeout = oldTimeout }()
+	selfTestTimeout = 100 * time.Millisecond
+
+	restore = fakeEngine(t, "sleep 60")
+	defer restore()
+	start := time.Now()
+	err = runEngine()
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("got %v, want a timeout", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("engine not killed on timeout")
+	}
+}
+
+func TestWriteTestImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selftest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := writeTestImages(path.Join(dir, "images")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"content.png", "style.png"} {
+		f, err := os.Open(path.Join(dir, "images", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+			t.Errorf("%s is %v, want 64x64", name, b)
+		}
+	}
+}
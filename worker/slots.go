@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+// CPU is the device of slots that render without a GPU
+const CPU = "cpu"
+
+// ParseSlots reads a comma separated list of devices, such as gpu:0,gpu:1 or
+// cpu. Each device becomes a slot running one job at a time.
+func ParseSlots(s string) ([]string, error) {
+	var devices []string
+	seen := make(map[string]bool)
+	for _, d := range strings.Split(s, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		if _, err := gpuIndex(d); err != nil && d != CPU {
+			return nil, fmt.Errorf("Unknown device %q. Use gpu:N or cpu", d)
+		}
+		if seen[d] && d != CPU {
+			return nil, fmt.Errorf("Device %q listed twice", d)
+		}
+		seen[d] = true
+		devices = append(devices, d)
+	}
+
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("No devices given")
+	}
+	return devices, nil
+}
+
+func gpuIndex(device string) (int, error) {
+	if !strings.HasPrefix(device, "gpu:") {
+		return 0, fmt.Errorf("Not a GPU: %q", device)
+	}
+	i, err := strconv.Atoi(strings.TrimPrefix(device, "gpu:"))
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("Bad GPU index in %q", device)
+	}
+	return i, nil
+}
+
+// Capabilities describes the backends and devices of a set of slots
+func Capabilities(devices []string) []string {
+	var gpu, cpu bool
+	for _, d := range devices {
+		if d == CPU {
+			cpu = true
+		} else {
+			gpu = true
+		}
+	}
+
+	var r []string
+	if gpu {
+		r = append(r, "backend:cudnn")
+	}
+	if cpu {
+		r = append(r, "backend:nn")
+	}
+	return append(r, devices...)
+}
+
+// slot is one job loop of the worker, bound to a device
+type slot struct {
+	index  int32
+	device string
+
+	// Protected by the worker lock
+	state   pb.WorkerState
+	jobID   string
+	jobName string
+	engine  *exec.Cmd
+}
+
+// engineArgs selects the device the engine renders on
+func (s *slot) engineArgs() []string {
+	i, err := gpuIndex(s.device)
+	if err != nil {
+		return []string{"-backend", "nn", "-gpu", "-1"}
+	}
+	return []string{"-backend", "cudnn", "-cudnn_autotune", "-gpu", strconv.Itoa(i)}
+}
+
+func (s *slot) toProto() *pb.SlotInfo {
+	return &pb.SlotInfo{
+		Index:   s.index,
+		Device:  s.device,
+		State:   s.state,
+		JobId:   s.jobID,
+		JobName: s.jobName,
+	}
+}
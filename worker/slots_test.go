@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSlots(t *testing.T) {
+	tests := []struct {
+		in      string
+		devices string
+		caps    string
+	}{
+		{"gpu:0", "gpu:0", "backend:cudnn gpu:0"},
+		{" gpu:0, gpu:1 ,", "gpu:0 gpu:1", "backend:cudnn gpu:0 gpu:1"},
+		{"cpu,cpu", "cpu cpu", "backend:nn cpu cpu"},
+		{"gpu:1,cpu", "gpu:1 cpu", "backend:cudnn backend:nn gpu:1 cpu"},
+		{"gpu:0,gpu:0", "", ""},
+		{"gpu:-1", "", ""},
+		{"gpu:x", "", ""},
+		{"tpu:0", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		devices, err := ParseSlots(tt.in)
+		if tt.devices == "" {
+			if err == nil {
+				t.Errorf("%q: parsed as %v, want an error", tt.in, devices)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if got := strings.Join(devices, " "); got != tt.devices {
+			t.Errorf("%q: got devices %q, want %q", tt.in, got, tt.devices)
+		}
+		if got := strings.Join(Capabilities(devices), " "); got != tt.caps {
+			t.Errorf("%q: got capabilities %q, want %q", tt.in, got, tt.caps)
+		}
+	}
+}
+
+func TestEngineArgs(t *testing.T) {
+	for device, want := range map[string]string{
+		"gpu:2": "-backend cudnn -cudnn_autotune -gpu 2",
+		"cpu":   "-backend nn -gpu -1",
+	} {
+		s := &slot{device: device}
+		if got := strings.Join(s.engineArgs(), " "); got != want {
+			t.Errorf("%s: got %q, want %q", device, got, want)
+		}
+	}
+}
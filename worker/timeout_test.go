@@ -0,0 +1,168 @@
+package worker
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+)
+
+func TestTimeouts(t *testing.T) {
+	w := &Worker{config: Config{MaxDuration: 2 * time.Hour, StallTimeout: 10 * time.Minute}}
+
+	tests := []struct {
+		name     string
+		timeouts *pb.Timeouts
+		max      time.Duration
+		stall    time.Duration
+	}{
+		{"worker defaults", nil, 2 * time.Hour, 10 * time.Minute},
+		{"unset", &pb.Timeouts{}, 2 * time.Hour, 10 * time.Minute},
+		{"job duration", &pb.Timeouts{MaxDuration: 60}, time.Minute, 10 * time.Minute},
+		{"job stall", &pb.Timeouts{Stall: 30}, 2 * time.Hour, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		max, stall := w.timeouts(tt.timeouts)
+		if max != tt.max || stall != tt.stall {
+			t.Errorf("%s: got %s and %s, want %s and %s", tt.name, max, stall, tt.max, tt.stall)
+		}
+	}
+}
+
+func TestTimeoutReason(t *testing.T) {
+	started := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return started.Add(d) }
+
+	tests := []struct {
+		name    string
+		now     time.Duration
+		updated time.Duration
+		max     time.Duration
+		stall   time.Duration
+		want    string
+	}{
+		{"within both", 50 * time.Minute, 45 * time.Minute, time.Hour, 10 * time.Minute, ""},
+		{"too long", 61 * time.Minute, 60 * time.Minute, time.Hour, 10 * time.Minute, "longer than 1h0m0s"},
+		{"stalled", 30 * time.Minute, 15 * time.Minute, time.Hour, 10 * time.Minute, "no progress for 10m0s"},
+		{"stalled from the start", 11 * time.Minute, 0, time.Hour, 10 * time.Minute, "no progress"},
+		{"disabled", 10 * time.Hour, 0, 0, 0, ""},
+	}
+	for _, tt := range tests {
+		got := timeoutReason(at(tt.now), started, at(tt.updated), tt.max, tt.stall)
+		if (got == "") != (tt.want == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKillGroup(t *testing.T) {
+	//The shell stands in for the engine and the sleep for a child it spawned
+	cmd := exec.Command("sh", "-c", "sleep 60 & wait")
+	if err := startGroup(cmd); err != nil {
+		t.Skip(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	killGroup(cmd)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("engine still running after being killed")
+	}
+	//Nothing is left in the group once the children are reaped
+	for i := 0; syscall.Kill(-cmd.Process.Pid, 0) == nil; i++ {
+		if i == 50 {
+			t.Fatalf("children of the engine still running")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestPauseStall(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	input := func(title string) *pb.InputImage {
+		return &pb.InputImage{Title: title, Format: pb.ImageFormat_PNG, Image: b.Bytes()}
+	}
+
+	for _, paused := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "worker")
+		if err != nil {
+			t.Fatal(err)
+		}
+		//The engine prints the first iteration, then hangs until told to go on
+		release := path.Join(dir, "release")
+		restore := fakeEngine(t, `while [ $# -gt 0 ]; do
+	case $1 in
+	-content_image) content=$2;;
+	-output_image) out=$2;;
+	esac
+	shift
+done
+echo "Iteration 1 / 2"
+while [ ! -f `+release+` ]; do sleep 0.1; done
+echo "Iteration 2 / 2"
+cp "$content" "$out"`)
+
+		c := &fakeClient{}
+		s := &slot{}
+		w := &Worker{client: c, slots: []*slot{s}, config: Config{WorkDir: dir, GracePeriod: time.Second, StallTimeout: 1500 * time.Millisecond}}
+		job := &pb.Job{Id: "job", Name: "cat", AttemptId: "a1", Style: input("wave"), Content: input("cat"), Params: &pb.JobParameters{NumIterations: 2}}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			w.runJob(context.Background(), s, job)
+		}()
+
+		if paused {
+			for i := 0; ; i++ {
+				w.lock.Lock()
+				started := s.engine != nil
+				w.lock.Unlock()
+				if started {
+					break
+				}
+				if i == 50 {
+					t.Fatalf("engine not started")
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			w.applyCommand(pb.WorkerCommand_COMMAND_PAUSE)
+		}
+		time.Sleep(3 * time.Second)
+		if paused {
+			w.applyCommand(pb.WorkerCommand_COMMAND_RESUME)
+		}
+		ioutil.WriteFile(release, nil, 0600)
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("paused %t: job still running", paused)
+		}
+		restore()
+		os.RemoveAll(dir)
+
+		if paused && (len(c.complete) != 1 || len(c.fails) != 0) {
+			t.Errorf("paused: completed %d times and failed %v, want the pause not to count as a stall", len(c.complete), c.fails)
+		}
+		if !paused && (len(c.fails) != 1 || !c.fails[0].TimedOut) {
+			t.Errorf("not paused: failed %v, want a stall", c.fails)
+		}
+	}
+}
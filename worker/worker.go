@@ -2,211 +2,1103 @@ package worker
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
 	"io/ioutil"
 	"log"
-	"os"
 	"os/exec"
-	"path"
 	"strconv"
-	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/mgilbir/neural-style-art-project/pb"
+	"github.com/mgilbir/neural-style-art-project/upload"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
+// Version is reported to the server when the worker registers
+const Version = "0.2.0"
+
+type Config struct {
+	ID           string
+	Hostname     string
+	Capabilities []string
+
+	// Devices has one entry per slot, each running its own jobs. Defaults
+	// to a single slot on gpu:0.
+	Devices []string
+
+	// GracePeriod bounds how long handing back an interrupted job can take
+	GracePeriod time.Duration
+
+	// WorkDir holds a scratch directory per job
+	WorkDir   string
+	Retention Retention
+
+	// Probe runs the self-test at startup. Jobs are refused until it passes.
+	Probe bool
+
+	// Cache keeps style images between jobs. Nil disables it.
+	Cache *Cache
+
+	// MaxLogSize bounds the engine output uploaded for each attempt
+	MaxLogSize int
+
+	// MaxDuration and StallTimeout bound a render unless the job sets its
+	// own. Zero disables the check.
+	MaxDuration  time.Duration
+	StallTimeout time.Duration
+
+	// ServerMaxMsgSize is the largest message the server accepts. Results
+	// that don't fit are streamed in chunks. Zero uses the gRPC default.
+	ServerMaxMsgSize int
+}
+
+// The engine saves an intermediate result every saveEvery iterations
+const saveEvery = 100
+
+// failure tells the server what to do with a job after a failed attempt
+type failure int
+
+const (
+	// failRetry hands the job to another attempt, until it has failed
+	// too often
+	failRetry failure = iota
+	// failPermanent gives up on a job whose inputs can never be rendered
+	failPermanent
+	// failPreempted hands back a job the worker stopped before it could
+	// finish, which doesn't count against the job
+	failPreempted
+	// failTimedOut hands the job to another attempt, unless it keeps timing
+	// out
+	failTimedOut
+)
+
 type Worker struct {
 	conn          *grpc.ClientConn
 	client        pb.NeuralStyleWorkerClient
-	basedir       string
+	config        Config
 	maxIterations int32
+
+	lock              sync.Mutex
+	heartbeatInterval time.Duration
+	slots             []*slot
+	paused            bool
+	draining          bool
+	shutdown          bool
+	problem           string
+
+	health health
 }
 
-func New(conn *grpc.ClientConn) *Worker {
-	return &Worker{
-		conn:          conn,
-		client:        pb.NewNeuralStyleWorkerClient(conn),
-		maxIterations: 500,
+func New(conn *grpc.ClientConn, config Config) *Worker {
+	if len(config.Devices) == 0 {
+		config.Devices = []string{"gpu:0"}
+	}
+
+	w := &Worker{
+		conn:              conn,
+		client:            pb.NewNeuralStyleWorkerClient(conn),
+		config:            config,
+		maxIterations:     500,
+		heartbeatInterval: 15 * time.Second,
 	}
+	for i, d := range config.Devices {
+		w.slots = append(w.slots, &slot{
+			index:  int32(i),
+			device: d,
+			state:  pb.WorkerState_WORKER_IDLE,
+		})
+	}
+	return w
 }
 
+// Run processes jobs on every slot until ctx is cancelled or the server
+// tells the worker to shut down
 func (w *Worker) Run(ctx context.Context) error {
+	if w.config.Probe && !w.probe() {
+		go w.reprobe(ctx)
+	}
+
+	w.register(ctx)
+	go w.heartbeat(ctx)
+
+	var slots sync.WaitGroup
+	for _, s := range w.slots {
+		slots.Add(1)
+		go func(s *slot) {
+			defer slots.Done()
+			w.runSlot(ctx, s)
+		}(s)
+	}
+	slots.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	log.Println("Shutting down as requested by the server")
+	return nil
+}
+
+// runSlot is the job loop of a single slot
+func (w *Worker) runSlot(ctx context.Context, s *slot) {
 	emptyJob := pb.Job{}
+	retry := newBackoff()
 	for {
-		//TODO: cancel on the context?
+		if ctx.Err() != nil {
+			return
+		}
+
+		if w.shuttingDown() {
+			return
+		}
+
+		if state, held := w.held(); held {
+			//Wait for the server to resume us through the heartbeat
+			w.setState(s, state, "", "")
+			w.wait(ctx, w.interval())
+			continue
+		}
+
+		w.setState(s, pb.WorkerState_WORKER_IDLE, "", "")
 
 		//Get new job
-		job, err := w.client.RequestJob(ctx, &pb.JobRequest{})
+		var job *pb.Job
+		err := w.call(ctx, func(ctx context.Context) error {
+			var err error
+			job, err = w.client.RequestJob(ctx, &pb.JobRequest{
+				WorkerId:      w.config.ID,
+				Slot:          s.index,
+				CachedDigests: w.cachedDigests(),
+			})
+			return err
+		})
 		if err != nil {
-			log.Println(err)
+			if ctx.Err() != nil {
+				return
+			}
+			d := retry.next()
+			log.Printf("Could not request a job for slot %d (%s), retrying in %s. %v", s.index, s.device, d, err)
+			w.wait(ctx, d)
+			continue
+		}
+		retry.reset()
+
+		if job.Command != pb.WorkerCommand_COMMAND_NONE {
+			w.applyCommand(job.Command)
+			if job.Id == "" {
+				continue
+			}
 		}
 
 		if *job == emptyJob {
 			//Nothing to see here, keep moving but not too quickly...
-			log.Println("No jobs availabla, wait and retry")
-			time.Sleep(10 * time.Second)
+			log.Printf("No jobs availabla for slot %d (%s), wait and retry", s.index, s.device)
+			w.wait(ctx, 10*time.Second)
 			continue
 		}
 
-		jobDir := getDirectory(w.basedir, job.Id, job.Name)
+		w.setState(s, pb.WorkerState_WORKER_BUSY, job.Id, job.Name)
+		w.runJob(ctx, s, job)
+	}
+}
+
+// runJob renders a single job in its own workspace, reporting progress and
+// the result to the server. If ctx is cancelled the engine is stopped and the
+// job is handed back to the server after uploading the latest partial result
+// as a checkpoint.
+func (w *Worker) runJob(ctx context.Context, s *slot, job *pb.Job) {
+	//Reporting the outcome may outlast the job context by the grace period,
+	//so an attempt stopped while it reports still reaches the server
+	rctx, cancel := w.graceContext(ctx)
+	defer cancel()
+
+	//Keep the engine output of this attempt for the server
+	logs := newLogBuffer(w.config.MaxLogSize)
+	defer w.uploadLog(rctx, job, logs)
+
+	fail := func(ctx context.Context, reason string, f failure) {
+		w.fail(ctx, job, reason, logs.tail(failureTailLines), f)
+	}
 
-		//TODO: cleanup
-		styleFilename, err := prepareFilename(jobDir, job.Style.Title, job.Style.Format)
+	ws, err := newWorkspace(w.config.WorkDir, job.Id, job.Name)
+	if err != nil {
+		log.Println(err)
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+
+	succeeded := false
+	defer func() {
+		ws.cleanup(w.config.Retention, succeeded)
+	}()
+
+	err = w.resolveStyle(job.Style)
+	if err != nil {
+		//The style was evicted after the worker listed it as cached, the
+		//next attempt gets it from the server
+		log.Println(err)
+		fail(rctx, err.Error(), failPreempted)
+		return
+	}
+
+	//Inputs the engine can't read would fail every attempt the same way
+	for _, img := range []*pb.InputImage{job.Style, job.Content} {
+		err = checkInput(img)
 		if err != nil {
 			log.Println(err)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+			fail(rctx, err.Error(), failPermanent)
+			return
 		}
-		err = ioutil.WriteFile(styleFilename, job.Style.Image, 0755)
+	}
+
+	styleFilename, err := ws.writeImage("style", job.Style)
+	if err != nil {
+		log.Println(err)
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+
+	contentFilename, err := ws.writeImage("content", job.Content)
+	if err != nil {
+		log.Println(err)
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+
+	maxIterations := w.iterations(job.Params)
+
+	//Continue from the checkpoint if the job was interrupted before, or
+	//start from the image the job came with
+	startIteration := int32(0)
+	initFilename := ""
+	if job.Init != nil && job.StartIteration < maxIterations {
+		initFilename, err = ws.writeImage("init", job.Init)
 		if err != nil {
 			log.Println(err)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+			fail(rctx, err.Error(), failRetry)
+			return
+		}
+		startIteration = job.StartIteration
+		if startIteration > 0 {
+			log.Printf("Resuming %q - %q from iteration %d", job.Id, job.Name, startIteration)
 		}
+	}
 
-		contentFilename, err := prepareFilename(jobDir, job.Content.Title, job.Content.Format)
-		if err != nil {
-			log.Println(err)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+	//Iterations are counted by the engine from the start of this run
+	numIterations := maxIterations - startIteration
+
+	//Run job
+	args := []string{"neural_style.lua",
+		"-style_image", styleFilename,
+		"-content_image", contentFilename,
+		"-output_image", ws.output(),
+		"-print_iter", "1",
+		"-save_iter", strconv.Itoa(saveEvery),
+		"-num_iterations", strconv.Itoa(int(numIterations)),
+	}
+	args = append(args, s.engineArgs()...)
+	args = append(args, weightArgs(job.Params)...)
+	if job.Params != nil && job.Params.ImageSize > 0 {
+		args = append(args, "-image_size", strconv.Itoa(int(job.Params.ImageSize)))
+	}
+	if initFilename != "" {
+		args = append(args, "-init", "image", "-init_image", initFilename)
+	}
+
+	cmd := exec.Command("th", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		log.Println(err)
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		log.Println(err)
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+	err = startGroup(cmd)
+	if err != nil {
+		log.Println(err)
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+	w.setEngine(s, cmd)
+	defer w.setEngine(s, nil)
+
+	//Stop the engine if we are asked to shut down
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			log.Printf("Stopping job %q - %q", job.Id, job.Name)
+			killGroup(cmd)
+		case <-done:
 		}
-		err = ioutil.WriteFile(contentFilename, job.Content.Image, 0755)
-		if err != nil {
-			log.Println(err)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+	}()
+
+	started := time.Now()
+	progress := &progressParser{offset: startIteration, updated: started}
+	maxDuration, stallTimeout := w.timeouts(job.Params.GetTimeouts())
+
+	var readers sync.WaitGroup
+	readers.Add(2)
+
+	stdoutScanner := bufio.NewScanner(stdout)
+	go func() {
+		defer readers.Done()
+		for stdoutScanner.Scan() {
+			logs.writeLine("", stdoutScanner.Text())
+			i, ok := progress.parseLine(stdoutScanner.Text())
+
+			//No point in carrying on once the loss has flattened, as long
+			//as the engine has finished saving a result to return
+			if !ok || i-startIteration <= saveEvery || !progress.converged(job.Params.GetConvergence(), i) {
+				continue
+			}
+			if progress.converge(i) {
+				log.Printf("Job %q - %q converged at iteration %d", job.Id, job.Name, i)
+				killGroup(cmd)
+			}
+		}
+	}()
+
+	stderrScanner := bufio.NewScanner(stderr)
+	go func() {
+		defer readers.Done()
+		for stderrScanner.Scan() {
+			logs.writeLine("ERRORS | ", stderrScanner.Text())
 		}
+	}()
 
-		//Run job
-		iterations := int32(0)
+	//Report the intermediate results the engine has finished writing. The
+	//engine saves an iteration before it prints the next one, so any file
+	//older than the last printed iteration is complete.
+	reported := startIteration
 
-		cmd := exec.Command("th", "neural_style.lua",
-			"-style_image", styleFilename,
-			"-content_image", contentFilename,
-			"-backend", "cudnn", "-cudnn_autotune",
-			"-gpu", "0",
-			"-print_iter", "1",
-			"-num_iterations", strconv.Itoa(int(w.maxIterations)),
-		)
-		stdout, err := cmd.StdoutPipe()
+	//Reports of this attempt are numbered so the server can drop repeats
+	var sequence int64
+	result := func(iteration int32, filename string) (*pb.JobResult, error) {
+		msg, err := readResult(job, iteration, filename)
 		if err != nil {
-			log.Println(err)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+			return nil, err
 		}
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			log.Println(err)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+		sequence++
+		msg.Sequence = sequence
+		msg.Losses = progress.takeLosses()
+		return msg, nil
+	}
+
+	//Set if the server no longer wants this attempt
+	abandoned := false
+
+	//Partial results stay in the workspace until the server has them, so
+	//reporting can stop on a connection error and pick up again later
+	reportPartials := func(ctx context.Context, upTo int32) error {
+		if progress.convergence() > 0 || abandoned {
+			return nil
 		}
-		err = cmd.Start()
+
+		partials, err := ws.partials()
 		if err != nil {
 			log.Println(err)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+			return nil
 		}
 
-		//Report progress
-		stdoutScanner := bufio.NewScanner(stdout)
-		go func() {
-			for stdoutScanner.Scan() {
-				txt := stdoutScanner.Text()
-				txt = strings.TrimSpace(txt)
-				tokens := strings.SplitN(txt, " ", -1)
-				if strings.Contains(tokens[0], "Iteration") {
-					i, err := strconv.Atoi(tokens[1])
-					if err != nil {
-						log.Printf("Problem parsing iteration %q. %v", txt, err)
-					}
-					iterations = int32(i)
-
-					if i > 50 && i%100 == 0 {
-						go func(i int32) {
-							outfile := "out.png"
-							if i != w.maxIterations {
-								ii := i / 100
-								outfile = fmt.Sprintf("out_%d00.png", ii)
-							}
-
-							//Give time to the OS to save the file
-							time.Sleep(3 * time.Second)
-							dest := path.Join(jobDir, outfile)
-							err := os.Rename(outfile, dest)
-							if err != nil {
-								log.Printf("Error while moving file %q to %q. %v", outfile, dest, err)
-							}
-
-							//Report progress
-							f, err := os.Open(dest)
-							if err != nil {
-								log.Printf("Error opening moved file. %v", err)
-							}
-
-							img, err := ioutil.ReadAll(f)
-							if err != nil {
-								log.Printf("Error reading moved file. %v", err)
-							}
-							f.Close()
-
-							msg := pb.JobResult{
-								Id:            job.Id,
-								Name:          job.Name,
-								ProgressCount: i,
-								Format:        pb.ImageFormat_PNG,
-								Image:         img,
-							}
-							if i != w.maxIterations {
-								w.client.ProgressReport(ctx, &msg)
-							} else {
-								w.client.CompleteJob(ctx, &msg)
-							}
-						}(int32(i))
+		for _, p := range partials {
+			i := startIteration + p.iteration
+			if i <= reported || p.iteration >= upTo {
+				continue
+			}
+
+			msg, err := result(i, p.filename)
+			if err != nil {
+				log.Printf("Error reading partial result %q. %v", p.filename, err)
+				continue
+			}
+
+			err = w.call(ctx, func(ctx context.Context) error {
+				return w.sendResult(ctx, msg, false)
+			})
+			if err != nil && !permanent(err) {
+				progress.returnLosses(msg.Losses)
+				return err
+			}
+			if err != nil {
+				log.Printf("Server rejected progress of %q - %q, abandoning it. %v", job.Id, job.Name, err)
+				abandoned = true
+				killGroup(cmd)
+				return nil
+			}
+			reported = i
+		}
+		return nil
+	}
+
+	//Set if the engine is stopped for taking too long or hanging
+	timedOut := ""
+	checkTimeouts := func() {
+		timedOut = timeoutReason(time.Now(), started, progress.lastUpdate(), maxDuration, stallTimeout)
+		if timedOut == "" {
+			return
+		}
+		log.Printf("Stopping job %q - %q. %s", job.Id, job.Name, timedOut)
+		killGroup(cmd)
+	}
+
+	stopWatching := make(chan struct{})
+	watcherDone := make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		retry := newBackoff()
+		retryAt := time.Time{}
+		last := started
+		for {
+			select {
+			case <-stopWatching:
+				return
+			case now := <-ticker.C:
+				//Time spent paused counts towards neither timeout
+				if w.isPaused() {
+					started = started.Add(now.Sub(last))
+					progress.suspended(now.Sub(last))
+				}
+				last = now
+
+				if now.After(retryAt) {
+					err := reportPartials(ctx, progress.lastIteration())
+					if err != nil && ctx.Err() == nil {
+						d := retry.next()
+						retryAt = now.Add(d)
+						log.Printf("Could not report progress of %q - %q, retrying in %s. %v", job.Id, job.Name, d, err)
+					} else {
+						retry.reset()
 					}
 				}
+				if timedOut == "" && progress.convergence() == 0 && !abandoned {
+					checkTimeouts()
+				}
 			}
-		}()
+		}
+	}()
 
-		stderrScanner := bufio.NewScanner(stderr)
-		go func() {
-			for stderrScanner.Scan() {
-				fmt.Printf("ERRORS | %s\n", stderrScanner.Text())
-			}
-		}()
+	readers.Wait()
+	err = cmd.Wait()
+	if err != nil {
+		log.Println(err)
+	}
 
-		err = cmd.Wait()
+	close(stopWatching)
+	<-watcherDone
+
+	if ctx.Err() != nil {
+		//Hand the job back so another worker can continue from the latest
+		//checkpoint. The job context is gone, so use the grace period.
+		reportPartials(rctx, progress.lastIteration())
+		fail(rctx, "preempted, retryable", failPreempted)
+		return
+	}
+
+	if abandoned {
+		return
+	}
+
+	if timedOut != "" {
+		//Keep what was rendered so the next attempt can resume from it
+		reportPartials(rctx, progress.lastIteration())
+		fail(rctx, timedOut, failTimedOut)
+		return
+	}
+
+	if convergedAt := progress.convergence(); convergedAt > 0 {
+		//The engine may have been saving the iteration it converged at
+		//when it was stopped, so return the one saved before
+		partials, err := ws.partials()
 		if err != nil {
 			log.Println(err)
+			fail(rctx, err.Error(), failRetry)
+			return
+		}
+		var last *partialFile
+		for i, p := range partials {
+			if startIteration+p.iteration < convergedAt {
+				last = &partials[i]
+			}
+		}
+		if last == nil {
+			reason := fmt.Sprintf("Converged at %d before saving a result", convergedAt)
+			log.Printf("Failed processing %q - %q. %s", job.Id, job.Name, reason)
+			fail(rctx, reason, failRetry)
+			return
 		}
 
-		if iterations != w.maxIterations {
-			log.Printf("Failed processing. Expected %d iterations. Saw %d", w.maxIterations, iterations)
-			w.client.FailJob(ctx, &pb.JobFail{Id: job.Id, Name: job.Name})
-			continue
+		returned := startIteration + last.iteration
+		msg, err := result(returned, last.filename)
+		if err != nil {
+			log.Printf("Error reading result %q. %v", last.filename, err)
+			fail(rctx, err.Error(), failRetry)
+			return
+		}
+		msg.Annotation = fmt.Sprintf("converged at %d, result from iteration %d", convergedAt, returned)
+
+		succeeded = w.complete(rctx, msg)
+		return
+	}
+
+	if err != nil {
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+
+	seen := progress.lastIteration()
+	if seen != numIterations {
+		log.Printf("Failed processing. Expected %d iterations. Saw %d", numIterations, seen)
+		fail(rctx, fmt.Sprintf("Expected %d iterations. Saw %d", numIterations, seen), failRetry)
+		return
+	}
+
+	//Every file is complete once the engine has exited. Wait for the server
+	//to have them all before completing.
+	for retry := newBackoff(); ; {
+		err := reportPartials(rctx, numIterations+1)
+		if err == nil || rctx.Err() != nil {
+			break
+		}
+		d := retry.next()
+		log.Printf("Could not report progress of %q - %q, retrying in %s. %v", job.Id, job.Name, d, err)
+		w.wait(rctx, d)
+	}
+	if abandoned {
+		return
+	}
+
+	msg, err := result(maxIterations, ws.output())
+	if err != nil {
+		log.Printf("Error reading result %q. %v", ws.output(), err)
+		fail(rctx, err.Error(), failRetry)
+		return
+	}
+
+	succeeded = w.complete(rctx, msg)
+}
+
+// resolveStyle fills in a style image the server left out because it is
+// cached, or caches it for the next job
+func (w *Worker) resolveStyle(img *pb.InputImage) error {
+	cache := w.config.Cache
+	if len(img.Image) > 0 {
+		if cache != nil && img.Digest != "" {
+			err := cache.Put(img.Digest, img.Image)
+			if err != nil {
+				log.Printf("Could not cache style image %q. %v", img.Title, err)
+			}
+		}
+		return nil
+	}
+
+	if cache == nil || img.Digest == "" {
+		return fmt.Errorf("Style image %q missing", img.Title)
+	}
+	b, ok := cache.Get(img.Digest)
+	if !ok {
+		return fmt.Errorf("Style image %q no longer cached", img.Title)
+	}
+	img.Image = b
+	return nil
+}
+
+func (w *Worker) cachedDigests() []string {
+	if w.config.Cache == nil {
+		return nil
+	}
+	return w.config.Cache.Digests()
+}
+
+func (w *Worker) complete(ctx context.Context, msg *pb.JobResult) bool {
+	err := w.deliver(ctx, fmt.Sprintf("completion of %q - %q", msg.Id, msg.Name), func(ctx context.Context) error {
+		return w.sendResult(ctx, msg, true)
+	})
+	if err != nil {
+		log.Printf("Could not report completion of %q - %q. %v", msg.Id, msg.Name, err)
+		return false
+	}
+	return true
+}
+
+// sendResult reports progress, or completion if complete is set. Images too
+// large for a single message are streamed in chunks.
+func (w *Worker) sendResult(ctx context.Context, msg *pb.JobResult, complete bool) error {
+	if upload.Fits(len(msg.Image), w.config.ServerMaxMsgSize) {
+		var err error
+		if complete {
+			_, err = w.client.CompleteJob(ctx, msg)
+		} else {
+			_, err = w.client.ProgressReport(ctx, msg)
 		}
+		return err
 	}
 
+	stream, err := w.client.UploadResult(ctx)
+	if err != nil {
+		return err
+	}
+
+	header := *msg
+	header.Image = nil
+	header.Digest = upload.Digest(msg.Image)
+
+	err = stream.Send(&pb.ResultUpload{Result: &header, Complete: complete})
+	if err == nil {
+		err = upload.Send(pb.ImagePart_PART_RESULT, msg.Image, func(c *pb.ImageChunk) error {
+			return stream.Send(&pb.ResultUpload{Chunk: c})
+		})
+	}
+	//The server ended the stream early, the reason comes with the response
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// checkInput reports an input image the engine won't be able to read
+func checkInput(img *pb.InputImage) error {
+	_, _, err := image.DecodeConfig(bytes.NewReader(img.Image))
+	if err != nil {
+		return fmt.Errorf("Could not decode image %q. %v", img.Title, err)
+	}
 	return nil
 }
 
-func getDirectory(basedir string, id string, name string) string {
-	return path.Join(basedir, name, id)
+func readResult(job *pb.Job, iteration int32, filename string) (*pb.JobResult, error) {
+	img, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.JobResult{
+		Id:            job.Id,
+		Name:          job.Name,
+		AttemptId:     job.AttemptId,
+		ProgressCount: iteration,
+		Format:        pb.ImageFormat_PNG,
+		Image:         img,
+	}, nil
+}
+
+func (w *Worker) fail(ctx context.Context, job *pb.Job, reason string, logTail string, f failure) {
+	msg := &pb.JobFail{
+		Id:        job.Id,
+		Name:      job.Name,
+		Reason:    reason,
+		Permanent: f == failPermanent,
+		AttemptId: job.AttemptId,
+		LogTail:   logTail,
+		Preempted: f == failPreempted,
+		TimedOut:  f == failTimedOut,
+	}
+	err := w.deliver(ctx, fmt.Sprintf("failure of %q - %q", job.Id, job.Name), func(ctx context.Context) error {
+		_, err := w.client.FailJob(ctx, msg)
+		return err
+	})
+	if err != nil {
+		log.Printf("Could not report failure of %q - %q. %v", job.Id, job.Name, err)
+	}
 }
 
-func prepareFilename(dir string, filename string, format pb.ImageFormat) (string, error) {
-	err := os.MkdirAll(dir, 0700)
+// uploadLog sends the engine output of an attempt to the server. It runs once
+// the attempt is over, possibly after the job context is gone, so ctx comes
+// from graceContext. It gives up after the grace period either way.
+func (w *Worker) uploadLog(ctx context.Context, job *pb.Job, logs *logBuffer) {
+	content, truncated := logs.contents()
+	if content == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.config.GracePeriod)
+	defer cancel()
+
+	msg := &pb.JobLogUpload{
+		Id:        job.Id,
+		Name:      job.Name,
+		AttemptId: job.AttemptId,
+		WorkerId:  w.config.ID,
+		Log:       content,
+		Truncated: truncated,
+	}
+	err := w.deliver(ctx, fmt.Sprintf("the log of %q - %q", job.Id, job.Name), func(ctx context.Context) error {
+		_, err := w.client.UploadLog(ctx, msg)
+		return err
+	})
 	if err != nil {
-		return "/tmp/dump", err
+		log.Printf("Could not upload the log of %q - %q. %v", job.Id, job.Name, err)
+	}
+}
+
+// weightArgs hands the weights a job sets to the engine
+func weightArgs(params *pb.JobParameters) []string {
+	if params == nil {
+		return nil
+	}
+
+	var args []string
+	weights := []struct {
+		flag  string
+		value float64
+	}{
+		{"-content_weight", params.ContentWeight},
+		{"-style_weight", params.StyleWeight},
+		{"-style_scale", params.StyleScale},
+		{"-tv_weight", params.TvWeight},
+	}
+	for _, w := range weights {
+		if w.value > 0 {
+			args = append(args, w.flag, strconv.FormatFloat(w.value, 'g', -1, 64))
+		}
+	}
+	return args
+}
+
+// iterations is how many iterations the engine runs for a job
+func (w *Worker) iterations(params *pb.JobParameters) int32 {
+	if params != nil && params.NumIterations > 0 {
+		return params.NumIterations
+	}
+	return w.maxIterations
+}
+
+// timeouts returns the limits for a render, preferring those of the job
+func (w *Worker) timeouts(t *pb.Timeouts) (time.Duration, time.Duration) {
+	maxDuration := w.config.MaxDuration
+	stallTimeout := w.config.StallTimeout
+	if t == nil {
+		return maxDuration, stallTimeout
+	}
+
+	if t.MaxDuration > 0 {
+		maxDuration = time.Duration(t.MaxDuration) * time.Second
+	}
+	if t.Stall > 0 {
+		stallTimeout = time.Duration(t.Stall) * time.Second
+	}
+	return maxDuration, stallTimeout
+}
+
+// timeoutReason tells why a render started at started, whose engine last
+// made progress at updated, has to be stopped at now. It is empty if the
+// render can go on.
+func timeoutReason(now time.Time, started time.Time, updated time.Time, maxDuration time.Duration, stallTimeout time.Duration) string {
+	switch {
+	case maxDuration > 0 && now.Sub(started) > maxDuration:
+		return fmt.Sprintf("timeout: render took longer than %s", maxDuration)
+	case stallTimeout > 0 && now.Sub(updated) > stallTimeout:
+		return fmt.Sprintf("timeout: no progress for %s", stallTimeout)
+	}
+	return ""
+}
+
+// wait sleeps for d or until ctx is cancelled
+func (w *Worker) wait(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// graceContext returns a context that ends the grace period after ctx does,
+// for reports that should still reach the server when a job is stopped. The
+// main program gives up on the worker shortly after the grace period.
+func (w *Worker) graceContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	gctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-gctx.Done():
+			return
+		}
+		select {
+		case <-time.After(w.config.GracePeriod):
+			cancel()
+		case <-gctx.Done():
+		}
+	}()
+	return gctx, cancel
+}
+
+// register announces the worker to the server, retrying until it succeeds
+func (w *Worker) register(ctx context.Context) {
+	retry := newBackoff()
+	for {
+		var resp *pb.WorkerRegistrationResponse
+		err := w.call(ctx, func(ctx context.Context) error {
+			var err error
+			resp, err = w.client.RegisterWorker(ctx, &pb.WorkerRegistration{
+				WorkerId:     w.config.ID,
+				Hostname:     w.config.Hostname,
+				Version:      Version,
+				Capabilities: w.config.Capabilities,
+				Slots:        w.slotInfo(),
+				Problem:      w.currentProblem(),
+			})
+			return err
+		})
+		if err == nil {
+			if resp.HeartbeatInterval > 0 {
+				w.lock.Lock()
+				w.heartbeatInterval = time.Duration(resp.HeartbeatInterval) * time.Second
+				w.lock.Unlock()
+			}
+			log.Printf("Registered as worker %q", w.config.ID)
+			return
+		}
+
+		d := retry.next()
+		log.Printf("Could not register with the server, retrying in %s. %v", d, err)
+		w.wait(ctx, d)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// heartbeat periodically reports the worker state to the server
+func (w *Worker) heartbeat(ctx context.Context) {
+	interval := w.interval()
+	ticker := time.NewTicker(interval)
+	defer func() {
+		ticker.Stop()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var resp *pb.HeartbeatResponse
+		err := w.call(ctx, func(ctx context.Context) error {
+			var err error
+			resp, err = w.client.Heartbeat(ctx, w.heartbeatMessage())
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			//The server may have restarted and forgotten about us
+			log.Printf("Heartbeat failed, registering again. %v", err)
+			w.register(ctx)
+
+			//The server may want heartbeats at another pace now
+			if i := w.interval(); i != interval {
+				ticker.Stop()
+				interval = i
+				ticker = time.NewTicker(interval)
+			}
+			continue
+		}
+
+		w.applyCommand(resp.Command)
+	}
+}
+
+// applyCommand records a control directive from the server. Pausing suspends
+// the running engines straight away, the other directives that stop the
+// worker take effect once the current job, if any, is done.
+func (w *Worker) applyCommand(c pb.WorkerCommand) {
+	if c == pb.WorkerCommand_COMMAND_NONE {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	//Draining and shutting down let the current jobs finish
+	if w.paused && (c == pb.WorkerCommand_COMMAND_DRAIN || c == pb.WorkerCommand_COMMAND_SHUTDOWN) {
+		w.paused = false
+		w.signalEngines(syscall.SIGCONT, pb.WorkerState_WORKER_BUSY)
+	}
+
+	switch c {
+	case pb.WorkerCommand_COMMAND_DRAIN:
+		w.draining = true
+	case pb.WorkerCommand_COMMAND_PAUSE:
+		if !w.paused {
+			w.paused = true
+			w.signalEngines(syscall.SIGSTOP, pb.WorkerState_WORKER_PAUSED)
+		}
+	case pb.WorkerCommand_COMMAND_RESUME:
+		if w.paused {
+			w.paused = false
+			w.signalEngines(syscall.SIGCONT, pb.WorkerState_WORKER_BUSY)
+		}
+		w.draining = false
+	case pb.WorkerCommand_COMMAND_SHUTDOWN:
+		w.shutdown = true
+	}
+
+	log.Printf("Received %s from the server", c)
+}
+
+// signalEngines sends a signal to the engine of every busy slot and reports
+// them in the given state. The lock must be held.
+func (w *Worker) signalEngines(sig syscall.Signal, state pb.WorkerState) {
+	for _, s := range w.slots {
+		if s.engine != nil {
+			signalGroup(s.engine, sig)
+			s.state = state
+		}
+	}
+}
+
+// setEngine records the engine a slot is running, suspending it straight
+// away if the worker was paused while it started
+func (w *Worker) setEngine(s *slot, cmd *exec.Cmd) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	s.engine = cmd
+	if cmd != nil && w.paused {
+		signalGroup(cmd, syscall.SIGSTOP)
+		s.state = pb.WorkerState_WORKER_PAUSED
+	}
+}
+
+func (w *Worker) isPaused() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.paused
+}
+
+// held reports whether the worker has been told not to take new jobs and the
+// state it should report while it waits
+func (w *Worker) held() (pb.WorkerState, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	switch {
+	case w.problem != "":
+		return pb.WorkerState_WORKER_UNHEALTHY, true
+	case w.draining:
+		return pb.WorkerState_WORKER_DRAINED, true
+	case w.paused:
+		return pb.WorkerState_WORKER_PAUSED, true
+	}
+	return pb.WorkerState_WORKER_IDLE, false
+}
+
+// How long to wait before repeating a failed self-test
+var probeRetry = 10 * time.Minute
+
+// probe runs the self-test, holding the worker while it fails
+func (w *Worker) probe() bool {
+	log.Println("Running self-test")
+	r := SelfTest(w.config.Devices, w.config.WorkDir)
+	problem := r.Problem()
+	if problem != "" {
+		log.Printf("Self-test failed, not taking jobs.\n%s", r)
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.problem = problem
+	return problem == ""
+}
+
+// reprobe repeats a failed self-test until it passes, so fixing the machine
+// doesn't need a restart
+func (w *Worker) reprobe(ctx context.Context) {
+	for {
+		w.wait(ctx, probeRetry)
+		if ctx.Err() != nil {
+			return
+		}
+		if w.probe() {
+			log.Println("Self-test passed, taking jobs")
+			return
+		}
+	}
+}
+
+// interval is how often the server wants to hear from the worker
+func (w *Worker) interval() time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.heartbeatInterval
+}
+
+func (w *Worker) currentProblem() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.problem
+}
+
+func (w *Worker) shuttingDown() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.shutdown
+}
+
+func (w *Worker) setState(s *slot, state pb.WorkerState, jobID string, jobName string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	s.state = state
+	s.jobID = jobID
+	s.jobName = jobName
+}
+
+func (w *Worker) slotInfo() []*pb.SlotInfo {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var r []*pb.SlotInfo
+	for _, s := range w.slots {
+		r = append(r, s.toProto())
+	}
+	return r
+}
+
+// heartbeatMessage reports every slot along with a summary for servers that
+// only know about single job workers: busy with the first job if any slot is
+// busy, otherwise the state of the first slot.
+func (w *Worker) heartbeatMessage() *pb.WorkerHeartbeat {
+	msg := &pb.WorkerHeartbeat{
+		WorkerId: w.config.ID,
+		Slots:    w.slotInfo(),
+		Problem:  w.currentProblem(),
+	}
+
+	for _, s := range msg.Slots {
+		if s.State == pb.WorkerState_WORKER_BUSY {
+			msg.State = s.State
+			msg.JobId = s.JobId
+			msg.JobName = s.JobName
+			return msg
+		}
 	}
-	extension := ""
-	switch format {
-	case pb.ImageFormat_JPG:
-		extension = ".jpg"
-	case pb.ImageFormat_PNG:
-		extension = ".png"
+	if len(msg.Slots) > 0 {
+		msg.State = msg.Slots[0].State
 	}
-	return path.Join(dir, filename+extension), nil
+	return msg
 }
@@ -0,0 +1,385 @@
+package worker
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeClient records what a worker reports to the server. Like real calls,
+// its calls fail once the context is done. Calls it doesn't implement panic
+// on the nil embedded client.
+type fakeClient struct {
+	pb.NeuralStyleWorkerClient
+
+	lock     sync.Mutex
+	progress []*pb.JobResult
+	fails    []*pb.JobFail
+	logs     []*pb.JobLogUpload
+	complete []*pb.JobResult
+
+	//Fails progress reports when set
+	progressErr func() error
+}
+
+func (c *fakeClient) ProgressReport(ctx context.Context, in *pb.JobResult, opts ...grpc.CallOption) (*pb.JobProgressResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.progressErr != nil {
+		if err := c.progressErr(); err != nil {
+			return nil, err
+		}
+	}
+	c.progress = append(c.progress, in)
+	return &pb.JobProgressResponse{}, nil
+}
+
+func (c *fakeClient) CompleteJob(ctx context.Context, in *pb.JobResult, opts ...grpc.CallOption) (*pb.JobResultResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.complete = append(c.complete, in)
+	return &pb.JobResultResponse{}, nil
+}
+
+func (c *fakeClient) FailJob(ctx context.Context, in *pb.JobFail, opts ...grpc.CallOption) (*pb.JobFail, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.fails = append(c.fails, in)
+	return &pb.JobFail{}, nil
+}
+
+func (c *fakeClient) UploadLog(ctx context.Context, in *pb.JobLogUpload, opts ...grpc.CallOption) (*pb.JobLogUploadResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.logs = append(c.logs, in)
+	return &pb.JobLogUploadResponse{}, nil
+}
+
+func TestApplyCommand(t *testing.T) {
+	w := &Worker{}
+	check := func(after string, state pb.WorkerState, held bool) {
+		if got, ok := w.held(); got != state || ok != held {
+			t.Errorf("after %s: held %t as %s, want %t as %s", after, ok, got, held, state)
+		}
+	}
+
+	check("registering", pb.WorkerState_WORKER_IDLE, false)
+
+	w.applyCommand(pb.WorkerCommand_COMMAND_PAUSE)
+	check("pause", pb.WorkerState_WORKER_PAUSED, true)
+
+	//Draining wins over pausing, as the worker won't take jobs either way
+	w.applyCommand(pb.WorkerCommand_COMMAND_DRAIN)
+	check("drain", pb.WorkerState_WORKER_DRAINED, true)
+
+	w.applyCommand(pb.WorkerCommand_COMMAND_NONE)
+	check("no command", pb.WorkerState_WORKER_DRAINED, true)
+
+	w.applyCommand(pb.WorkerCommand_COMMAND_RESUME)
+	check("resume", pb.WorkerState_WORKER_IDLE, false)
+
+	if w.shuttingDown() {
+		t.Errorf("shutting down before being told to")
+	}
+	w.applyCommand(pb.WorkerCommand_COMMAND_SHUTDOWN)
+	if !w.shuttingDown() {
+		t.Errorf("not shutting down when told to")
+	}
+}
+
+// processState reads the state of a process from /proc, 'T' once stopped
+func processState(t *testing.T, pid int) byte {
+	b, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		t.Skip(err)
+	}
+	//The state follows the command name, which is in parentheses
+	stat := string(b)
+	return stat[strings.LastIndex(stat, ")")+2]
+}
+
+func TestPauseSuspendsEngine(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if err := startGroup(cmd); err != nil {
+		t.Skip(err)
+	}
+	defer cmd.Wait()
+	defer killGroup(cmd)
+
+	s := &slot{state: pb.WorkerState_WORKER_BUSY}
+	w := &Worker{slots: []*slot{s, {state: pb.WorkerState_WORKER_IDLE}}}
+	w.setEngine(s, cmd)
+
+	waitFor := func(after string, want byte) {
+		for i := 0; processState(t, cmd.Process.Pid) != want; i++ {
+			if i == 50 {
+				t.Fatalf("after %s: engine in state %c, want %c", after, processState(t, cmd.Process.Pid), want)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	w.applyCommand(pb.WorkerCommand_COMMAND_PAUSE)
+	waitFor("pause", 'T')
+	if s.state != pb.WorkerState_WORKER_PAUSED || w.slots[1].state != pb.WorkerState_WORKER_IDLE {
+		t.Errorf("after pause: slots %s and %s, want the busy one paused", s.state, w.slots[1].state)
+	}
+
+	w.applyCommand(pb.WorkerCommand_COMMAND_RESUME)
+	waitFor("resume", 'S')
+	if s.state != pb.WorkerState_WORKER_BUSY {
+		t.Errorf("after resume: slot %s, want it busy", s.state)
+	}
+
+	//Draining a paused worker lets its engines finish
+	w.applyCommand(pb.WorkerCommand_COMMAND_PAUSE)
+	waitFor("pause", 'T')
+	w.applyCommand(pb.WorkerCommand_COMMAND_DRAIN)
+	waitFor("drain", 'S')
+
+	//An engine started while paused is suspended straight away
+	w.applyCommand(pb.WorkerCommand_COMMAND_RESUME)
+	w.applyCommand(pb.WorkerCommand_COMMAND_PAUSE)
+	w.setEngine(s, nil)
+	s.state = pb.WorkerState_WORKER_BUSY
+	w.setEngine(s, cmd)
+	waitFor("starting paused", 'T')
+	if s.state != pb.WorkerState_WORKER_PAUSED {
+		t.Errorf("engine started while paused: slot %s", s.state)
+	}
+}
+
+func TestFail(t *testing.T) {
+	c := &fakeClient{}
+	w := &Worker{client: c}
+	job := &pb.Job{Id: "job", Name: "cat", AttemptId: "a1"}
+
+	tests := []struct {
+		failure   failure
+		permanent bool
+		preempted bool
+		timedOut  bool
+	}{
+		{failRetry, false, false, false},
+		{failPermanent, true, false, false},
+		{failPreempted, false, true, false},
+		{failTimedOut, false, false, true},
+	}
+	for i, tt := range tests {
+		w.fail(context.Background(), job, "reason", "tail", tt.failure)
+		if len(c.fails) != i+1 {
+			t.Fatalf("reported %d failures, want %d", len(c.fails), i+1)
+		}
+		f := c.fails[i]
+		if f.Permanent != tt.permanent || f.Preempted != tt.preempted || f.TimedOut != tt.timedOut || f.AttemptId != "a1" || f.LogTail != "tail" {
+			t.Errorf("failure %d reported as %+v", tt.failure, f)
+		}
+	}
+}
+
+func TestCheckInput(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkInput(&pb.InputImage{Title: "cat", Image: b.Bytes()}); err != nil {
+		t.Errorf("readable image rejected: %v", err)
+	}
+	for _, data := range [][]byte{nil, []byte("not an image"), b.Bytes()[:20]} {
+		if err := checkInput(&pb.InputImage{Title: "cat", Image: data}); err == nil {
+			t.Errorf("unreadable image %q accepted", data)
+		}
+	}
+}
+
+func TestGraceContext(t *testing.T) {
+	w := &Worker{config: Config{GracePeriod: 100 * time.Millisecond}}
+	ctx, cancel := context.WithCancel(context.Background())
+	gctx, gcancel := w.graceContext(ctx)
+	defer gcancel()
+
+	cancel()
+	select {
+	case <-gctx.Done():
+		t.Fatalf("ended with the job context")
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case <-gctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("still going after the grace period")
+	}
+}
+
+// engineScript stands in for neural_style.lua. The loss never changes, and
+// unless told not to it saves a result every saveEvery iterations.
+func engineScript(save bool) string {
+	script := `while [ $# -gt 0 ]; do
+	case $1 in
+	-content_image) content=$2;;
+	-output_image) out=$2;;
+	-num_iterations) n=$2;;
+	esac
+	shift
+done
+i=1
+while [ $i -le $n ]; do
+	echo "Iteration $i / $n"
+	echo "  Total loss: 100"
+`
+	if save {
+		script += `	[ $((i % ` + strconv.Itoa(saveEvery) + `)) -eq 0 ] && cp "$content" "${out%.png}_$i.png"
+`
+	}
+	return script + `	i=$((i+1))
+done
+cp "$content" "$out"`
+}
+
+func TestRunJobConverged(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	input := func(title string) *pb.InputImage {
+		return &pb.InputImage{Title: title, Format: pb.ImageFormat_PNG, Image: b.Bytes()}
+	}
+
+	tests := []struct {
+		name       string
+		save       bool
+		init       *pb.InputImage
+		start      int32
+		annotation string
+		iteration  int32
+	}{
+		{"converged", true, nil, 0, "converged at 101, result from iteration 100", 100},
+		{"resumed", true, input("init"), 300, "converged at 401, result from iteration 400", 400},
+		{"nothing saved", false, nil, 0, "", 0},
+	}
+
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "worker")
+		if err != nil {
+			t.Fatal(err)
+		}
+		restore := fakeEngine(t, engineScript(tt.save))
+
+		c := &fakeClient{}
+		w := &Worker{client: c, config: Config{WorkDir: dir, GracePeriod: time.Second}}
+		job := &pb.Job{
+			Id: "job", Name: "cat", AttemptId: "a1",
+			Style: input("wave"), Content: input("cat"),
+			Init: tt.init, StartIteration: tt.start,
+			Params: &pb.JobParameters{NumIterations: 1000, Convergence: &pb.Convergence{Threshold: 0.01, Window: 10}},
+		}
+		w.runJob(context.Background(), &slot{}, job)
+		restore()
+		os.RemoveAll(dir)
+
+		if tt.annotation == "" {
+			if len(c.complete) != 0 || len(c.fails) != 1 || !strings.Contains(c.fails[0].Reason, "before saving a result") || c.fails[0].Permanent {
+				t.Errorf("%s: completed %v and failed %v, want a retryable failure", tt.name, c.complete, c.fails)
+			}
+			continue
+		}
+		if len(c.complete) != 1 {
+			t.Errorf("%s: completed %d times and failed %v", tt.name, len(c.complete), c.fails)
+			continue
+		}
+		if r := c.complete[0]; r.Annotation != tt.annotation || r.ProgressCount != tt.iteration {
+			t.Errorf("%s: completed at %d as %q, want %d as %q", tt.name, r.ProgressCount, r.Annotation, tt.iteration, tt.annotation)
+		}
+	}
+}
+
+func TestRunJobStopped(t *testing.T) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	input := func(title string) *pb.InputImage {
+		return &pb.InputImage{Title: title, Format: pb.ImageFormat_PNG, Image: b.Bytes()}
+	}
+	dir, err := ioutil.TempDir("", "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer fakeEngine(t, engineScript(true))()
+
+	//The worker is stopped while it reports the last partial, after the
+	//engine finished
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &fakeClient{progressErr: func() error {
+		if ctx.Err() != nil {
+			return nil
+		}
+		cancel()
+		return grpc.Errorf(codes.Unavailable, "connection reset")
+	}}
+	w := &Worker{client: c, config: Config{WorkDir: dir, GracePeriod: 5 * time.Second}}
+	job := &pb.Job{Id: "job", Name: "cat", AttemptId: "a1", Style: input("wave"), Content: input("cat"), Params: &pb.JobParameters{NumIterations: saveEvery}}
+	w.runJob(ctx, &slot{}, job)
+
+	//Finishing off within the grace period beats rendering it again
+	if len(c.complete) != 1 || len(c.fails) != 0 {
+		t.Errorf("completed %d times and failed %v, want the job completed", len(c.complete), c.fails)
+	}
+}
+
+func TestIterations(t *testing.T) {
+	w := &Worker{maxIterations: 1000}
+	for params, want := range map[*pb.JobParameters]int32{
+		nil:                  1000,
+		{}:                   1000,
+		{NumIterations: 250}: 250,
+	} {
+		if got := w.iterations(params); got != want {
+			t.Errorf("%v: got %d iterations, want %d", params, got, want)
+		}
+	}
+}
+
+func TestWeightArgs(t *testing.T) {
+	tests := []struct {
+		params *pb.JobParameters
+		want   string
+	}{
+		{nil, ""},
+		{&pb.JobParameters{}, ""},
+		{&pb.JobParameters{StyleWeight: 100, TvWeight: 0.001}, "-style_weight 100 -tv_weight 0.001"},
+		{&pb.JobParameters{ContentWeight: 5, StyleScale: 1.5}, "-content_weight 5 -style_scale 1.5"},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(weightArgs(tt.params), " "); got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.params, got, tt.want)
+		}
+	}
+}
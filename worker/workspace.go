@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+// Retention decides which job workspaces are kept once a job is done
+type Retention int
+
+const (
+	KeepNone Retention = iota
+	KeepFailed
+	KeepAll
+)
+
+func ParseRetention(s string) (Retention, error) {
+	switch s {
+	case "none":
+		return KeepNone, nil
+	case "failed":
+		return KeepFailed, nil
+	case "all":
+		return KeepAll, nil
+	}
+	return KeepNone, fmt.Errorf("Unknown retention %q. Use none, failed or all", s)
+}
+
+const outputName = "out"
+
+// neural_style.lua names intermediate results after the output image
+var partialRe = regexp.MustCompile("^" + outputName + `_(\d+)\.png$`)
+
+// workspace is the scratch directory a single job attempt runs in
+type workspace struct {
+	dir string
+}
+
+type partialFile struct {
+	iteration int32
+	filename  string
+}
+
+func newWorkspace(basedir string, id string, name string) (*workspace, error) {
+	dir := path.Join(basedir, name, id)
+
+	//Start from a clean slate if a previous attempt left files behind
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &workspace{dir: dir}, nil
+}
+
+// writeImage saves one of the job inputs and returns its filename
+func (ws *workspace) writeImage(prefix string, img *pb.InputImage) (string, error) {
+	filename := path.Join(ws.dir, prefix+"_"+path.Base(img.Title)+imageExtension(img.Format))
+	err := ioutil.WriteFile(filename, img.Image, 0600)
+	return filename, err
+}
+
+// output is where the engine writes the final result
+func (ws *workspace) output() string {
+	return path.Join(ws.dir, outputName+".png")
+}
+
+// partials lists the intermediate results in the workspace, in iteration order
+func (ws *workspace) partials() ([]partialFile, error) {
+	entries, err := ioutil.ReadDir(ws.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var r []partialFile
+	for _, e := range entries {
+		m := partialRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		i, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		r = append(r, partialFile{
+			iteration: int32(i),
+			filename:  path.Join(ws.dir, e.Name()),
+		})
+	}
+
+	sort.Sort(byIteration(r))
+	return r, nil
+}
+
+func (ws *workspace) cleanup(retention Retention, succeeded bool) {
+	if retention == KeepAll || (retention == KeepFailed && !succeeded) {
+		return
+	}
+
+	err := os.RemoveAll(ws.dir)
+	if err != nil {
+		log.Printf("Could not remove workspace %q. %v", ws.dir, err)
+	}
+}
+
+type byIteration []partialFile
+
+func (p byIteration) Len() int           { return len(p) }
+func (p byIteration) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byIteration) Less(i, j int) bool { return p[i].iteration < p[j].iteration }
+
+func imageExtension(format pb.ImageFormat) string {
+	switch format {
+	case pb.ImageFormat_JPG:
+		return ".jpg"
+	case pb.ImageFormat_PNG:
+		return ".png"
+	case pb.ImageFormat_WEBP:
+		return ".webp"
+	case pb.ImageFormat_GIF:
+		return ".gif"
+	case pb.ImageFormat_TIFF:
+		return ".tiff"
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mgilbir/neural-style-art-project/pb"
+)
+
+func TestWorkspacePartials(t *testing.T) {
+	base, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	//A previous attempt left a partial behind
+	stale := path.Join(base, "cat", "job", "out_900.png")
+	if err := os.MkdirAll(path.Dir(stale), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(stale, []byte("stale"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := newWorkspace(base, "job", "cat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("files of a previous attempt kept")
+	}
+
+	style, err := ws.writeImage("style", &pb.InputImage{Title: "../starry", Format: pb.ImageFormat_JPG, Image: []byte("style")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style != path.Join(ws.dir, "style_starry.jpg") {
+		t.Errorf("style written to %q, want it inside the workspace", style)
+	}
+
+	for _, name := range []string{"out_200.png", "out_1000.png", "out_100.png", "out.png", "out_x.png", "out_300.jpg"} {
+		if err := ioutil.WriteFile(path.Join(ws.dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	partials, err := ws.partials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int32
+	for _, p := range partials {
+		got = append(got, p.iteration)
+	}
+	if len(got) != 3 || got[0] != 100 || got[1] != 200 || got[2] != 1000 {
+		t.Errorf("found partials at %v, want 100, 200 and 1000", got)
+	}
+}
+
+func TestWorkspaceRetention(t *testing.T) {
+	base, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	for _, retention := range []string{"none", "failed", "all"} {
+		r, err := ParseRetention(retention)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, succeeded := range []bool{true, false} {
+			ws, err := newWorkspace(base, "job", retention)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ws.cleanup(r, succeeded)
+
+			_, err = os.Stat(ws.dir)
+			kept := err == nil
+			want := retention == "all" || (retention == "failed" && !succeeded)
+			if kept != want {
+				t.Errorf("keeping %s, succeeded %t: kept %t, want %t", retention, succeeded, kept, want)
+			}
+		}
+	}
+
+	if _, err := ParseRetention("some"); err == nil {
+		t.Errorf("parsed an unknown retention")
+	}
+}